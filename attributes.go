@@ -3,7 +3,10 @@ package machina
 import (
 	"errors"
 	"fmt"
+	"strings"
+	"time"
 
+	"github.com/gentlemanautomaton/machina/qemu/qfirmware"
 	"github.com/gentlemanautomaton/machina/qemu/qguest"
 	"github.com/gentlemanautomaton/machina/summary"
 )
@@ -13,11 +16,18 @@ type Attributes struct {
 	Firmware       Firmware       `json:"firmware,omitempty"`
 	CPU            CPU            `json:"cpu,omitempty"`
 	Memory         Memory         `json:"memory,omitempty"`
+	Balloon        Balloon        `json:"balloon,omitempty"`
+	NUMA           NUMA           `json:"numa,omitempty"`
+	Pinning        Pinning        `json:"pinning,omitempty"`
+	Clock          Clock          `json:"clock,omitempty"`
 	Enlightenments Enlightenments `json:"enlightenments,omitempty"`
+	Nested         Nested         `json:"nested,omitempty"`
+	SEV            SEV            `json:"sev,omitempty"`
 	TPM            TPM            `json:"tpm,omitempty"`
 	QMP            QMP            `json:"qmp,omitempty"`
 	Agent          Agent          `json:"agent,omitempty"`
 	Spice          Spice          `json:"spice,omitempty"`
+	Hotplug        Hotplug        `json:"hotplug,omitempty"`
 }
 
 // Config adds the attributes configuration to the summary.
@@ -25,11 +35,18 @@ func (a *Attributes) Config(info MachineInfo, vars Vars, out summary.Interface)
 	a.Firmware.Config(out)
 	a.CPU.Config(out)
 	a.Memory.Config(out)
+	a.Balloon.Config(out)
+	a.NUMA.Config(out)
+	a.Pinning.Config(out)
+	a.Clock.Config(out)
 	a.Enlightenments.Config(out)
+	a.Nested.Config(out)
+	a.SEV.Config(out)
 	a.TPM.Config(info, out)
 	a.QMP.Config(info, out)
 	a.Agent.Config(vars, out)
 	a.Spice.Config(vars, out)
+	a.Hotplug.Config(out)
 }
 
 // MergeAttributes merges a set of attributes in order. If an attribute value
@@ -40,25 +57,66 @@ func MergeAttributes(attrs ...Attributes) Attributes {
 		overlayFirmware(&merged.Firmware, &attrs[i].Firmware)
 		overlayCPU(&merged.CPU, &attrs[i].CPU)
 		overlayMemory(&merged.Memory, &attrs[i].Memory)
+		overlayBalloon(&merged.Balloon, &attrs[i].Balloon)
+		overlayNUMA(&merged.NUMA, &attrs[i].NUMA)
+		overlayPinning(&merged.Pinning, &attrs[i].Pinning)
+		overlayClock(&merged.Clock, &attrs[i].Clock)
 		overlayEnlightenments(&merged.Enlightenments, &attrs[i].Enlightenments)
+		overlayNested(&merged.Nested, &attrs[i].Nested)
+		overlaySEV(&merged.SEV, &attrs[i].SEV)
 		overlayTPM(&merged.TPM, &attrs[i].TPM)
 		overlayQMP(&merged.QMP, &attrs[i].QMP)
 		overlayAgent(&merged.Agent, &attrs[i].Agent)
 		overlaySpice(&merged.Spice, &attrs[i].Spice)
+		overlayHotplug(&merged.Hotplug, &attrs[i].Hotplug)
 	}
 	return merged
 }
 
 // Firmware describes the attributes of a machine's firmware.
+//
+// When Code is not specified and Interface is "uefi", the firmware code is
+// selected from the QEMU firmware descriptors installed on the host. When
+// SecureBoot is true, firmware with Secure Boot keys enrolled is selected.
+// The machine's firmware variables are stored in the Vars volume, which is
+// initialized from the firmware's template before the machine first starts.
 type Firmware struct {
-	Code Volume `json:"code,omitempty"`
-	Vars Volume `json:"vars,omitempty"`
+	Code       Volume              `json:"code,omitempty"`
+	Vars       Volume              `json:"vars,omitempty"`
+	Interface  qfirmware.Interface `json:"interface,omitempty"`
+	SecureBoot bool                `json:"secure-boot,omitempty"`
+}
+
+// IsDiscovered returns true if the firmware code is selected from the
+// firmware descriptors installed on the host.
+func (f Firmware) IsDiscovered() bool {
+	return f.Code.IsEmpty() && f.Interface != ""
+}
+
+// Validate returns an error if the firmware configuration is invalid.
+func (f Firmware) Validate() error {
+	if f.Interface != "" && f.Interface != qfirmware.UEFI {
+		return fmt.Errorf("unsupported firmware interface: \"%s\"", f.Interface)
+	}
+	if f.SecureBoot && f.Interface == "" && f.Code.IsEmpty() {
+		return errors.New("secure boot requires a uefi firmware interface")
+	}
+	if f.IsDiscovered() && f.Vars.IsEmpty() {
+		return errors.New("uefi firmware requires a vars volume to hold the machine's firmware variables")
+	}
+	return nil
 }
 
 // Config adds the firmware configuration to the summary.
 func (f *Firmware) Config(out summary.Interface) {
 	if !f.Code.IsEmpty() {
 		out.Add("Firmware Code (read-only): %s", f.Code)
+	} else if f.Interface != "" {
+		if f.SecureBoot {
+			out.Add("Firmware Code (read-only): %s with secure boot", f.Interface)
+		} else {
+			out.Add("Firmware Code (read-only): %s", f.Interface)
+		}
 	}
 	if !f.Vars.IsEmpty() {
 		out.Add("Firmware Variables (read/write): %s", f.Vars)
@@ -72,14 +130,30 @@ func overlayFirmware(merged, overlay *Firmware) {
 	if !overlay.Vars.IsEmpty() {
 		merged.Vars = overlay.Vars
 	}
+	if overlay.Interface != "" {
+		merged.Interface = overlay.Interface
+	}
+	if overlay.SecureBoot {
+		merged.SecureBoot = overlay.SecureBoot
+	}
 }
 
 // CPU describes the attributes of a machine's central processing units.
+//
+// CPUModel overrides the QEMU CPU model of the processor. Flags are applied
+// after the flags of the processor.
+//
+// Sockets, Cores and ThreadsPerCore describe the processors that the
+// machine starts with. When MaxCPUs is specified, additional sockets are
+// reserved so that virtual CPUs can be hotplugged up to a total of MaxCPUs.
 type CPU struct {
 	Processor      ProcessorName `json:"processor,omitempty"`
+	CPUModel       string        `json:"cpu-model,omitempty"`
+	Flags          []CPUFlag     `json:"flags,omitempty"`
 	Sockets        int           `json:"sockets,omitempty"`
 	Cores          int           `json:"cores,omitempty"`
 	ThreadsPerCore int           `json:"threads,omitempty"`
+	MaxCPUs        int           `json:"max-cpus,omitempty"`
 }
 
 // Config adds the cpu configuration to the summary.
@@ -87,6 +161,16 @@ func (cpu *CPU) Config(out summary.Interface) {
 	if cpu.Processor != "" {
 		out.Add("Processor: %s", cpu.Processor)
 	}
+	if cpu.CPUModel != "" {
+		out.Add("CPU Model: %s", cpu.CPUModel)
+	}
+	if len(cpu.Flags) > 0 {
+		flags := make([]string, len(cpu.Flags))
+		for i, flag := range cpu.Flags {
+			flags[i] = string(flag)
+		}
+		out.Add("CPU Flags: %s", strings.Join(flags, " "))
+	}
 	if cpu.Sockets > 0 {
 		out.Add("Sockets: %d", cpu.Sockets)
 	}
@@ -96,12 +180,21 @@ func (cpu *CPU) Config(out summary.Interface) {
 	if cpu.ThreadsPerCore > 0 {
 		out.Add("Threads Per Core: %d", cpu.ThreadsPerCore)
 	}
+	if cpu.MaxCPUs > 0 {
+		out.Add("Maximum CPUs: %d", cpu.MaxCPUs)
+	}
 }
 
 func overlayCPU(merged, overlay *CPU) {
 	if overlay.Processor != "" {
 		merged.Processor = overlay.Processor
 	}
+	if overlay.CPUModel != "" {
+		merged.CPUModel = overlay.CPUModel
+	}
+	if len(overlay.Flags) > 0 {
+		merged.Flags = overlay.Flags
+	}
 	if overlay.Sockets > 0 {
 		merged.Sockets = overlay.Sockets
 	}
@@ -111,11 +204,77 @@ func overlayCPU(merged, overlay *CPU) {
 	if overlay.ThreadsPerCore > 0 {
 		merged.ThreadsPerCore = overlay.ThreadsPerCore
 	}
+	if overlay.MaxCPUs > 0 {
+		merged.MaxCPUs = overlay.MaxCPUs
+	}
 }
 
 // Memory describes the attributes of a machine's memory.
+//
+// RAM is the amount of memory in mebibytes. The remaining fields select
+// the host memory backend that supplies it. When HugePages is specified,
+// memory is allocated from huge pages of that size, either through
+// a hugetlbfs file system mounted at HugePagesPath or through memfd when
+// the backend is memfd.
+//
+// When MaxRAM is specified, the machine reserves Slots memory slots that
+// DIMMs can be hotplugged into, up to a total of MaxRAM mebibytes.
 type Memory struct {
-	RAM int `json:"ram,omitempty"`
+	RAM           int           `json:"ram,omitempty"`
+	MaxRAM        int           `json:"max-ram,omitempty"`
+	Slots         int           `json:"slots,omitempty"`
+	Backend       MemoryBackend `json:"backend,omitempty"`
+	HugePages     HugePageSize  `json:"hugepages,omitempty"`
+	HugePagesPath string        `json:"hugepages-path,omitempty"`
+	Share         bool          `json:"share,omitempty"`
+	Prealloc      bool          `json:"prealloc,omitempty"`
+	Lock          bool          `json:"lock,omitempty"`
+}
+
+// IsDefault returns true if the memory is supplied by QEMU's default
+// memory backend.
+func (m Memory) IsDefault() bool {
+	return (m.Backend == "" || m.Backend == RAMMemoryBackend) && m.HugePages == "" && !m.Share && !m.Prealloc
+}
+
+// EffectiveHugePagesPath returns the path of the hugetlbfs file system that
+// huge pages are allocated from. If a path hasn't been specified, the
+// conventional mount point for the huge page size is returned.
+func (m Memory) EffectiveHugePagesPath() string {
+	switch {
+	case m.HugePagesPath != "":
+		return m.HugePagesPath
+	case m.HugePages == HugePageSize1G:
+		return "/dev/hugepages1G"
+	default:
+		return "/dev/hugepages"
+	}
+}
+
+// Validate returns an error if the memory configuration is invalid.
+func (m Memory) Validate() error {
+	if m.Backend != "" && !m.Backend.Valid() {
+		return fmt.Errorf("unrecognized memory backend: \"%s\"", m.Backend)
+	}
+	if m.HugePages != "" {
+		if !m.HugePages.Valid() {
+			return fmt.Errorf("unrecognized huge page size: \"%s\"", m.HugePages)
+		}
+		if err := m.HugePages.CheckSize(m.RAM); err != nil {
+			return err
+		}
+	}
+	if m.MaxRAM > 0 {
+		if m.MaxRAM < m.RAM {
+			return fmt.Errorf("the maximum memory of %s is less than the memory of %s", qguest.MB(m.MaxRAM).Size(), qguest.MB(m.RAM).Size())
+		}
+		if m.Slots <= 0 {
+			return errors.New("memory hotplug requires at least one memory slot")
+		}
+	} else if m.Slots > 0 {
+		return errors.New("memory slots require a maximum amount of memory")
+	}
+	return nil
 }
 
 // Config adds the memory configuration to the summary.
@@ -123,32 +282,452 @@ func (m *Memory) Config(out summary.Interface) {
 	if m.RAM > 0 {
 		out.Add("RAM: %s", qguest.MB(m.RAM).Size())
 	}
+	if m.MaxRAM > 0 {
+		out.Add("Maximum RAM: %s (%d slots)", qguest.MB(m.MaxRAM).Size(), m.Slots)
+	}
+	if m.Backend != "" {
+		out.Add("Memory Backend: %s", m.Backend)
+	}
+	if m.HugePages != "" {
+		out.Add("Huge Pages: %s (%s)", m.HugePages, m.EffectiveHugePagesPath())
+	}
+	if m.Share {
+		out.Add("Shared Memory: Enabled")
+	}
+	if m.Prealloc {
+		out.Add("Memory Preallocation: Enabled")
+	}
+	if m.Lock {
+		out.Add("Memory Locking: Enabled")
+	}
 }
 
 func overlayMemory(merged, overlay *Memory) {
 	if overlay.RAM > 0 {
 		merged.RAM = overlay.RAM
 	}
+	if overlay.MaxRAM > 0 {
+		merged.MaxRAM = overlay.MaxRAM
+	}
+	if overlay.Slots > 0 {
+		merged.Slots = overlay.Slots
+	}
+	if overlay.Backend != "" {
+		merged.Backend = overlay.Backend
+	}
+	if overlay.HugePages != "" {
+		merged.HugePages = overlay.HugePages
+	}
+	if overlay.HugePagesPath != "" {
+		merged.HugePagesPath = overlay.HugePagesPath
+	}
+	if overlay.Share {
+		merged.Share = true
+	}
+	if overlay.Prealloc {
+		merged.Prealloc = true
+	}
+	if overlay.Lock {
+		merged.Lock = true
+	}
+}
+
+// MemoryBackend identifies the kind of host memory that supplies a
+// machine's memory.
+type MemoryBackend string
+
+// Memory backends.
+const (
+	// RAMMemoryBackend allocates anonymous memory, or memory from
+	// a hugetlbfs file system when huge pages are used. It is the default
+	// memory backend.
+	RAMMemoryBackend = MemoryBackend("ram")
+
+	// MemFDMemoryBackend allocates memory through memfd, which can be
+	// shared with vhost-user processes such as virtiofsd.
+	MemFDMemoryBackend = MemoryBackend("memfd")
+)
+
+// Valid returns true if b is a recognized memory backend.
+func (b MemoryBackend) Valid() bool {
+	switch b {
+	case RAMMemoryBackend, MemFDMemoryBackend:
+		return true
+	default:
+		return false
+	}
+}
+
+// HugePageSize is the size of the huge pages that memory is allocated
+// from.
+type HugePageSize string
+
+// Huge page sizes.
+const (
+	HugePageSize2M = HugePageSize("2M")
+	HugePageSize1G = HugePageSize("1G")
+)
+
+// Valid returns true if size is a recognized huge page size.
+func (size HugePageSize) Valid() bool {
+	return size == HugePageSize2M || size == HugePageSize1G
+}
+
+// MB returns the huge page size in mebibytes.
+func (size HugePageSize) MB() int {
+	switch size {
+	case HugePageSize2M:
+		return 2
+	case HugePageSize1G:
+		return 1024
+	default:
+		return 0
+	}
+}
+
+// Pages returns the number of huge pages needed to hold the given number
+// of mebibytes.
+func (size HugePageSize) Pages(mb int) int {
+	page := size.MB()
+	if page == 0 {
+		return 0
+	}
+	return (mb + page - 1) / page
+}
+
+// CheckSize returns an error if the given number of mebibytes is not
+// a multiple of the huge page size.
+func (size HugePageSize) CheckSize(mb int) error {
+	if page := size.MB(); page > 0 && mb%page != 0 {
+		return fmt.Errorf("%s of memory is not a multiple of the %s huge page size", qguest.MB(mb).Size(), size)
+	}
+	return nil
+}
+
+// Balloon describes the attributes of a machine's virtio memory balloon
+// device, which allows the memory used by a running machine to be reduced.
+//
+// When FreePageReporting is enabled, the guest reports free pages to the
+// host so that they can be reclaimed. When StatsInterval is greater than
+// zero, the guest's memory statistics are polled at that interval in
+// seconds.
+type Balloon struct {
+	Enabled           bool `json:"enabled,omitempty"`
+	FreePageReporting bool `json:"free-page-reporting,omitempty"`
+	StatsInterval     int  `json:"stats-interval,omitempty"`
+}
+
+// Config adds the balloon configuration to the summary.
+func (b *Balloon) Config(out summary.Interface) {
+	if !b.Enabled {
+		return
+	}
+	out.Add("Memory Balloon: Enabled")
+	if b.FreePageReporting {
+		out.Add("Free Page Reporting: Enabled")
+	}
+	if b.StatsInterval > 0 {
+		out.Add("Balloon Statistics Interval: %ds", b.StatsInterval)
+	}
+}
+
+func overlayBalloon(merged, overlay *Balloon) {
+	if overlay.Enabled {
+		merged.Enabled = overlay.Enabled
+	}
+	if overlay.FreePageReporting {
+		merged.FreePageReporting = overlay.FreePageReporting
+	}
+	if overlay.StatsInterval > 0 {
+		merged.StatsInterval = overlay.StatsInterval
+	}
+}
+
+// Clock describes the attributes of a machine's real time clock and timers.
+//
+// Base is "utc", "localtime" or a starting date in the form
+// "2006-01-02T15:04:05" or "2006-01-02". Windows guests expect the real time
+// clock to follow local time. Isolation is "host", "rt" or "vm" and DriftFix
+// is "slew" or "none". Unspecified fields default to a UTC clock that
+// follows the host and slews to recover from drift.
+//
+// HPET enables or disables the high precision event timer when specified.
+// PITTickPolicy is "delay" or "discard" and determines what the in-kernel
+// programmable interval timer does with missed ticks.
+type Clock struct {
+	Base          qguest.ClockBase      `json:"base,omitempty"`
+	Isolation     qguest.ClockIsolation `json:"isolation,omitempty"`
+	DriftFix      qguest.ClockDriftFix  `json:"driftfix,omitempty"`
+	HPET          *bool                 `json:"hpet,omitempty"`
+	PITTickPolicy qguest.TickPolicy     `json:"pit-tick-policy,omitempty"`
+}
+
+// Validate returns an error if the clock configuration is invalid.
+func (c Clock) Validate() error {
+	switch c.Base {
+	case "", qguest.ClockBaseUTC, qguest.ClockBaseLocal:
+	default:
+		if _, err := time.Parse("2006-01-02T15:04:05", string(c.Base)); err != nil {
+			if _, err := time.Parse("2006-01-02", string(c.Base)); err != nil {
+				return fmt.Errorf("unrecognized clock base: \"%s\"", c.Base)
+			}
+		}
+	}
+	switch c.Isolation {
+	case "", qguest.ClockIsolationHost, qguest.ClockIsolationRealtime, qguest.ClockIsolationVM:
+	default:
+		return fmt.Errorf("unrecognized clock isolation: \"%s\"", c.Isolation)
+	}
+	switch c.DriftFix {
+	case "", qguest.ClockDriftFixNone, qguest.ClockDriftFixSlew:
+	default:
+		return fmt.Errorf("unrecognized clock drift fix: \"%s\"", c.DriftFix)
+	}
+	switch c.PITTickPolicy {
+	case "", qguest.TickPolicyDelay, qguest.TickPolicyDiscard:
+	default:
+		return fmt.Errorf("unrecognized PIT tick policy: \"%s\"", c.PITTickPolicy)
+	}
+	return nil
+}
+
+// Config adds the clock configuration to the summary.
+func (c *Clock) Config(out summary.Interface) {
+	if c.Base != "" {
+		out.Add("Clock Base: %s", c.Base)
+	}
+	if c.Isolation != "" {
+		out.Add("Clock Isolation: %s", c.Isolation)
+	}
+	if c.DriftFix != "" {
+		out.Add("Clock Drift Fix: %s", c.DriftFix)
+	}
+	if c.HPET != nil {
+		if *c.HPET {
+			out.Add("HPET: Enabled")
+		} else {
+			out.Add("HPET: Disabled")
+		}
+	}
+	if c.PITTickPolicy != "" {
+		out.Add("PIT Tick Policy: %s", c.PITTickPolicy)
+	}
+}
+
+func overlayClock(merged, overlay *Clock) {
+	if overlay.Base != "" {
+		merged.Base = overlay.Base
+	}
+	if overlay.Isolation != "" {
+		merged.Isolation = overlay.Isolation
+	}
+	if overlay.DriftFix != "" {
+		merged.DriftFix = overlay.DriftFix
+	}
+	if overlay.HPET != nil {
+		merged.HPET = overlay.HPET
+	}
+	if overlay.PITTickPolicy != "" {
+		merged.PITTickPolicy = overlay.PITTickPolicy
+	}
 }
 
 // Enlightenments describe Hyper-V features for guests running Windows.
 //
+// The Preset selects a set of enlightenments, which can be adjusted by
+// naming enlightenments to Enable or Disable without the "hv-" prefix, such
+// as "evmcs" or "spinlocks=0x2000". When Passthrough is true, every
+// enlightenment supported by the host is enabled instead of a preset. If
+// VendorID is specified, it replaces the hypervisor vendor ID reported to
+// the guest.
+//
 // https://github.com/qemu/qemu/blob/master/docs/hyperv.txt
 type Enlightenments struct {
-	Enabled bool `json:"enabled,omitempty"`
+	Enabled     bool                `json:"enabled,omitempty"`
+	Preset      EnlightenmentPreset `json:"preset,omitempty"`
+	Enable      []string            `json:"enable,omitempty"`
+	Disable     []string            `json:"disable,omitempty"`
+	Passthrough bool                `json:"passthrough,omitempty"`
+	VendorID    string              `json:"vendor-id,omitempty"`
+}
+
+// Validate returns an error if the enlightenments are invalid.
+func (e Enlightenments) Validate() error {
+	if e.Preset != "" && !e.Preset.Valid() {
+		return fmt.Errorf("unrecognized enlightenment preset: \"%s\"", e.Preset)
+	}
+	if e.Passthrough && e.Preset != "" {
+		return errors.New("an enlightenment preset cannot be combined with passthrough")
+	}
+	if len(e.VendorID) > 12 {
+		return fmt.Errorf("the hypervisor vendor ID \"%s\" is longer than 12 characters", e.VendorID)
+	}
+	return nil
 }
 
 // Config adds the enlightenments configuration to the summary.
 func (e *Enlightenments) Config(out summary.Interface) {
-	if e.Enabled {
+	if !e.Enabled {
+		return
+	}
+	switch {
+	case e.Passthrough:
+		out.Add("Hyper-V Enlightenments: Passthrough")
+	case e.Preset != "":
+		out.Add("Hyper-V Enlightenments: %s", e.Preset)
+	default:
 		out.Add("Hyper-V Enlightenments: Enabled")
 	}
+	if len(e.Enable) > 0 {
+		out.Add("Hyper-V Enlightenments Enabled: %s", strings.Join(e.Enable, ", "))
+	}
+	if len(e.Disable) > 0 {
+		out.Add("Hyper-V Enlightenments Disabled: %s", strings.Join(e.Disable, ", "))
+	}
+	if e.VendorID != "" {
+		out.Add("Hyper-V Vendor ID: %s", e.VendorID)
+	}
 }
 
 func overlayEnlightenments(merged, overlay *Enlightenments) {
 	if overlay.Enabled {
 		merged.Enabled = overlay.Enabled
 	}
+	if overlay.Preset != "" {
+		merged.Preset = overlay.Preset
+	}
+	if len(overlay.Enable) > 0 {
+		merged.Enable = overlay.Enable
+	}
+	if len(overlay.Disable) > 0 {
+		merged.Disable = overlay.Disable
+	}
+	if overlay.Passthrough {
+		merged.Passthrough = overlay.Passthrough
+	}
+	if overlay.VendorID != "" {
+		merged.VendorID = overlay.VendorID
+	}
+}
+
+// EnlightenmentPreset identifies a named set of Hyper-V enlightenments.
+type EnlightenmentPreset string
+
+// Enlightenment presets.
+const (
+	// StandardEnlightenments are recommended for modern Windows guests.
+	// It is the default preset.
+	StandardEnlightenments = EnlightenmentPreset("standard")
+
+	// MinimalEnlightenments are understood by older Windows guests.
+	MinimalEnlightenments = EnlightenmentPreset("minimal")
+)
+
+// Valid returns true if p is a recognized enlightenment preset.
+func (p EnlightenmentPreset) Valid() bool {
+	return p == StandardEnlightenments || p == MinimalEnlightenments
+}
+
+// Nested describes whether a machine can run virtual machines of its own.
+//
+// When enabled, the hardware virtualization extensions of the processor,
+// vmx or svm, are exposed to the guest. The host's KVM module must have
+// nested virtualization enabled.
+type Nested struct {
+	Enabled bool `json:"enabled,omitempty"`
+}
+
+// Config adds the nested virtualization configuration to the summary.
+func (n *Nested) Config(out summary.Interface) {
+	if n.Enabled {
+		out.Add("Nested Virtualization: Enabled")
+	}
+}
+
+func overlayNested(merged, overlay *Nested) {
+	if overlay.Enabled {
+		merged.Enabled = overlay.Enabled
+	}
+}
+
+// SEV describes the AMD Secure Encrypted Virtualization attributes of
+// a machine. When enabled, the machine's memory is encrypted with a key
+// that the host cannot access. When ES is enabled, the machine's CPU
+// register state is also encrypted.
+//
+// Policy overrides the guest policy. CBitPos and ReducedPhysBits describe
+// the host processor and must be specified. QEMU rejects values that don't
+// match the host, which reports them in CPUID leaf 0x8000001F and in the
+// response to the query-sev-capabilities QMP command.
+type SEV struct {
+	Enabled         bool   `json:"enabled,omitempty"`
+	ES              bool   `json:"es,omitempty"`
+	Policy          uint32 `json:"policy,omitempty"`
+	CBitPos         int    `json:"cbitpos,omitempty"`
+	ReducedPhysBits int    `json:"reduced-phys-bits,omitempty"`
+}
+
+// SEV guest policy bits.
+const (
+	sevPolicyNoDebug = 0x1
+	sevPolicyES      = 0x4
+)
+
+// EffectivePolicy returns the guest policy. If a policy hasn't been
+// specified, debugging is disabled and SEV-ES is enabled when requested.
+func (s SEV) EffectivePolicy() uint32 {
+	if s.Policy != 0 {
+		return s.Policy
+	}
+	policy := uint32(sevPolicyNoDebug)
+	if s.ES {
+		policy |= sevPolicyES
+	}
+	return policy
+}
+
+// Validate returns an error if the SEV configuration is invalid.
+func (s SEV) Validate() error {
+	if s.ES && s.Policy != 0 && s.Policy&sevPolicyES == 0 {
+		return fmt.Errorf("the SEV policy 0x%x does not enable SEV-ES", s.Policy)
+	}
+	if s.CBitPos < 1 {
+		return errors.New("SEV requires the cbitpos of the host processor to be specified")
+	}
+	if s.ReducedPhysBits < 1 {
+		return errors.New("SEV requires the reduced-phys-bits of the host processor to be specified")
+	}
+	return nil
+}
+
+// Config adds the SEV configuration to the summary.
+func (s *SEV) Config(out summary.Interface) {
+	if !s.Enabled {
+		return
+	}
+	if s.ES {
+		out.Add("Secure Encrypted Virtualization: SEV-ES (policy 0x%x)", s.EffectivePolicy())
+	} else {
+		out.Add("Secure Encrypted Virtualization: SEV (policy 0x%x)", s.EffectivePolicy())
+	}
+}
+
+func overlaySEV(merged, overlay *SEV) {
+	if overlay.Enabled {
+		merged.Enabled = overlay.Enabled
+	}
+	if overlay.ES {
+		merged.ES = overlay.ES
+	}
+	if overlay.Policy != 0 {
+		merged.Policy = overlay.Policy
+	}
+	if overlay.CBitPos > 0 {
+		merged.CBitPos = overlay.CBitPos
+	}
+	if overlay.ReducedPhysBits > 0 {
+		merged.ReducedPhysBits = overlay.ReducedPhysBits
+	}
 }
 
 // TPM describes the attributes of a machine's Trusted Platform Module
@@ -382,6 +961,27 @@ func overlaySpice(merged, overlay *Spice) {
 	}
 }
 
+// Hotplug describes the attributes of a machine that allow devices to be
+// added while it is running.
+type Hotplug struct {
+	// RootPorts is the number of spare PCI Express root ports that are
+	// reserved for hotplugged devices.
+	RootPorts int `json:"root-ports,omitempty"`
+}
+
+// Config adds the hotplug configuration to the summary.
+func (h *Hotplug) Config(out summary.Interface) {
+	if h.RootPorts > 0 {
+		out.Add("Spare Hotplug Root Ports: %d", h.RootPorts)
+	}
+}
+
+func overlayHotplug(merged, overlay *Hotplug) {
+	if overlay.RootPorts > 0 {
+		merged.RootPorts = overlay.RootPorts
+	}
+}
+
 func unionStrings(a []string, b []string) []string {
 	alen := len(a)
 	blen := len(b)
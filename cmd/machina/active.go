@@ -0,0 +1,116 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"strconv"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/systemd"
+	"github.com/gentlemanautomaton/machina/systemdgen"
+)
+
+// collectActiveMachines returns the set of machines that are running or
+// being started on the local system. A machine is active if its systemd
+// unit is active or transitioning between states, or if a QEMU process is
+// running for it, as is the case for machines started with machina run.
+//
+// An error is returned if the status of the units cannot be determined.
+func collectActiveMachines(ctx context.Context) (map[machina.MachineName]bool, error) {
+	names, err := EnumMachines()
+	if err != nil {
+		return nil, err
+	}
+
+	units := make(map[string]machina.MachineName, len(names))
+	unitNames := make([]string, 0, len(names))
+	for _, name := range names {
+		unit := fmt.Sprintf("%s.service", systemdgen.UnitNameForQEMU(name))
+		units[unit] = name
+		unitNames = append(unitNames, unit)
+	}
+
+	running := runningQEMUMachines(os.DirFS("/proc"))
+
+	statuses, err := systemd.ListUnitStatuses(ctx, unitNames...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine which machines are active: %w", err)
+	}
+
+	active := make(map[machina.MachineName]bool)
+	for _, status := range statuses {
+		if name, ok := units[status.Name]; ok && status.Active() {
+			active[name] = true
+		}
+	}
+
+	// Only include QEMU processes that belong to machines managed by
+	// machina.
+	for _, name := range names {
+		if running[name] {
+			active[name] = true
+		}
+	}
+
+	return active, nil
+}
+
+// runningQEMUMachines returns the names of the machines with QEMU processes
+// in the given proc file system. Machines are identified by the -name
+// option that machina supplies to QEMU.
+//
+// Processes that exit or can't be inspected while the file system is being
+// read are skipped.
+func runningQEMUMachines(proc fs.FS) map[machina.MachineName]bool {
+	running := make(map[machina.MachineName]bool)
+
+	entries, err := fs.ReadDir(proc, ".")
+	if err != nil {
+		return running
+	}
+
+	for _, entry := range entries {
+		if _, err := strconv.Atoi(entry.Name()); err != nil || !entry.IsDir() {
+			continue
+		}
+		data, err := fs.ReadFile(proc, path.Join(entry.Name(), "cmdline"))
+		if err != nil {
+			continue
+		}
+		args := strings.Split(string(bytes.TrimRight(data, "\x00")), "\x00")
+		if !strings.HasPrefix(path.Base(args[0]), "qemu-system-") {
+			continue
+		}
+		for i := 1; i+1 < len(args); i++ {
+			if args[i] != "-name" {
+				continue
+			}
+			if name := qemuGuestName(args[i+1]); name != "" {
+				running[machina.MachineName(name)] = true
+			}
+			break
+		}
+	}
+
+	return running
+}
+
+// qemuGuestName returns the guest name from the value of QEMU's -name
+// option, which can be supplied in either of these forms:
+//
+//	name
+//	guest=name,debug-threads=on
+func qemuGuestName(value string) string {
+	params := strings.Split(value, ",")
+	for _, param := range params {
+		if name, ok := strings.CutPrefix(param, "guest="); ok {
+			return name
+		}
+	}
+	return params[0]
+}
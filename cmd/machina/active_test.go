@@ -0,0 +1,54 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gentlemanautomaton/machina"
+)
+
+func TestRunningQEMUMachines(t *testing.T) {
+	cmdline := func(args ...string) *fstest.MapFile {
+		var data []byte
+		for _, arg := range args {
+			data = append(data, arg...)
+			data = append(data, 0)
+		}
+		return &fstest.MapFile{Data: data}
+	}
+
+	proc := fstest.MapFS{
+		"1/cmdline":    cmdline("/sbin/init"),
+		"100/cmdline":  cmdline("qemu-system-x86_64", "-uuid", "5b4f2a3c-0d7e-4c9a-8a5e-1f2b3c4d5e6f", "-name", "alpha", "-m", "4096"),
+		"200/cmdline":  cmdline("/usr/bin/qemu-system-x86_64", "-name", "guest=beta,debug-threads=on"),
+		"300/cmdline":  cmdline("/usr/bin/vim", "-name", "gamma"),
+		"400/cmdline":  cmdline("qemu-system-x86_64", "-m", "1024"),
+		"self/cmdline": cmdline("qemu-system-x86_64", "-name", "delta"),
+	}
+
+	got := slices.Sorted(maps.Keys(runningQEMUMachines(proc)))
+	want := []machina.MachineName{"alpha", "beta"}
+	if !slices.Equal(got, want) {
+		t.Errorf("got %v, want %v", got, want)
+	}
+}
+
+func TestPassthroughClaimMapActive(t *testing.T) {
+	claims := passthroughClaimMap{
+		"0000:01:00.0": {{Machine: "alpha", Device: "gpu"}, {Machine: "beta", Device: "gpu"}},
+		"0000:02:00.0": {{Machine: "beta", Device: "nic"}},
+	}
+
+	active := claims.Active(map[machina.MachineName]bool{"alpha": true})
+	if len(active) != 1 {
+		t.Fatalf("expected claims on 1 device, got %d", len(active))
+	}
+	if others := active.Others("0000:01:00.0", "beta"); len(others) != 1 || others[0].Machine != "alpha" {
+		t.Errorf("unexpected claims on 0000:01:00.0: %v", others)
+	}
+	if others := active.Others("0000:02:00.0", "alpha"); len(others) != 0 {
+		t.Errorf("claims by inactive machines should be ignored: %v", others)
+	}
+}
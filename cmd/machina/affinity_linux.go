@@ -0,0 +1,20 @@
+//go:build linux
+// +build linux
+
+package main
+
+import (
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+	"golang.org/x/sys/unix"
+)
+
+// setThreadAffinity restricts the thread with the given ID to the given
+// host CPUs.
+func setThreadAffinity(tid int, cpus sysfs.CPUList) error {
+	var set unix.CPUSet
+	set.Zero()
+	for _, cpu := range cpus {
+		set.Set(cpu)
+	}
+	return unix.SchedSetaffinity(tid, &set)
+}
@@ -0,0 +1,16 @@
+//go:build !linux
+// +build !linux
+
+package main
+
+import (
+	"errors"
+
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+// setThreadAffinity restricts the thread with the given ID to the given
+// host CPUs. It is only supported on linux.
+func setThreadAffinity(tid int, cpus sysfs.CPUList) error {
+	return errors.New("thread affinity is not supported on this platform")
+}
@@ -3,10 +3,10 @@ package main
 import (
 	"context"
 	"fmt"
+	"strings"
 
 	"github.com/gentlemanautomaton/machina"
 	"github.com/gentlemanautomaton/machina/qemu/qvm"
-	"github.com/gentlemanautomaton/machina/qemugen"
 )
 
 // ArgsCmd displays the QEMU arguments used to invocake one or more virtual machines.
@@ -30,7 +30,7 @@ func (cmd ArgsCmd) Run(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("failed to load machine configuration for \"%s\": %v", name, err)
 		}
-		vm, err := qemugen.Build(machine, sys)
+		vm, err := buildQEMU(machine, sys, false)
 		if err != nil {
 			return fmt.Errorf("failed to build configuration for \"%s\": %v", name, err)
 		}
@@ -45,6 +45,13 @@ func (cmd ArgsCmd) Run(ctx context.Context) error {
 		if len(vms) > 1 {
 			fmt.Printf("----%s----\n", machines[i].Name)
 		}
+		if spare := vms[i].Topology.SpareRoots(); len(spare) > 0 {
+			ids := make([]string, 0, len(spare))
+			for _, root := range spare {
+				ids = append(ids, string(root.ID()))
+			}
+			fmt.Printf("# Spare hotplug root ports: %s\n", strings.Join(ids, ", "))
+		}
 		fmt.Println(vms[i].Options().String())
 	}
 
@@ -0,0 +1,240 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
+	"github.com/gentlemanautomaton/machina/qemu/qhost/blockdev"
+	"github.com/gentlemanautomaton/machina/qemu/qvm"
+	"github.com/gentlemanautomaton/machina/qemugen"
+	"github.com/gentlemanautomaton/machina/qmp"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+// AttachCmd adds volumes and connections to running virtual machines.
+type AttachCmd struct {
+	VolumesOrConnections []string `kong:"arg,predictor=machines,help='Volumes or connections to attach. Use [machine].[volume] or [machine].[conn].'"`
+}
+
+// Run executes the attach command.
+func (cmd AttachCmd) Run(ctx context.Context) error {
+	return hotplugResources(ctx, cmd.VolumesOrConnections, true)
+}
+
+// hotplugTarget describes a volume or connection of a machine that is
+// hotplugged into or out of a running machine.
+type hotplugTarget struct {
+	Machine    machina.Machine
+	Definition machina.Definition
+	Volume     *machina.Volume
+	Connection *machina.Connection
+}
+
+// hotplugResources attaches or detaches each of the named volumes or
+// connections through QMP.
+func hotplugResources(ctx context.Context, names []string, attach bool) error {
+	sys, err := LoadSystem()
+	if err != nil {
+		return fmt.Errorf("failed to load system configuration: %v", err)
+	}
+
+	var firstError error
+	for _, name := range names {
+		if err := hotplugResource(ctx, sys, name, attach); err != nil {
+			if firstError == nil {
+				firstError = err
+			}
+			fmt.Printf("%s: failed: %v\n", name, err)
+		}
+	}
+	return firstError
+}
+
+func hotplugResource(ctx context.Context, sys machina.System, name string, attach bool) error {
+	target, err := loadHotplugTarget(sys, name)
+	if err != nil {
+		return err
+	}
+
+	// Generate the machine's QEMU configuration so that hotplugged devices
+	// have the same properties that they would have when the machine
+	// starts.
+	vm, err := buildQEMU(target.Machine, sys, false)
+	if err != nil {
+		return fmt.Errorf("failed to generate QEMU configuration: %v", err)
+	}
+
+	client, err := connectToMachineQMP(ComposedMachine{MachineInfo: target.Machine.Info(), Definition: target.Definition})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	switch {
+	case target.Volume != nil && attach:
+		err = attachVolume(ctx, client, sys, target, &vm)
+	case target.Volume != nil:
+		err = detachVolume(ctx, client, sys, target, &vm)
+	case attach:
+		err = attachConnection(ctx, client, sys, target, &vm)
+	default:
+		err = detachConnection(ctx, client, sys, target)
+	}
+	if err != nil {
+		return err
+	}
+
+	if attach {
+		fmt.Printf("%s: attached\n", name)
+	} else {
+		fmt.Printf("%s: detached\n", name)
+	}
+	return nil
+}
+
+// loadHotplugTarget loads the machine volume or connection identified by
+// name, which must be in the form [machine].[volume] or [machine].[conn].
+func loadHotplugTarget(sys machina.System, name string) (hotplugTarget, error) {
+	machineName, resourceName, ok := strings.Cut(name, ".")
+	if !ok || resourceName == "" {
+		return hotplugTarget{}, fmt.Errorf("a volume or connection must be specified as [machine].[volume] or [machine].[conn]")
+	}
+
+	machine, err := LoadMachine(machina.MachineName(machineName))
+	if err != nil {
+		return hotplugTarget{}, fmt.Errorf("failed to load machine configuration: %v", err)
+	}
+	definition, err := machina.Build(machine, sys)
+	if err != nil {
+		return hotplugTarget{}, fmt.Errorf("failed to build configuration: %v", err)
+	}
+
+	target := hotplugTarget{Machine: machine, Definition: definition}
+	for i := range definition.Volumes {
+		if string(definition.Volumes[i].Name) == resourceName {
+			target.Volume = &definition.Volumes[i]
+			break
+		}
+	}
+	for i := range definition.Connections {
+		if string(definition.Connections[i].Name) == resourceName {
+			target.Connection = &definition.Connections[i]
+			break
+		}
+	}
+
+	switch {
+	case target.Volume != nil && target.Connection != nil:
+		return hotplugTarget{}, fmt.Errorf("the machine has both a volume and a connection named \"%s\"", resourceName)
+	case target.Volume == nil && target.Connection == nil:
+		return hotplugTarget{}, fmt.Errorf("the machine does not have a volume or connection named \"%s\"", resourceName)
+	}
+
+	return target, nil
+}
+
+// attachVolume adds the block device nodes and the disk device for
+// a volume to a running machine.
+func attachVolume(ctx context.Context, client *qmp.Client, sys machina.System, target hotplugTarget, vm *qvm.Definition) error {
+	volume := *target.Volume
+
+	device, found := findGeneratedDevice(&vm.Topology, qemugen.VolumeDeviceID(volume))
+	if !found {
+		return fmt.Errorf("volume %s uses storage that does not support hotplug", volume.Name)
+	}
+
+	top, err := qemugen.VolumeNodeName(target.Machine.Info(), target.Definition.Vars, volume, sys.Storage)
+	if err != nil {
+		return err
+	}
+	lineage, err := blockdevLineage(vm.Resources.BlockDevs(), top)
+	if err != nil {
+		return err
+	}
+
+	// Add the block device nodes from the bottom of the graph up, so that
+	// each node's children exist before it is added.
+	var added []blockdev.Node
+	rollback := func() {
+		for i := len(added) - 1; i >= 0; i-- {
+			client.Execute(ctx, qmpcmd.BlockdevDel{NodeName: string(added[i].Name())})
+		}
+	}
+	for i := len(lineage) - 1; i >= 0; i-- {
+		node := lineage[i]
+		if err := client.Execute(ctx, qmpcmd.BlockdevAdd{Arguments: blockdev.Arguments(node)}); err != nil {
+			rollback()
+			return fmt.Errorf("failed to add block device node \"%s\": %w", node.Name(), err)
+		}
+		added = append(added, node)
+	}
+
+	// Let QEMU choose a free SCSI target for disks on a SCSI controller.
+	cmd := deviceAddCommand(device, "scsi-id", "lun")
+	if err := placeDeviceAddCommand(ctx, client, &vm.Topology, &cmd); err != nil {
+		rollback()
+		return err
+	}
+	if err := client.Execute(ctx, cmd); err != nil {
+		rollback()
+		return fmt.Errorf("failed to add device \"%s\": %w", cmd.ID, err)
+	}
+
+	return nil
+}
+
+// hotplugNetDevID returns the identifier of the network backend that is
+// hotplugged for conn. It is distinct from the identifiers assigned when
+// the machine starts, which are based on the order of its connections.
+func hotplugNetDevID(conn machina.Connection) string {
+	return "hotplug." + string(conn.Name)
+}
+
+// attachConnection adds the network backend and the network adapter for
+// a connection to a running machine.
+func attachConnection(ctx context.Context, client *qmp.Client, sys machina.System, target hotplugTarget, vm *qvm.Definition) error {
+	conn := *target.Connection
+
+	device, found := findGeneratedDevice(&vm.Topology, qemugen.NetworkDeviceID(conn))
+	if !found {
+		return fmt.Errorf("the QEMU configuration for connection %s could not be found", conn.Name)
+	}
+	cmd := deviceAddCommand(device)
+
+	var netdev qhost.NetDev
+	for _, candidate := range vm.Resources.NetDevs() {
+		if string(candidate.ID()) == cmd.Properties["netdev"] {
+			netdev = candidate
+			break
+		}
+	}
+	if netdev == nil {
+		return fmt.Errorf("the QEMU network backend for connection %s could not be found", conn.Name)
+	}
+
+	if err := prepareConnection(target.Machine.Name, target.Definition.Privileges, conn, sys); err != nil {
+		return err
+	}
+
+	id := hotplugNetDevID(conn)
+	args := qhost.NetDevArguments(netdev)
+	args["id"] = id
+	if err := client.Execute(ctx, qmpcmd.NetdevAdd{Arguments: args}); err != nil {
+		return fmt.Errorf("failed to add network backend \"%s\": %w", id, err)
+	}
+
+	cmd.Properties["netdev"] = id
+	if err := placeDeviceAddCommand(ctx, client, &vm.Topology, &cmd); err != nil {
+		client.Execute(ctx, qmpcmd.NetdevDel{ID: id})
+		return err
+	}
+	if err := client.Execute(ctx, cmd); err != nil {
+		client.Execute(ctx, qmpcmd.NetdevDel{ID: id})
+		return fmt.Errorf("failed to add device \"%s\": %w", cmd.ID, err)
+	}
+
+	return nil
+}
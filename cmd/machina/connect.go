@@ -10,10 +10,14 @@ import (
 // ConnectCmd enables the connections to one or more virtual machines.
 type ConnectCmd struct {
 	MachinesOrConnections []string `kong:"arg,help='Machines or individual connections to bridge. Use [machine] or [machine].[conn].'"`
+	Link                  bool     `kong:"link,help='Also bring up the link seen by the guest through QMP.'"`
 }
 
 // Run executes the connect command.
 func (cmd ConnectCmd) Run(ctx context.Context) error {
+	if cmd.Link {
+		return connectWithLink(ctx, cmd.MachinesOrConnections)
+	}
 	return connect(cmd.MachinesOrConnections)
 }
 
@@ -26,6 +30,10 @@ func connect(names []string) error {
 	var firstError error
 	for _, mconn := range mconns {
 		link := machina.MakeLinkName(mconn.Machine, mconn.Connection)
+		if network, ok := sys.Network[mconn.Network]; ok && network.EffectiveType().UserMode() {
+			fmt.Printf("%s: skipped: user-mode network\n", link)
+			continue
+		}
 		if err := enableConnection(mconn.Machine, mconn.Connection, sys); err != nil {
 			if firstError == nil {
 				firstError = err
@@ -37,3 +45,38 @@ func connect(names []string) error {
 	}
 	return firstError
 }
+
+// connectWithLink enables the host side of each connection and then brings
+// up the guest-visible link state of its network adapter through QMP.
+//
+// User-mode networks have no host side, but their guest-visible link state
+// is still updated.
+func connectWithLink(ctx context.Context, names []string) error {
+	mconns, sys, err := LoadMachineConnections(names...)
+	if err != nil {
+		return err
+	}
+
+	var firstError error
+	for _, mconn := range mconns {
+		link := machina.MakeLinkName(mconn.Machine, mconn.Connection)
+		if network, ok := sys.Network[mconn.Network]; !ok || !network.EffectiveType().UserMode() {
+			if err := enableConnection(mconn.Machine, mconn.Connection, sys); err != nil {
+				if firstError == nil {
+					firstError = err
+				}
+				fmt.Printf("%s: failed: %v\n", link, err)
+				continue
+			}
+		}
+		if err := setGuestLink(ctx, mconn.Machine, mconn.Connection, true); err != nil {
+			if firstError == nil {
+				firstError = err
+			}
+			fmt.Printf("%s: failed to bring up guest link: %v\n", link, err)
+		} else {
+			fmt.Printf("%s: enabled (guest link up)\n", link)
+		}
+	}
+	return firstError
+}
@@ -0,0 +1,181 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"text/tabwriter"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qmp"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+// CPUCmd manages the virtual CPUs of running virtual machines.
+type CPUCmd struct {
+	Set CPUSetCmd `kong:"cmd,help='Changes the number of virtual CPUs in a running virtual machine.'"`
+}
+
+// CPUSetCmd changes the number of virtual CPUs in a running virtual machine.
+type CPUSetCmd struct {
+	Machine machina.MachineName `kong:"arg,predictor=machines,help='Virtual machine to change.'"`
+	Count   int                 `kong:"arg,help='Number of virtual CPUs.'"`
+}
+
+// Run executes the cpu set command.
+func (cmd CPUSetCmd) Run(ctx context.Context) error {
+	vms, _, err := LoadAndComposeMachines(cmd.Machine)
+	if err != nil {
+		return err
+	}
+	vm := vms[0]
+
+	client, err := connectToMachineQMP(vm)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	slots, err := queryCPUSlots(ctx, client)
+	if err != nil {
+		return err
+	}
+
+	add, remove, err := planCPUChanges(slots, cmd.Count)
+	if err != nil {
+		return err
+	}
+
+	for _, slot := range add {
+		if err := client.Execute(ctx, slot); err != nil {
+			return fmt.Errorf("failed to add vCPU %s: %w", slot.ID, err)
+		}
+		fmt.Printf("%s: added vCPU %s\n", vm.Name, slot.ID)
+	}
+
+	for _, id := range remove {
+		if err := deleteDevice(ctx, client, id); err != nil {
+			return fmt.Errorf("failed to remove vCPU %s: %w", id, err)
+		}
+		fmt.Printf("%s: removed vCPU %s\n", vm.Name, id)
+	}
+
+	return nil
+}
+
+// queryCPUSlots returns the virtual CPU slots of a running virtual machine
+// in topological order.
+func queryCPUSlots(ctx context.Context, client *qmp.Client) ([]qmpcmd.HotpluggableCPU, error) {
+	var query qmpcmd.QueryHotpluggableCPUs
+	if err := client.Execute(ctx, &query); err != nil {
+		return nil, fmt.Errorf("failed to query hotpluggable CPUs: %w", err)
+	}
+	sortCPUSlots(query.Response)
+	return query.Response, nil
+}
+
+// cpuTopologyProps lists the CPU slot properties in order of significance.
+var cpuTopologyProps = []string{"node-id", "socket-id", "die-id", "cluster-id", "module-id", "core-id", "thread-id"}
+
+// sortCPUSlots sorts CPU slots by their location in the processor topology.
+func sortCPUSlots(slots []qmpcmd.HotpluggableCPU) {
+	slices.SortStableFunc(slots, func(a, b qmpcmd.HotpluggableCPU) int {
+		for _, prop := range cpuTopologyProps {
+			if c := cmp.Compare(a.Props[prop], b.Props[prop]); c != 0 {
+				return c
+			}
+		}
+		return 0
+	})
+}
+
+// cpuDeviceID returns the device identifier for a vCPU that is hotplugged
+// into the slot with the given index.
+func cpuDeviceID(index int) string {
+	return "vcpu." + strconv.Itoa(index)
+}
+
+// planCPUChanges determines the CPUs that must be added or removed to
+// bring the number of online vCPUs to count. The slots must be sorted.
+//
+// vCPUs are added to the first unoccupied slots and removed from the last
+// occupied slots. Only vCPUs that were hotplugged can be removed.
+func planCPUChanges(slots []qmpcmd.HotpluggableCPU, count int) (add []qmpcmd.CPUAdd, remove []string, err error) {
+	var total, online int
+	for _, slot := range slots {
+		total += slot.VCPUsCount
+		if slot.Online() {
+			online += slot.VCPUsCount
+		}
+	}
+
+	switch {
+	case count <= 0:
+		return nil, nil, fmt.Errorf("a machine must have at least one vCPU")
+	case count > total:
+		return nil, nil, fmt.Errorf("the machine supports a maximum of %d vCPUs", total)
+	}
+
+	for i := 0; i < len(slots) && online < count; i++ {
+		slot := slots[i]
+		if slot.Online() {
+			continue
+		}
+		add = append(add, qmpcmd.CPUAdd{
+			Driver: slot.Type,
+			ID:     cpuDeviceID(i),
+			Props:  slot.Props,
+		})
+		online += slot.VCPUsCount
+	}
+
+	for i := len(slots) - 1; i >= 0 && online > count; i-- {
+		slot := slots[i]
+		if !slot.Online() {
+			continue
+		}
+		if !slot.Removable() {
+			return nil, nil, fmt.Errorf("the machine can't be reduced to %d vCPUs because the vCPUs it started with can't be removed", count)
+		}
+		remove = append(remove, slot.DeviceID())
+		online -= slot.VCPUsCount
+	}
+
+	return add, remove, nil
+}
+
+// formatCPUSlots returns a table describing the virtual CPU slots of
+// a machine. The thread IDs of online vCPUs are looked up by their QOM
+// path.
+func formatCPUSlots(slots []qmpcmd.HotpluggableCPU, cpus []qmpcmd.CPUInfo) string {
+	threads := make(map[string]int, len(cpus))
+	for _, cpu := range cpus {
+		threads[cpu.QOMPath] = cpu.ThreadID
+	}
+
+	var (
+		out           bytes.Buffer
+		online, total int
+	)
+	w := tabwriter.NewWriter(&out, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "SOCKET\tCORE\tTHREAD\tSTATE\tTHREAD ID\tDEVICE\t")
+	for _, slot := range slots {
+		total += slot.VCPUsCount
+		state, thread := "offline", ""
+		if slot.Online() {
+			state = "online"
+			online += slot.VCPUsCount
+			if id, ok := threads[slot.QOMPath]; ok {
+				thread = strconv.Itoa(id)
+			}
+		}
+		fmt.Fprintf(w, "%d\t%d\t%d\t%s\t%s\t%s\t\n", slot.Props["socket-id"], slot.Props["core-id"], slot.Props["thread-id"], state, thread, slot.DeviceID())
+	}
+	w.Flush()
+	fmt.Fprintf(&out, "%d of %d vCPUs online\n", online, total)
+
+	return out.String()
+}
@@ -0,0 +1,51 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+func TestPlanCPUChanges(t *testing.T) {
+	slot := func(socket, core int, qomPath string) qmpcmd.HotpluggableCPU {
+		return qmpcmd.HotpluggableCPU{
+			Type:       "host-x86_64-cpu",
+			VCPUsCount: 1,
+			Props:      map[string]int{"socket-id": socket, "core-id": core, "thread-id": 0},
+			QOMPath:    qomPath,
+		}
+	}
+
+	// QEMU reports the slots in reverse order.
+	slots := []qmpcmd.HotpluggableCPU{
+		slot(1, 1, ""),
+		slot(1, 0, "/machine/peripheral/vcpu.2"),
+		slot(0, 1, "/machine/unattached/device[1]"),
+		slot(0, 0, "/machine/unattached/device[0]"),
+	}
+	sortCPUSlots(slots)
+
+	add, remove, err := planCPUChanges(slots, 4)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(add) != 1 || add[0].ID != "vcpu.3" || add[0].Props["socket-id"] != 1 || add[0].Props["core-id"] != 1 || len(remove) != 0 {
+		t.Errorf("unexpected changes when adding vCPUs: %v %v", add, remove)
+	}
+
+	add, remove, err = planCPUChanges(slots, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(add) != 0 || !slices.Equal(remove, []string{"vcpu.2"}) {
+		t.Errorf("unexpected changes when removing vCPUs: %v %v", add, remove)
+	}
+
+	if _, _, err := planCPUChanges(slots, 1); err == nil {
+		t.Errorf("expected an error when removing vCPUs the machine started with")
+	}
+	if _, _, err := planCPUChanges(slots, 5); err == nil {
+		t.Errorf("expected an error when exceeding the maximum number of vCPUs")
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qvm"
+	"github.com/gentlemanautomaton/machina/qemugen"
+	"github.com/gentlemanautomaton/machina/qmp"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+// DetachCmd removes volumes and connections from running virtual machines.
+type DetachCmd struct {
+	VolumesOrConnections []string `kong:"arg,predictor=machines,help='Volumes or connections to detach. Use [machine].[volume] or [machine].[conn].'"`
+}
+
+// Run executes the detach command.
+func (cmd DetachCmd) Run(ctx context.Context) error {
+	return hotplugResources(ctx, cmd.VolumesOrConnections, false)
+}
+
+// detachVolume removes the disk device and the block device nodes for
+// a volume from a running machine.
+func detachVolume(ctx context.Context, client *qmp.Client, sys machina.System, target hotplugTarget, vm *qvm.Definition) error {
+	volume := *target.Volume
+
+	id := string(qemugen.VolumeDeviceID(volume))
+	if err := deleteDevice(ctx, client, id); err != nil {
+		return fmt.Errorf("failed to remove device \"%s\": %w", id, err)
+	}
+
+	top, err := qemugen.VolumeNodeName(target.Machine.Info(), target.Definition.Vars, volume, sys.Storage)
+	if err != nil {
+		return err
+	}
+	lineage, err := blockdevLineage(vm.Resources.BlockDevs(), top)
+	if err != nil {
+		return err
+	}
+
+	// Remove the block device nodes from the top of the graph down, so
+	// that each node is unused when it is removed.
+	for _, node := range lineage {
+		if err := client.Execute(ctx, qmpcmd.BlockdevDel{NodeName: string(node.Name())}); err != nil {
+			return fmt.Errorf("failed to remove block device node \"%s\": %w", node.Name(), err)
+		}
+	}
+
+	return nil
+}
+
+// detachConnection removes the network adapter and the network backend
+// for a connection from a running machine.
+func detachConnection(ctx context.Context, client *qmp.Client, sys machina.System, target hotplugTarget) error {
+	conn := *target.Connection
+
+	// The network backend's identifier depends on whether the connection
+	// was present when the machine started or was hotplugged later, so ask
+	// QEMU which backend the adapter is using.
+	id := string(qemugen.NetworkDeviceID(conn))
+	query := qmpcmd.QOMGet{Path: "/machine/peripheral/" + id, Property: "netdev"}
+	if err := client.Execute(ctx, &query); err != nil {
+		return fmt.Errorf("failed to identify the network backend of device \"%s\": %w", id, err)
+	}
+	netdev, err := query.String()
+	if err != nil {
+		return fmt.Errorf("failed to identify the network backend of device \"%s\": %w", id, err)
+	}
+
+	if err := deleteDevice(ctx, client, id); err != nil {
+		return fmt.Errorf("failed to remove device \"%s\": %w", id, err)
+	}
+	if netdev != "" {
+		if err := client.Execute(ctx, qmpcmd.NetdevDel{ID: netdev}); err != nil {
+			return fmt.Errorf("failed to remove network backend \"%s\": %w", netdev, err)
+		}
+	}
+
+	return teardownConnection(target.Machine.Name, conn, sys)
+}
@@ -0,0 +1,340 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+	"github.com/gentlemanautomaton/machina/mdevdriver"
+)
+
+// DevicesCmd reports on the host devices used by virtual machines.
+type DevicesCmd struct {
+	List  DevicesListCmd  `kong:"cmd,help='Lists mediated devices, their capacity and the machines that claim them.'"`
+	IOMMU DevicesIOMMUCmd `kong:"cmd,name='iommu',help='Lists IOMMU groups and the machines that claim their devices.'"`
+	GC    DevicesGCCmd    `kong:"cmd,name='gc',help='Removes mediated devices that are not in use by any active machine.'"`
+}
+
+// DevicesListCmd lists the mediated devices on the physical devices in the
+// system configuration.
+type DevicesListCmd struct {
+	All bool `kong:"all,help='Include mediated device types that are not configured and not in use.'"`
+}
+
+// Run executes the devices list command.
+func (cmd DevicesListCmd) Run(ctx context.Context) error {
+	sys, err := LoadSystem()
+	if err != nil {
+		return fmt.Errorf("failed to load system configuration: %v", err)
+	}
+
+	if len(sys.MediatedDevices) == 0 {
+		fmt.Printf("No mediated devices are present in the system configuration.\n")
+		return nil
+	}
+
+	claims, err := collectMediatedDeviceClaims(sys)
+	if err != nil {
+		return err
+	}
+
+	bus := mdevfs.Local()
+	for _, name := range slices.Sorted(maps.Keys(sys.MediatedDevices)) {
+		mdev := sys.MediatedDevices[name]
+		fmt.Printf("%s (%s, %s):\n", name, mdev.Address, mdev.EffectiveDriver())
+
+		driver, err := mdevdriver.For(mdev)
+		if err != nil {
+			fmt.Printf("  %v\n", err)
+			continue
+		}
+
+		parent := bus.PhysicalDevice(mdev.Address)
+		if exists, err := parent.Exists(); err != nil {
+			fmt.Printf("  %v\n", err)
+			continue
+		} else if !exists {
+			fmt.Printf("  The physical device is not present.\n")
+			continue
+		}
+
+		types, err := parent.Types()
+		if err != nil {
+			fmt.Printf("  Failed to enumerate mediated device types: %v\n", err)
+			continue
+		}
+
+		// Determine which device classes are supplied by each type.
+		classes := make(map[string][]string)
+		for class, typ := range mdev.Classes {
+			classes[string(typ.Name)] = append(classes[string(typ.Name)], string(class))
+		}
+
+		for _, typ := range types {
+			devices, err := typ.Devices()
+			if err != nil {
+				fmt.Printf("  %s: failed to enumerate mediated devices: %v\n", typ.ID(), err)
+				continue
+			}
+
+			typeClasses := classes[typ.Name()]
+			if !cmd.All && len(typeClasses) == 0 && len(devices) == 0 {
+				continue
+			}
+
+			line := fmt.Sprintf("  %s [%s]:", typ.Name(), typ.ID())
+			if available, err := typ.AvailableInstances(); err != nil {
+				line += fmt.Sprintf(" unknown availability (%v)", err)
+			} else {
+				line += fmt.Sprintf(" %d available", available)
+			}
+			if len(typeClasses) > 0 {
+				slices.Sort(typeClasses)
+				line += fmt.Sprintf(" (classes: %s)", strings.Join(typeClasses, ", "))
+			}
+			fmt.Println(line)
+
+			for _, dev := range devices {
+				id, err := dev.ID()
+				if err != nil {
+					fmt.Printf("    %v\n", err)
+					continue
+				}
+
+				line := fmt.Sprintf("    %s", id)
+				if placement, err := driver.Placement(dev); err == nil {
+					line += fmt.Sprintf(" (placement %d)", placement)
+				} else if errors.Is(err, mdevfs.ErrNotApplicable) {
+					line += " (placement n/a)"
+				}
+				if claim, claimed := claims[id]; claimed {
+					line += fmt.Sprintf(": %s", claim)
+				} else {
+					line += ": orphaned, not claimed by any machine"
+				}
+				fmt.Println(line)
+			}
+		}
+	}
+
+	return nil
+}
+
+// mediatedDeviceClaimMap maps the IDs of mediated devices to the machine
+// devices that claim them.
+type mediatedDeviceClaimMap map[machina.DeviceID]deviceClaim
+
+// collectMediatedDeviceClaims loads the configuration of every machine on
+// the local system and returns the mediated device IDs that they claim.
+//
+// Machines with configuration that cannot be loaded are skipped.
+func collectMediatedDeviceClaims(sys machina.System) (mediatedDeviceClaimMap, error) {
+	definitions, err := loadAllDefinitions(sys)
+	if err != nil {
+		return nil, err
+	}
+
+	claims := make(mediatedDeviceClaimMap)
+	for _, name := range slices.Sorted(maps.Keys(definitions)) {
+		for _, device := range definitions[name].Devices {
+			if device.ID.IsZero() || len(sys.MediatedDevices.WithClass(device.Class)) == 0 {
+				continue
+			}
+			if _, exists := claims[device.ID]; exists {
+				continue
+			}
+			claims[device.ID] = deviceClaim{
+				Machine: name,
+				Device:  device.Name,
+			}
+		}
+	}
+
+	return claims, nil
+}
+
+// DevicesGCCmd removes mediated devices that do not belong to any active
+// machine. Mediated devices can be left behind when a machine is
+// removed, or when its teardown fails.
+type DevicesGCCmd struct {
+	DryRun bool `kong:"dry-run,short='n',help='Report the mediated devices that would be removed without removing them.'"`
+}
+
+// Run executes the devices gc command.
+func (cmd DevicesGCCmd) Run(ctx context.Context) error {
+	sys, err := LoadSystem()
+	if err != nil {
+		return fmt.Errorf("failed to load system configuration: %v", err)
+	}
+
+	if len(sys.MediatedDevices) == 0 {
+		fmt.Printf("No mediated devices are present in the system configuration.\n")
+		return nil
+	}
+
+	// Hold the mediated device lock so that we don't race with machines
+	// that are preparing their devices. The lock must be acquired before
+	// the active machines are collected, otherwise a machine could create
+	// its devices in between.
+	if !cmd.DryRun {
+		lock, err := lockMediatedDevices(ctx)
+		if err != nil {
+			return err
+		}
+		defer lock.Close()
+	}
+
+	keep, err := collectActiveMediatedDevices(ctx, sys)
+	if err != nil {
+		return err
+	}
+
+	var errs []error
+	bus := mdevfs.Local()
+	for _, name := range slices.Sorted(maps.Keys(sys.MediatedDevices)) {
+		mdev := sys.MediatedDevices[name]
+		parent := bus.PhysicalDevice(mdev.Address)
+		if exists, err := parent.Exists(); err != nil {
+			errs = append(errs, err)
+			continue
+		} else if !exists {
+			continue
+		}
+
+		types, err := parent.Types()
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to enumerate mediated device types for %s: %w", name, err))
+			continue
+		}
+
+		for _, typ := range types {
+			devices, err := typ.Devices()
+			if err != nil {
+				errs = append(errs, fmt.Errorf("failed to enumerate mediated devices of type %s for %s: %w", typ.ID(), name, err))
+				continue
+			}
+			for _, dev := range devices {
+				id, err := dev.ID()
+				if err != nil {
+					errs = append(errs, err)
+					continue
+				}
+				if _, active := keep[id]; active {
+					continue
+				}
+				if cmd.DryRun {
+					fmt.Printf("Would remove mediated device %s (%s) from %s\n", id, typ.Name(), name)
+					continue
+				}
+				fmt.Printf("Removing mediated device %s (%s) from %s\n", id, typ.Name(), name)
+				if err := dev.Remove(); err != nil {
+					errs = append(errs, fmt.Errorf("failed to remove mediated device %s: %w", id, err))
+				}
+			}
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// collectActiveMediatedDevices returns the IDs of the mediated devices that
+// are claimed by active machines, including machines started with
+// machina run.
+//
+// An error is returned if the status of the units cannot be determined, or
+// if the configuration of an active machine cannot be loaded, because the
+// devices in use would be unknown.
+func collectActiveMediatedDevices(ctx context.Context, sys machina.System) (map[machina.DeviceID]struct{}, error) {
+	machines, err := collectActiveMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	active := make(map[machina.DeviceID]struct{})
+	for _, name := range slices.Sorted(maps.Keys(machines)) {
+		machine, err := LoadMachine(name)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load machine configuration for active machine \"%s\": %v", name, err)
+		}
+		definition, err := machina.Build(machine, sys)
+		if err != nil {
+			return nil, fmt.Errorf("failed to build configuration for active machine \"%s\": %v", name, err)
+		}
+		for _, device := range definition.Devices {
+			if device.ID.IsZero() || len(sys.MediatedDevices.WithClass(device.Class)) == 0 {
+				continue
+			}
+			active[device.ID] = struct{}{}
+		}
+	}
+
+	return active, nil
+}
+
+// DevicesIOMMUCmd lists the IOMMU groups on the local system.
+type DevicesIOMMUCmd struct {
+	Claimed bool `kong:"claimed,help='Only list groups with devices claimed by machines.'"`
+}
+
+// Run executes the devices iommu command.
+func (cmd DevicesIOMMUCmd) Run(ctx context.Context) error {
+	sys, err := LoadSystem()
+	if err != nil {
+		return fmt.Errorf("failed to load system configuration: %v", err)
+	}
+
+	claims, err := collectPassthroughClaims(sys)
+	if err != nil {
+		return err
+	}
+
+	fsys := sysfs.Local()
+	groups, err := sysfs.IOMMUGroups(fsys)
+	if err != nil {
+		return err
+	}
+	if len(groups) == 0 {
+		fmt.Printf("No IOMMU groups are present. The IOMMU may be disabled.\n")
+		return nil
+	}
+
+	for _, group := range groups {
+		if cmd.Claimed && !groupClaimed(group, claims) {
+			continue
+		}
+
+		fmt.Printf("IOMMU Group %d:\n", group.ID)
+		for _, member := range group.Devices {
+			dev, err := sysfs.ReadPCIDevice(fsys, member)
+			if err != nil {
+				fmt.Printf("  %s: %v\n", member, err)
+				continue
+			}
+			line := fmt.Sprintf("  %s", dev)
+			if dev.Bridge() {
+				line += " bridge"
+			}
+			if claimed := claims[machina.DeviceAddress(member)]; len(claimed) > 0 {
+				line += fmt.Sprintf(" claimed by %s", joinClaims(claimed))
+			}
+			fmt.Println(line)
+		}
+	}
+
+	return nil
+}
+
+func groupClaimed(group sysfs.IOMMUGroup, claims passthroughClaimMap) bool {
+	for _, member := range group.Devices {
+		if len(claims[machina.DeviceAddress(member)]) > 0 {
+			return true
+		}
+	}
+	return false
+}
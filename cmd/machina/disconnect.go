@@ -10,10 +10,14 @@ import (
 // DisconnectCmd enables the connections to one or more virtual machines.
 type DisconnectCmd struct {
 	MachinesOrConnections []string `kong:"arg,help='Machines or individual connections to remove from the bridge. Use [machine] or [machine].[conn]..'"`
+	Link                  bool     `kong:"link,help='Also bring down the link seen by the guest through QMP.'"`
 }
 
 // Run executes the disconnect command.
 func (cmd DisconnectCmd) Run(ctx context.Context) error {
+	if cmd.Link {
+		return disconnectWithLink(ctx, cmd.MachinesOrConnections)
+	}
 	return disconnect(cmd.MachinesOrConnections)
 }
 
@@ -26,6 +30,51 @@ func disconnect(names []string) error {
 	var firstError error
 	for _, mconn := range mconns {
 		link := machina.MakeLinkName(mconn.Machine, mconn.Connection)
+		if network, ok := sys.Network[mconn.Network]; ok && network.EffectiveType().UserMode() {
+			fmt.Printf("%s: skipped: user-mode network\n", link)
+			continue
+		}
+		if err := disableConnection(mconn.Machine, mconn.Connection, sys); err != nil {
+			if firstError == nil {
+				firstError = err
+			}
+			fmt.Printf("%s: failed: %v\n", link, err)
+		} else {
+			fmt.Printf("%s: disabled\n", link)
+		}
+	}
+
+	return firstError
+}
+
+// disconnectWithLink brings down the guest-visible link state of each
+// connection's network adapter through QMP and then disables the host side
+// of the connection.
+//
+// The host side is disabled even if the guest link could not be updated,
+// so that a machine that isn't running can still be disconnected.
+func disconnectWithLink(ctx context.Context, names []string) error {
+	mconns, sys, err := LoadMachineConnections(names...)
+	if err != nil {
+		return err
+	}
+
+	var firstError error
+	for _, mconn := range mconns {
+		link := machina.MakeLinkName(mconn.Machine, mconn.Connection)
+		linkErr := setGuestLink(ctx, mconn.Machine, mconn.Connection, false)
+		if linkErr != nil {
+			if firstError == nil {
+				firstError = linkErr
+			}
+			fmt.Printf("%s: failed to bring down guest link: %v\n", link, linkErr)
+		}
+		if network, ok := sys.Network[mconn.Network]; ok && network.EffectiveType().UserMode() {
+			if linkErr == nil {
+				fmt.Printf("%s: disabled (guest link down)\n", link)
+			}
+			continue
+		}
 		if err := disableConnection(mconn.Machine, mconn.Connection, sys); err != nil {
 			if firstError == nil {
 				firstError = err
@@ -0,0 +1,95 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemugen"
+)
+
+// prepareFirmware initializes the firmware variables of a machine from the
+// template of its firmware when the firmware is selected from the firmware
+// descriptors installed on the host. Existing firmware variables are left
+// alone.
+func prepareFirmware(info machina.MachineInfo, definition machina.Definition, sys machina.System) error {
+	fw := definition.Attributes.Firmware
+	if !fw.IsDiscovered() {
+		return nil
+	}
+	if err := fw.Validate(); err != nil {
+		return err
+	}
+
+	storage, ok := sys.Storage[fw.Vars.Storage]
+	if !ok {
+		return fmt.Errorf("firmware variables rely on storage pool \"%s\", which is not defined", fw.Vars.Storage)
+	}
+	varsPath := string(storage.Volume(info, definition.Vars, fw.Vars.Name))
+
+	if _, err := os.Stat(varsPath); err == nil {
+		return nil
+	} else if !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("failed to inspect firmware variables \"%s\": %w", varsPath, err)
+	}
+
+	desc, err := qemugen.SelectFirmware(definition.Attributes)
+	if err != nil {
+		return err
+	}
+	templatePath := desc.Mapping.NVRAMTemplate.Filename
+	if templatePath == "" {
+		return fmt.Errorf("the selected firmware \"%s\" does not provide a template for its variables", desc.Description)
+	}
+
+	fmt.Printf("Initializing firmware variables %s from template %s.\n", varsPath, templatePath)
+
+	return copyFirmwareVars(templatePath, varsPath, int(definition.Privileges.FileSystem.Group.ID))
+}
+
+// copyFirmwareVars copies the firmware variables template at templatePath
+// to varsPath. The copy is written to a temporary file first, so that a
+// partially written file is never left at varsPath. If gid is non-zero the
+// copy is owned by that group.
+func copyFirmwareVars(templatePath, varsPath string, gid int) error {
+	template, err := os.Open(templatePath)
+	if err != nil {
+		return fmt.Errorf("failed to open firmware variables template \"%s\": %w", templatePath, err)
+	}
+	defer template.Close()
+
+	dir := filepath.Dir(varsPath)
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return fmt.Errorf("failed to create firmware variables directory \"%s\": %w", dir, err)
+	}
+
+	tmp, err := os.CreateTemp(dir, filepath.Base(varsPath)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create firmware variables in \"%s\": %w", dir, err)
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := io.Copy(tmp, template); err != nil {
+		tmp.Close()
+		return fmt.Errorf("failed to copy firmware variables template \"%s\": %w", templatePath, err)
+	}
+	if err := tmp.Chmod(0660); err != nil {
+		tmp.Close()
+		return err
+	}
+	if gid != 0 {
+		if err := tmp.Chown(-1, gid); err != nil {
+			tmp.Close()
+			return fmt.Errorf("failed to assign firmware variables to group %d: %w", gid, err)
+		}
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), varsPath)
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCopyFirmwareVars(t *testing.T) {
+	dir := t.TempDir()
+	templatePath := filepath.Join(dir, "OVMF_VARS.fd")
+	template := []byte("firmware variables template")
+	if err := os.WriteFile(templatePath, template, 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	varsPath := filepath.Join(dir, "machine", "firmware-vars.fd")
+	if err := copyFirmwareVars(templatePath, varsPath, 0); err != nil {
+		t.Fatal(err)
+	}
+
+	data, err := os.ReadFile(varsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(data, template) {
+		t.Errorf("unexpected firmware variables: %q", data)
+	}
+
+	fi, err := os.Stat(varsPath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if mode := fi.Mode().Perm(); mode != 0660 {
+		t.Errorf("unexpected file mode: %v", mode)
+	}
+
+	entries, err := os.ReadDir(filepath.Dir(varsPath))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 {
+		t.Errorf("expected only the firmware variables to remain, found %d files", len(entries))
+	}
+}
+
+func TestCopyFirmwareVarsMissingTemplate(t *testing.T) {
+	dir := t.TempDir()
+	varsPath := filepath.Join(dir, "firmware-vars.fd")
+	if err := copyFirmwareVars(filepath.Join(dir, "missing.fd"), varsPath, 0); err == nil {
+		t.Fatal("expected an error for a missing template")
+	}
+	if _, err := os.Stat(varsPath); !os.IsNotExist(err) {
+		t.Errorf("firmware variables should not have been created")
+	}
+}
@@ -12,7 +12,6 @@ import (
 
 	"github.com/gentlemanautomaton/machina"
 	"github.com/gentlemanautomaton/machina/qemu/qvm"
-	"github.com/gentlemanautomaton/machina/qemugen"
 	"github.com/gentlemanautomaton/machina/swtpm"
 	"github.com/gentlemanautomaton/machina/swtpmgen"
 	"github.com/gentlemanautomaton/machina/systemdgen"
@@ -35,6 +34,7 @@ func (cmd GenerateCmd) Run(ctx context.Context) error {
 	}
 
 	var machines []machina.MachineInfo
+	var placements []systemdgen.Placement
 	var vms []qvm.Definition
 	var tpms []swtpm.Settings
 	for _, name := range cmd.Machines {
@@ -42,7 +42,11 @@ func (cmd GenerateCmd) Run(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("failed to load machine configuration for \"%s\": %v", name, err)
 		}
-		vm, err := qemugen.Build(machine, sys)
+		definition, err := machina.Build(machine, sys)
+		if err != nil {
+			return fmt.Errorf("failed to build configuration for \"%s\": %v", name, err)
+		}
+		vm, err := buildQEMU(machine, sys, !cmd.Preview)
 		if err != nil {
 			return fmt.Errorf("failed to build QEMU configuration for \"%s\": %v", name, err)
 		}
@@ -50,7 +54,12 @@ func (cmd GenerateCmd) Run(ctx context.Context) error {
 		if err != nil {
 			return fmt.Errorf("failed to build software TPM configuration for \"%s\": %v", name, err)
 		}
+		pinning := definition.Attributes.Pinning
 		machines = append(machines, machine.Info())
+		placements = append(placements, systemdgen.Placement{
+			CPUAffinity: pinning.Emulator,
+			PinThreads:  pinning.VCPUs != "" || pinning.IOThreads != "",
+		})
 		vms = append(vms, vm)
 		tpms = append(tpms, tpm)
 	}
@@ -70,7 +79,7 @@ func (cmd GenerateCmd) Run(ctx context.Context) error {
 		}
 
 		var qemuBuf bytes.Buffer
-		if _, err := systemdconf.WriteSections(&qemuBuf, systemdgen.BuildQEMU(machines[i], qemuOptions, qemuBindToUnits...)...); err != nil {
+		if _, err := systemdconf.WriteSections(&qemuBuf, systemdgen.BuildQEMUWithPlacement(machines[i], qemuOptions, placements[i], qemuBindToUnits...)...); err != nil {
 			return fmt.Errorf("failed to prepare QEMU configuration for %s: %v", machines[i].Name, err)
 		}
 		qemuUnits = append(qemuUnits, qemuBuf.String())
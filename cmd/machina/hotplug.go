@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+	"github.com/gentlemanautomaton/machina/qemu/qhost/blockdev"
+	"github.com/gentlemanautomaton/machina/qmp"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+// findGeneratedDevice returns the device with the given identifier from a
+// device topology generated by qemugen.
+func findGeneratedDevice(topo *qdev.Topology, id qdev.ID) (device qdev.Device, found bool) {
+	qdev.Walk(topo.Devices(), func(depth int, d qdev.Device) {
+		if found {
+			return
+		}
+		for _, prop := range d.Properties() {
+			if prop.Name == "id" && prop.Value == string(id) {
+				device, found = d, true
+				return
+			}
+		}
+	})
+	return device, found
+}
+
+// deviceAddCommand returns a QMP device_add command that adds device to a
+// running machine with the same properties that would be provided to
+// QEMU's -device option. Properties with the given names are omitted, which
+// lets QEMU choose values such as a free port.
+func deviceAddCommand(device qdev.Device, omit ...string) qmpcmd.DeviceAdd {
+	cmd := qmpcmd.DeviceAdd{
+		Driver:     string(device.Driver()),
+		Properties: make(map[string]string),
+	}
+
+next:
+	for _, prop := range device.Properties() {
+		switch {
+		case prop.Name == "" || prop.Value == "":
+			// The driver is provided as a parameter without a name.
+			continue
+		case prop.Name == "id":
+			cmd.ID = prop.Value
+			continue
+		}
+		for _, name := range omit {
+			if prop.Name == name {
+				continue next
+			}
+		}
+		cmd.Properties[prop.Name] = prop.Value
+	}
+
+	return cmd
+}
+
+// isRootPort returns true if id identifies a PCI Express Root Port device
+// in topo.
+func isRootPort(topo *qdev.Topology, id string) bool {
+	for _, device := range topo.Devices() {
+		if root, ok := device.(*qdev.Root); ok && string(root.ID()) == id {
+			return true
+		}
+	}
+	return false
+}
+
+// findFreeRootPort returns the identifier of a PCI Express Root Port in a
+// running machine that doesn't have a device connected to it.
+func findFreeRootPort(ctx context.Context, client *qmp.Client) (string, error) {
+	var query qmpcmd.QueryPCI
+	if err := client.Execute(ctx, &query); err != nil {
+		return "", fmt.Errorf("failed to query the PCI bus: %w", err)
+	}
+	buses, err := query.Buses()
+	if err != nil {
+		return "", fmt.Errorf("failed to parse the PCI bus query response: %w", err)
+	}
+	if id, ok := freeRootPort(buses); ok {
+		return id, nil
+	}
+	return "", errors.New("the machine does not have a free PCI Express root port for hotplugged devices")
+}
+
+// freeRootPort returns the identifier of the first PCI Express Root Port on
+// buses that doesn't have a device connected to it.
+func freeRootPort(buses []qmpcmd.PCIBus) (id string, found bool) {
+	qmpcmd.WalkPCIDevices(buses, func(device qmpcmd.PCIDevice) {
+		if found || device.Bridge == nil || len(device.Bridge.Devices) > 0 {
+			return
+		}
+		if strings.HasPrefix(device.QdevID, "pcie.") {
+			id, found = device.QdevID, true
+		}
+	})
+	return id, found
+}
+
+// placeDeviceAddCommand updates the bus of cmd so that devices which would
+// normally be connected to their own PCI Express Root Port are connected
+// to a free root port in the running machine instead.
+func placeDeviceAddCommand(ctx context.Context, client *qmp.Client, topo *qdev.Topology, cmd *qmpcmd.DeviceAdd) error {
+	if !isRootPort(topo, cmd.Properties["bus"]) {
+		return nil
+	}
+	bus, err := findFreeRootPort(ctx, client)
+	if err != nil {
+		return err
+	}
+	cmd.Properties["bus"] = bus
+	return nil
+}
+
+// deviceDeleteTimeout is the amount of time to wait for a guest to release
+// a device after it has been asked to remove it.
+const deviceDeleteTimeout = 30 * time.Second
+
+// deleteDevice asks a running machine to remove the device with the given
+// identifier and waits for QEMU to report that it has been removed.
+func deleteDevice(ctx context.Context, client *qmp.Client, id string) error {
+	listener := client.Listen()
+	defer listener.Close()
+
+	if err := client.Execute(ctx, qmpcmd.DeviceDel{ID: id}); err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, deviceDeleteTimeout)
+	defer cancel()
+
+	for {
+		event, err := listener.Receive(ctx)
+		if err != nil {
+			if errors.Is(err, context.DeadlineExceeded) {
+				return fmt.Errorf("the guest did not release device \"%s\" within %s", id, deviceDeleteTimeout)
+			}
+			return err
+		}
+		if event.Event != "DEVICE_DELETED" {
+			continue
+		}
+		var data struct {
+			Device string `json:"device"`
+		}
+		if err := json.Unmarshal(event.Data.Bytes(), &data); err != nil {
+			continue
+		}
+		if data.Device == id {
+			return nil
+		}
+	}
+}
+
+// blockdevLineage returns the block device node with the given name in
+// graph followed by each of the nodes that it is layered upon.
+func blockdevLineage(graph blockdev.NodeGraph, name blockdev.NodeName) ([]blockdev.Node, error) {
+	var lineage []blockdev.Node
+	for name != "" {
+		node := graph.Find(name)
+		if node == nil {
+			return nil, fmt.Errorf("the block device node \"%s\" could not be found", name)
+		}
+		lineage = append(lineage, node)
+
+		name = ""
+		for _, prop := range node.Properties() {
+			if prop.Name == "file" {
+				name = blockdev.NodeName(prop.Value)
+			}
+		}
+	}
+	return lineage, nil
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"maps"
+	"slices"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+	"github.com/gentlemanautomaton/machina/qemu/qhost/blockdev"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+func TestDeviceAddCommand(t *testing.T) {
+	var topo qdev.Topology
+	root, err := topo.AddRoot()
+	if err != nil {
+		t.Fatal(err)
+	}
+	usb, err := root.AddUSB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	selector := qdev.USBHostSelector{VendorID: 0x046d, ProductID: 0xc52b}
+	if _, err := usb.AddHost(selector, qdev.USBHostID("usbhost.dongle.0")); err != nil {
+		t.Fatal(err)
+	}
+
+	device, found := findGeneratedDevice(&topo, "usbhost.dongle.0")
+	if !found {
+		t.Fatal("the generated device was not found")
+	}
+
+	cmd := deviceAddCommand(device, "port")
+	if cmd.Driver != "usb-host" {
+		t.Errorf("unexpected driver: %s", cmd.Driver)
+	}
+	if cmd.ID != "usbhost.dongle.0" {
+		t.Errorf("unexpected ID: %s", cmd.ID)
+	}
+	want := map[string]string{
+		"bus":       "usb.0",
+		"vendorid":  "0x046d",
+		"productid": "0xc52b",
+	}
+	if !maps.Equal(cmd.Properties, want) {
+		t.Errorf("unexpected properties: %v (want %v)", cmd.Properties, want)
+	}
+
+	if _, found := findGeneratedDevice(&topo, "usbhost.missing.0"); found {
+		t.Error("found a device that does not exist")
+	}
+}
+
+func TestFreeRootPort(t *testing.T) {
+	query := qmpcmd.QueryPCI{Response: `[{"bus":0,"devices":[
+		{"bus":0,"slot":0,"function":0,"qdev_id":""},
+		{"bus":0,"slot":1,"function":0,"qdev_id":"pcie.1.0","pci_bridge":{"devices":[
+			{"bus":1,"slot":0,"function":0,"qdev_id":"nic.0"}
+		]}},
+		{"bus":0,"slot":1,"function":1,"qdev_id":"pcie.1.1","pci_bridge":{"devices":[]}},
+		{"bus":0,"slot":1,"function":2,"qdev_id":"pcie.1.2","pci_bridge":{"devices":[]}}
+	]}]`}
+
+	buses, err := query.Buses()
+	if err != nil {
+		t.Fatal(err)
+	}
+	id, found := freeRootPort(buses)
+	if !found {
+		t.Fatal("a free root port was not found")
+	}
+	if id != "pcie.1.1" {
+		t.Errorf("unexpected root port: %s", id)
+	}
+
+	buses[0].Devices = buses[0].Devices[:2]
+	if id, found := freeRootPort(buses); found {
+		t.Errorf("found a free root port that is occupied: %s", id)
+	}
+}
+
+func TestBlockdevLineage(t *testing.T) {
+	var graph blockdev.Graph
+	file, err := blockdev.File{Name: "vm-os-file", Path: "/var/lib/os.raw"}.Connect(&graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := (blockdev.Raw{Name: "vm-os"}).Connect(file); err != nil {
+		t.Fatal(err)
+	}
+
+	lineage, err := blockdevLineage(&graph, "vm-os")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []blockdev.NodeName
+	for _, node := range lineage {
+		names = append(names, node.Name())
+	}
+	if want := []blockdev.NodeName{"vm-os", "vm-os-file"}; !slices.Equal(names, want) {
+		t.Errorf("unexpected lineage: %v (want %v)", names, want)
+	}
+
+	if _, err := blockdevLineage(&graph, "vm-missing"); err == nil {
+		t.Error("expected an error for a missing node")
+	}
+}
@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+// checkHugePages returns an error if the host doesn't have enough free huge
+// pages to supply the memory of a machine.
+//
+// NUMA nodes that are bound to host nodes must be supplied by the free huge
+// pages of those host nodes.
+func checkHugePages(fsys fs.FS, attrs machina.Attributes) error {
+	mem := attrs.Memory
+	if mem.HugePages == "" {
+		return nil
+	}
+	size := mem.HugePages
+	sizeKB := size.MB() * 1024
+
+	// Determine the number of huge pages needed from each set of host
+	// nodes, and from the system as a whole.
+	total := size.Pages(mem.RAM)
+	bound := make(map[string]int)
+	if len(attrs.NUMA.Nodes) > 0 {
+		total = 0
+		for _, node := range attrs.NUMA.Nodes {
+			pages := size.Pages(node.Memory)
+			total += pages
+			if node.HostNodes != "" && node.EffectivePolicy() == machina.BindMemoryPolicy {
+				hostNodes, err := sysfs.ParseCPUList(node.HostNodes)
+				if err != nil {
+					return err
+				}
+				bound[hostNodes.String()] += pages
+			}
+		}
+	}
+
+	free, err := sysfs.FreeHugePages(fsys, -1, sizeKB)
+	if err != nil {
+		return fmt.Errorf("failed to determine the number of free %s huge pages: %w", size, err)
+	}
+	if free < total {
+		return fmt.Errorf("the machine needs %d free %s huge pages but only %d are available", total, size, free)
+	}
+
+	for nodes, needed := range bound {
+		hostNodes, _ := sysfs.ParseCPUList(nodes)
+		var free int
+		for _, node := range hostNodes {
+			count, err := sysfs.FreeHugePages(fsys, node, sizeKB)
+			if err != nil {
+				return fmt.Errorf("failed to determine the number of free %s huge pages on host node %d: %w", size, node, err)
+			}
+			free += count
+		}
+		if free < needed {
+			return fmt.Errorf("the machine needs %d free %s huge pages on host nodes %s but only %d are available", needed, size, nodes, free)
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,48 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/gentlemanautomaton/machina"
+)
+
+func TestCheckHugePages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kernel/mm/hugepages/hugepages-2048kB/free_hugepages":                 {Data: []byte("3072\n")},
+		"devices/system/node/node0/hugepages/hugepages-2048kB/free_hugepages": {Data: []byte("2048\n")},
+		"devices/system/node/node1/hugepages/hugepages-2048kB/free_hugepages": {Data: []byte("1024\n")},
+	}
+
+	tests := []struct {
+		Name  string
+		Attrs machina.Attributes
+		OK    bool
+	}{
+		{"no huge pages", machina.Attributes{Memory: machina.Memory{RAM: 65536}}, true},
+		{"enough", machina.Attributes{Memory: machina.Memory{RAM: 6144, HugePages: machina.HugePageSize2M}}, true},
+		{"too many", machina.Attributes{Memory: machina.Memory{RAM: 8192, HugePages: machina.HugePageSize2M}}, false},
+		{"missing size", machina.Attributes{Memory: machina.Memory{RAM: 1024, HugePages: machina.HugePageSize1G}}, false},
+		{"bound to node", machina.Attributes{
+			Memory: machina.Memory{HugePages: machina.HugePageSize2M},
+			NUMA: machina.NUMA{Nodes: []machina.NUMANode{
+				{CPUs: "0-1", Memory: 4096, HostNodes: "0"},
+				{CPUs: "2-3", Memory: 2048, HostNodes: "1"},
+			}},
+		}, true},
+		{"node exhausted", machina.Attributes{
+			Memory: machina.Memory{HugePages: machina.HugePageSize2M},
+			NUMA: machina.NUMA{Nodes: []machina.NUMANode{
+				{CPUs: "0-1", Memory: 4096, HostNodes: "1"},
+			}},
+		}, false},
+	}
+	for _, test := range tests {
+		err := checkHugePages(fsys, test.Attrs)
+		if test.OK && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.Name, err)
+		} else if !test.OK && err == nil {
+			t.Errorf("%s: expected an error", test.Name)
+		}
+	}
+}
@@ -43,6 +43,14 @@ func main() {
 		Teardown   TeardownCmd   `kong:"cmd,help='Removes host resources prepared for a virtual machine.'"`
 		Connect    ConnectCmd    `kong:"cmd,help='Connects a whole virtual machine or individual connections to the network.'"`
 		Disconnect DisconnectCmd `kong:"cmd,help='Disconnects a whole virtual machine or individual connections from the network.'"`
+		Pin        PinCmd        `kong:"cmd,help='Pins the vCPU and I/O threads of running virtual machines to host CPUs.'"`
+		Attach     AttachCmd     `kong:"cmd,help='Adds volumes or connections to running virtual machines.'"`
+		Detach     DetachCmd     `kong:"cmd,help='Removes volumes or connections from running virtual machines.'"`
+		Devices    DevicesCmd    `kong:"cmd,help='Reports on host devices used by virtual machines.'"`
+		Net        NetCmd        `kong:"cmd,help='Reports on virtual machine network connections.'"`
+		CPU        CPUCmd        `kong:"cmd,name='cpu',help='Manages the virtual CPUs of running virtual machines.'"`
+		Memory     MemoryCmd     `kong:"cmd,help='Manages the memory of running virtual machines.'"`
+		USB        USBCmd        `kong:"cmd,name='usb',help='Manages USB host devices passed through to running virtual machines.'"`
 		Query      QueryCmd      `kong:"cmd,help='Queries virtual machines via the QMP protocol.'"`
 		GenID      GenIDCmd      `kong:"cmd,name='gen-id',help='Generate a random machine identifier.'"`
 		GenMAC     GenMACCmd     `kong:"cmd,name='gen-mac',help='Generate a random MAC hardware address.'"`
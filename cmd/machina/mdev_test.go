@@ -0,0 +1,250 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs/mdevtest"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+func newTestMediatedDevices() (*mdevtest.Bus, machina.MediatedDeviceList) {
+	bus := mdevtest.NewBus()
+	bus.AddPhysicalDevice("0000:3b:00.0", mdevtest.Type{
+		ID:           "nvidia-256",
+		Name:         "GRID T4-8Q",
+		MaxInstances: 2,
+		Placements:   []machina.MediatedDevicePlacementID{0, 8},
+	})
+	bus.AddPhysicalDevice("0000:af:00.0", mdevtest.Type{
+		ID:           "nvidia-256",
+		Name:         "GRID T4-8Q",
+		MaxInstances: 2,
+		Placements:   []machina.MediatedDevicePlacementID{0, 8},
+	})
+
+	wanted := machina.MediatedDeviceType{
+		Name:       "GRID T4-8Q",
+		Placements: machina.MediatedDevicePlacementList{8, 0},
+	}
+	mdevs := machina.MediatedDeviceMap{
+		"gpu0": {Address: "0000:3b:00.0", Classes: machina.MediatedDeviceClassMap{"vgpu": wanted}},
+		"gpu1": {Address: "0000:af:00.0", Classes: machina.MediatedDeviceClassMap{"vgpu": wanted}},
+	}
+
+	return bus, mdevs.WithClass("vgpu")
+}
+
+func testDevice(t *testing.T, name machina.DeviceName, id string) machina.Device {
+	t.Helper()
+	device := machina.Device{Name: name, Class: "vgpu"}
+	if err := device.ID.UnmarshalText([]byte(id)); err != nil {
+		t.Fatal(err)
+	}
+	return device
+}
+
+func TestPrepareAndTeardownMediatedDevices(t *testing.T) {
+	ctx := context.Background()
+	bus, mdevs := newTestMediatedDevices()
+	system := mdevfs.New(bus)
+
+	devices := []machina.Device{
+		testDevice(t, "a", "79db70f1-92a5-4879-95f7-6325b66c1ff9"),
+		testDevice(t, "b", "0c1b4ba4-2b1e-4e1a-9d3b-5e0a7f3f5d18"),
+		testDevice(t, "c", "d2f0b7b4-8e39-4a9c-a7f6-3a9c1de2d8a1"),
+	}
+
+	want := []struct {
+		Parent    machina.DeviceAddress
+		Placement machina.MediatedDevicePlacementID
+	}{
+		{"0000:3b:00.0", 8},
+		{"0000:3b:00.0", 0},
+		{"0000:af:00.0", 8},
+	}
+
+	// Prepare each device twice to make sure that preparation is
+	// idempotent.
+	for round := 0; round < 2; round++ {
+		for i, device := range devices {
+			if err := prepareMediatedDevice(ctx, system, device, mdevs, nil); err != nil {
+				t.Fatalf("failed to prepare device %s: %v", device.Name, err)
+			}
+			dev, ok := bus.Device(device.ID)
+			if !ok {
+				t.Fatalf("device %s was not created", device.Name)
+			}
+			if dev.Parent != want[i].Parent {
+				t.Errorf("device %s: unexpected parent %s (want %s)", device.Name, dev.Parent, want[i].Parent)
+			}
+			if dev.Placement != want[i].Placement {
+				t.Errorf("device %s: unexpected placement %d (want %d)", device.Name, dev.Placement, want[i].Placement)
+			}
+		}
+		if got := len(bus.Devices()); got != len(devices) {
+			t.Fatalf("unexpected number of mediated devices after round %d: %d (want %d)", round, got, len(devices))
+		}
+	}
+
+	// Tear the devices down twice to make sure that teardown is
+	// idempotent.
+	for round := 0; round < 2; round++ {
+		for _, device := range devices {
+			if err := teardownMediatedDevice(system, device); err != nil {
+				t.Fatalf("failed to teardown device %s: %v", device.Name, err)
+			}
+			if _, ok := bus.Device(device.ID); ok {
+				t.Errorf("device %s was not removed", device.Name)
+			}
+		}
+	}
+}
+
+func TestPrepareMediatedDeviceReusesPlacement(t *testing.T) {
+	ctx := context.Background()
+	bus, mdevs := newTestMediatedDevices()
+	system := mdevfs.New(bus)
+
+	a := testDevice(t, "a", "79db70f1-92a5-4879-95f7-6325b66c1ff9")
+	b := testDevice(t, "b", "0c1b4ba4-2b1e-4e1a-9d3b-5e0a7f3f5d18")
+
+	if err := prepareMediatedDevice(ctx, system, a, mdevs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if err := teardownMediatedDevice(system, a); err != nil {
+		t.Fatal(err)
+	}
+
+	// With the first device gone, its preferred placement should be
+	// available again.
+	if err := prepareMediatedDevice(ctx, system, b, mdevs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dev, _ := bus.Device(b.ID); dev.Placement != 8 {
+		t.Errorf("unexpected placement %d (want 8)", dev.Placement)
+	}
+}
+
+func TestPrepareMediatedDevicePolicies(t *testing.T) {
+	const (
+		gpu0 = machina.DeviceAddress("0000:3b:00.0")
+		gpu1 = machina.DeviceAddress("0000:af:00.0")
+	)
+
+	tests := []struct {
+		Name     string
+		Policy   machina.MediatedDevicePolicy
+		Priority int // of gpu1
+		CPUs     sysfs.CPUList
+		Want     []machina.DeviceAddress
+	}{
+		{"default", "", 0, nil, []machina.DeviceAddress{gpu0, gpu0, gpu1}},
+		{"prefer-address", machina.PreferAddressMediatedDevicePolicy, 1, nil, []machina.DeviceAddress{gpu1, gpu1, gpu0}},
+		{"spread", machina.SpreadMediatedDevicePolicy, 0, nil, []machina.DeviceAddress{gpu0, gpu1, gpu0}},
+		{"pack", machina.PackMediatedDevicePolicy, 0, nil, []machina.DeviceAddress{gpu0, gpu0, gpu1}},
+		{"numa-local", machina.NUMALocalMediatedDevicePolicy, 0, sysfs.CPUList{8, 9}, []machina.DeviceAddress{gpu1, gpu1, gpu0}},
+		{"numa-unknown", machina.NUMALocalMediatedDevicePolicy, 0, nil, []machina.DeviceAddress{gpu0, gpu1, gpu0}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			ctx := context.Background()
+			bus, _ := newTestMediatedDevices()
+			bus.SetLocalCPUs(gpu0, "0-7")
+			bus.SetLocalCPUs(gpu1, "8-15")
+			system := mdevfs.New(bus)
+
+			wanted := machina.MediatedDeviceType{Name: "GRID T4-8Q", Policy: test.Policy}
+			mdevs := machina.MediatedDeviceMap{
+				"gpu0": {Address: gpu0, Classes: machina.MediatedDeviceClassMap{"vgpu": wanted}},
+				"gpu1": {Address: gpu1, Priority: test.Priority, Classes: machina.MediatedDeviceClassMap{"vgpu": wanted}},
+			}.WithClass("vgpu")
+
+			devices := []machina.Device{
+				testDevice(t, "a", "79db70f1-92a5-4879-95f7-6325b66c1ff9"),
+				testDevice(t, "b", "0c1b4ba4-2b1e-4e1a-9d3b-5e0a7f3f5d18"),
+				testDevice(t, "c", "d2f0b7b4-8e39-4a9c-a7f6-3a9c1de2d8a1"),
+			}
+			for i, device := range devices {
+				if err := prepareMediatedDevice(ctx, system, device, mdevs, test.CPUs); err != nil {
+					t.Fatalf("failed to prepare device %s: %v", device.Name, err)
+				}
+				if dev, _ := bus.Device(device.ID); dev.Parent != test.Want[i] {
+					t.Errorf("device %s: unexpected parent %s (want %s)", device.Name, dev.Parent, test.Want[i])
+				}
+			}
+		})
+	}
+}
+
+func TestPrepareMediatedDeviceNoCapacity(t *testing.T) {
+	ctx := context.Background()
+	bus, mdevs := newTestMediatedDevices()
+	system := mdevfs.New(bus)
+
+	devices := []machina.Device{
+		testDevice(t, "a", "79db70f1-92a5-4879-95f7-6325b66c1ff9"),
+		testDevice(t, "b", "0c1b4ba4-2b1e-4e1a-9d3b-5e0a7f3f5d18"),
+		testDevice(t, "c", "d2f0b7b4-8e39-4a9c-a7f6-3a9c1de2d8a1"),
+		testDevice(t, "d", "5f7c2f3e-0b8a-4d61-9f0e-2c4b7a9d1e36"),
+	}
+	for _, device := range devices {
+		if err := prepareMediatedDevice(ctx, system, device, mdevs, nil); err != nil {
+			t.Fatalf("failed to prepare device %s: %v", device.Name, err)
+		}
+	}
+
+	e := testDevice(t, "e", "a3c9e1d2-6f4b-4e8a-b1c7-9d2e5f0a8b43")
+	err := prepareMediatedDevice(ctx, system, e, mdevs, nil)
+	if err == nil {
+		t.Fatal("expected an error when no physical device has capacity")
+	}
+	for _, address := range []string{"0000:3b:00.0", "0000:af:00.0"} {
+		if !strings.Contains(err.Error(), address) {
+			t.Errorf("the error does not mention physical device %s: %v", address, err)
+		}
+	}
+	if _, ok := bus.Device(e.ID); ok {
+		t.Error("a mediated device was created without capacity")
+	}
+}
+
+func TestPrepareMediatedDeviceFallback(t *testing.T) {
+	ctx := context.Background()
+	bus, mdevs := newTestMediatedDevices()
+	system := mdevfs.New(bus)
+
+	// The first physical device refuses to create mediated devices even
+	// though it reports capacity for them.
+	bus.RejectCreate("0000:3b:00.0", errors.New("the vendor driver rejected the device"))
+
+	a := testDevice(t, "a", "79db70f1-92a5-4879-95f7-6325b66c1ff9")
+	if err := prepareMediatedDevice(ctx, system, a, mdevs, nil); err != nil {
+		t.Fatal(err)
+	}
+	if dev, _ := bus.Device(a.ID); dev.Parent != "0000:af:00.0" {
+		t.Errorf("unexpected parent %s (want 0000:af:00.0)", dev.Parent)
+	}
+
+	// When every physical device refuses, each of them is reported.
+	bus.RejectCreate("0000:af:00.0", errors.New("the vendor driver rejected the device"))
+
+	b := testDevice(t, "b", "0c1b4ba4-2b1e-4e1a-9d3b-5e0a7f3f5d18")
+	err := prepareMediatedDevice(ctx, system, b, mdevs, nil)
+	if err == nil {
+		t.Fatal("expected an error when every physical device rejects creation")
+	}
+	for _, address := range []string{"0000:3b:00.0", "0000:af:00.0"} {
+		if !strings.Contains(err.Error(), address) {
+			t.Errorf("the error does not mention physical device %s: %v", address, err)
+		}
+	}
+	if _, ok := bus.Device(b.ID); ok {
+		t.Error("a mediated device was created despite being rejected")
+	}
+}
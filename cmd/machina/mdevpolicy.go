@@ -0,0 +1,180 @@
+package main
+
+import (
+	"bufio"
+	"cmp"
+	"errors"
+	"fmt"
+	"os"
+	"slices"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+	"github.com/gentlemanautomaton/machina/mdevdriver"
+)
+
+// mediatedDeviceCandidate is a physical device that has capacity for
+// another instance of the mediated device type needed by a device.
+type mediatedDeviceCandidate struct {
+	Config    machina.MediatedDevice
+	Driver    mdevdriver.Driver
+	Parent    mdevfs.PhysicalDevice
+	Types     mdevfs.TypeList
+	Type      mdevfs.Type
+	Wanted    machina.MediatedDeviceType
+	Available int
+	Local     bool
+}
+
+// collectMediatedDeviceCandidates returns the physical devices in mdevs
+// that have capacity for another mediated device of the type that supplies
+// the device's class, in the order that they should be tried according to
+// policy.
+//
+// The cpus are the host CPUs that the virtual machine runs on. They are
+// only used by the NUMA-local policy.
+//
+// If none of the physical devices have capacity, an error is returned that
+// explains why each of them was rejected.
+func collectMediatedDeviceCandidates(bus mdevfs.System, device machina.Device, mdevs machina.MediatedDeviceList, policy machina.MediatedDevicePolicy, cpus sysfs.CPUList) ([]mediatedDeviceCandidate, error) {
+	var (
+		candidates []mediatedDeviceCandidate
+		rejections []string
+	)
+
+	for _, mdev := range mdevs {
+		// Translate the device class to a mediated device type supplied by
+		// machina's system configuration.
+		wantedType, found := mdev.Classes[device.Class]
+		if !found || wantedType.Name == "" {
+			continue
+		}
+
+		// Select the vendor driver for the physical device.
+		driver, err := mdevdriver.For(mdev)
+		if err != nil {
+			return nil, err
+		}
+
+		// Prepare access to the physical device through sysfs.
+		parent := bus.PhysicalDevice(mdev.Address)
+		if exists, err := parent.Exists(); err != nil {
+			return nil, fmt.Errorf("failed to open mediated device file system for %s: %v", mdev.Address, err)
+		} else if !exists {
+			rejections = append(rejections, fmt.Sprintf("%s is not present", mdev.Address))
+			continue
+		}
+
+		// Enumerate the supported types via sysfs.
+		supportedTypes, err := parent.Types()
+		if err != nil {
+			return nil, fmt.Errorf("failed to enumerate mediated device types supported by physical device %s: %v", mdev.Address, err)
+		}
+
+		// Search for an enumerated type with the requested name.
+		supportedType, found := supportedTypes.FindName(wantedType.Name)
+		if !found {
+			rejections = append(rejections, fmt.Sprintf("%s does not offer type \"%s\"", mdev.Address, wantedType.Name))
+			continue
+		}
+
+		// Skip physical devices that don't have capacity for another
+		// instance of the type.
+		available, err := supportedType.AvailableInstances()
+		if err != nil {
+			return nil, fmt.Errorf("failed to determine available instances of mediated device type \"%s\" for physical device %s: %v", wantedType.Name, mdev.Address, err)
+		} else if available < 1 {
+			rejections = append(rejections, fmt.Sprintf("%s has no available instances of type \"%s\"", mdev.Address, wantedType.Name))
+			continue
+		}
+
+		candidate := mediatedDeviceCandidate{
+			Config:    mdev,
+			Driver:    driver,
+			Parent:    parent,
+			Types:     supportedTypes,
+			Type:      supportedType,
+			Wanted:    wantedType,
+			Available: available,
+		}
+
+		// Determine whether the physical device is local to the NUMA node
+		// of the virtual machine's CPUs.
+		if policy == machina.NUMALocalMediatedDevicePolicy && len(cpus) > 0 {
+			local, err := parent.LocalCPUs()
+			if err != nil {
+				return nil, fmt.Errorf("failed to determine the local CPUs of physical device %s: %v", mdev.Address, err)
+			}
+			candidate.Local = local.Intersects(cpus)
+		}
+
+		candidates = append(candidates, candidate)
+	}
+
+	if len(candidates) == 0 {
+		if len(rejections) == 0 {
+			return nil, fmt.Errorf("no physical device supplies a mediated device type for device class %s", device.Class)
+		}
+		return nil, fmt.Errorf("no physical device has capacity for device %s of class %s: %s", device.Name, device.Class, strings.Join(rejections, "; "))
+	}
+
+	orderMediatedDeviceCandidates(policy, candidates)
+
+	return candidates, nil
+}
+
+// orderMediatedDeviceCandidates sorts candidates in the order that they
+// should be tried according to policy. The candidates are expected to be
+// in order of address already.
+func orderMediatedDeviceCandidates(policy machina.MediatedDevicePolicy, candidates []mediatedDeviceCandidate) {
+	spread := func(a, b mediatedDeviceCandidate) int {
+		return cmp.Compare(b.Available, a.Available)
+	}
+
+	switch policy {
+	case machina.SpreadMediatedDevicePolicy:
+		slices.SortStableFunc(candidates, spread)
+	case machina.PackMediatedDevicePolicy:
+		slices.SortStableFunc(candidates, func(a, b mediatedDeviceCandidate) int {
+			return cmp.Compare(a.Available, b.Available)
+		})
+	case machina.NUMALocalMediatedDevicePolicy:
+		slices.SortStableFunc(candidates, func(a, b mediatedDeviceCandidate) int {
+			switch {
+			case a.Local && !b.Local:
+				return -1
+			case b.Local && !a.Local:
+				return 1
+			}
+			return spread(a, b)
+		})
+	default:
+		slices.SortStableFunc(candidates, func(a, b mediatedDeviceCandidate) int {
+			return cmp.Compare(b.Config.Priority, a.Config.Priority)
+		})
+	}
+}
+
+// allowedCPUs returns the CPUs that the current process is allowed to run
+// on. Machina prepares a virtual machine's devices from within its systemd
+// unit, so these are the CPUs that the virtual machine will run on.
+func allowedCPUs() (sysfs.CPUList, error) {
+	f, err := os.Open("/proc/self/status")
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if value, found := strings.CutPrefix(scanner.Text(), "Cpus_allowed_list:"); found {
+			return sysfs.ParseCPUList(value)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return nil, errors.New("the CPU affinity of the process could not be determined")
+}
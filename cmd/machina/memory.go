@@ -0,0 +1,181 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
+	"github.com/gentlemanautomaton/machina/qemugen"
+	"github.com/gentlemanautomaton/machina/qmp"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+// mebibyte is the number of bytes in a mebibyte.
+const mebibyte = 1 << 20
+
+// MemoryCmd manages the memory of running virtual machines.
+type MemoryCmd struct {
+	Set MemorySetCmd `kong:"cmd,help='Resizes the memory of a running virtual machine.'"`
+}
+
+// MemorySetCmd resizes the memory of a running virtual machine.
+type MemorySetCmd struct {
+	Machine string `kong:"arg,predictor=machines,help='Virtual machine to resize.'"`
+	Size    string `kong:"arg,help='Amount of memory, such as 8192, 8192M or 8G. Mebibytes are assumed without a suffix.'"`
+}
+
+// Run executes the memory set command.
+func (cmd MemorySetCmd) Run(ctx context.Context) error {
+	size, err := parseMemorySize(cmd.Size)
+	if err != nil {
+		return err
+	}
+
+	vms, _, err := LoadAndComposeMachines(machina.MachineName(cmd.Machine))
+	if err != nil {
+		return err
+	}
+	vm := vms[0]
+
+	client, err := connectToMachineQMP(vm)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	var summary qmpcmd.QueryMemorySizeSummary
+	if err := client.Execute(ctx, &summary); err != nil {
+		return fmt.Errorf("failed to query the memory of the machine: %w", err)
+	}
+	current := int(summary.Response.Total() / mebibyte)
+
+	attrs := vm.Definition.Attributes
+	if size > current {
+		if err := plugMemory(ctx, client, attrs.Memory, current, size-current); err != nil {
+			return err
+		}
+		fmt.Printf("%s: added %s of memory\n", vm.Name, qguest.MB(size-current).Size())
+	}
+
+	if !attrs.Balloon.Enabled {
+		if size < current {
+			return fmt.Errorf("the machine has %s of memory and can't be reduced without a memory balloon", qguest.MB(current).Size())
+		}
+		return nil
+	}
+
+	if err := client.Execute(ctx, qmpcmd.Balloon{Value: int64(size) * mebibyte}); err != nil {
+		return fmt.Errorf("failed to set the memory balloon target: %w", err)
+	}
+
+	var balloon qmpcmd.QueryBalloon
+	if err := client.Execute(ctx, &balloon); err != nil {
+		return fmt.Errorf("failed to query the memory balloon: %w", err)
+	}
+	fmt.Printf("%s: set the memory balloon target to %s (currently %s)\n", vm.Name, qguest.MB(size).Size(), qguest.MB(balloon.Response.Actual/mebibyte).Size())
+
+	return nil
+}
+
+// plugMemory hotplugs a DIMM with the given number of mebibytes into
+// a running virtual machine that currently has the given number of
+// mebibytes of memory. The DIMM's memory backend matches the memory
+// configuration of the machine.
+func plugMemory(ctx context.Context, client *qmp.Client, mem machina.Memory, current, size int) error {
+	var devices qmpcmd.QueryMemoryDevices
+	if err := client.Execute(ctx, &devices); err != nil {
+		return fmt.Errorf("failed to query the memory devices of the machine: %w", err)
+	}
+	if err := checkMemoryPlug(mem, current, len(devices.Response), size); err != nil {
+		return err
+	}
+
+	id := nextDIMMID(devices.Response)
+	memdev := "mem." + id
+
+	var res qhost.Resources
+	backend, err := qemugen.AddMemoryBackend(&res, mem, qhost.MemorySettings{Size: size})
+	if err != nil {
+		return err
+	}
+	args, err := qhost.MemoryBackendArguments(backend)
+	if err != nil {
+		return err
+	}
+	args["id"] = memdev
+
+	if err := client.Execute(ctx, qmpcmd.ObjectAdd{Arguments: args}); err != nil {
+		return fmt.Errorf("failed to add memory backend %s: %w", memdev, err)
+	}
+
+	dimm := qmpcmd.DeviceAdd{
+		Driver:     "pc-dimm",
+		ID:         id,
+		Properties: map[string]string{"memdev": memdev},
+	}
+	if err := client.Execute(ctx, dimm); err != nil {
+		client.Execute(ctx, qmpcmd.ObjectDel{ID: memdev})
+		return fmt.Errorf("failed to add memory device %s: %w", id, err)
+	}
+
+	return nil
+}
+
+// checkMemoryPlug returns an error if a DIMM with the given number of
+// mebibytes can't be added to a machine that currently has the given
+// number of mebibytes of memory and DIMMs in use.
+//
+// The current amount of memory includes DIMMs that have already been
+// plugged, and is used instead of mem.RAM because the memory of machines
+// with NUMA nodes is defined by the nodes.
+func checkMemoryPlug(mem machina.Memory, current, dimms, size int) error {
+	if mem.MaxRAM <= 0 {
+		return fmt.Errorf("memory hotplug is not enabled for the machine")
+	}
+	if err := mem.HugePages.CheckSize(size); err != nil {
+		return err
+	}
+	if dimms >= mem.Slots {
+		return fmt.Errorf("all %d memory slots are in use", mem.Slots)
+	}
+	if total := current + size; total > mem.MaxRAM {
+		return fmt.Errorf("%s of memory exceeds the maximum of %s", qguest.MB(total).Size(), qguest.MB(mem.MaxRAM).Size())
+	}
+	return nil
+}
+
+// nextDIMMID returns the first DIMM device identifier that is not already
+// used by one of the given memory devices.
+func nextDIMMID(devices []qmpcmd.MemoryDeviceInfo) string {
+	used := make(map[string]bool, len(devices))
+	for _, device := range devices {
+		used[device.Data.ID] = true
+	}
+	for i := 0; ; i++ {
+		if id := "dimm." + strconv.Itoa(i); !used[id] {
+			return id
+		}
+	}
+}
+
+// parseMemorySize parses an amount of memory and returns it in mebibytes.
+// The amount may have an M or G suffix. Mebibytes are assumed if a suffix
+// is not present.
+func parseMemorySize(value string) (int, error) {
+	number, multiplier := strings.ToUpper(value), 1
+	switch {
+	case strings.HasSuffix(number, "G"):
+		number, multiplier = strings.TrimSuffix(number, "G"), 1024
+	case strings.HasSuffix(number, "M"):
+		number = strings.TrimSuffix(number, "M")
+	}
+	size, err := strconv.Atoi(number)
+	if err != nil || size <= 0 {
+		return 0, fmt.Errorf("invalid memory size \"%s\"", value)
+	}
+	return size * multiplier, nil
+}
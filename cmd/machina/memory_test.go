@@ -0,0 +1,80 @@
+package main
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+func TestParseMemorySize(t *testing.T) {
+	fixtures := []struct {
+		Value    string
+		Expected int
+	}{
+		{"8192", 8192},
+		{"8192M", 8192},
+		{"8G", 8192},
+		{"2g", 2048},
+		{"", 0},
+		{"0", 0},
+		{"8T", 0},
+	}
+	for _, f := range fixtures {
+		got, err := parseMemorySize(f.Value)
+		if f.Expected == 0 {
+			if err == nil {
+				t.Errorf("%q: expected an error", f.Value)
+			}
+			continue
+		}
+		if err != nil {
+			t.Errorf("%q: unexpected error: %v", f.Value, err)
+		} else if got != f.Expected {
+			t.Errorf("%q: got %d, want %d", f.Value, got, f.Expected)
+		}
+	}
+}
+
+func TestCheckMemoryPlug(t *testing.T) {
+	hotplug := machina.Memory{RAM: 4096, MaxRAM: 16384, Slots: 2}
+	numa := machina.Memory{MaxRAM: 8192, Slots: 4}
+	huge := machina.Memory{RAM: 4096, MaxRAM: 16384, Slots: 2, HugePages: machina.HugePageSize1G}
+
+	tests := []struct {
+		Name    string
+		Mem     machina.Memory
+		Current int
+		DIMMs   int
+		Size    int
+		OK      bool
+	}{
+		{"disabled", machina.Memory{RAM: 4096}, 4096, 0, 1024, false},
+		{"fits", hotplug, 4096, 0, 4096, true},
+		{"reaches the maximum", hotplug, 12288, 1, 4096, true},
+		{"exceeds the maximum", hotplug, 12288, 1, 8192, false},
+		{"slots exhausted", hotplug, 8192, 2, 1024, false},
+		{"numa nodes within the maximum", numa, 6144, 0, 2048, true},
+		{"numa nodes exceed the maximum", numa, 6144, 0, 4096, false},
+		{"huge page multiple", huge, 4096, 0, 2048, true},
+		{"not a huge page multiple", huge, 4096, 0, 1536, false},
+	}
+	for _, test := range tests {
+		err := checkMemoryPlug(test.Mem, test.Current, test.DIMMs, test.Size)
+		if test.OK && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.Name, err)
+		} else if !test.OK && err == nil {
+			t.Errorf("%s: expected an error", test.Name)
+		}
+	}
+}
+
+func TestNextDIMMID(t *testing.T) {
+	devices := []qmpcmd.MemoryDeviceInfo{
+		{Type: "dimm", Data: qmpcmd.MemoryDeviceData{ID: "dimm.0"}},
+		{Type: "dimm", Data: qmpcmd.MemoryDeviceData{ID: "dimm.2"}},
+	}
+	if got := nextDIMMID(devices); got != "dimm.1" {
+		t.Errorf("got %s, want dimm.1", got)
+	}
+}
@@ -24,3 +24,23 @@ func disableConnection(machine machina.MachineName, conn machina.Connection, sys
 	}
 	return errors.New("not supported on systems without netlink")
 }
+
+func checkConnection(machine machina.MachineName, conn machina.Connection, sys machina.System) error {
+	_, ok := sys.Network[conn.Network]
+	if !ok {
+		return fmt.Errorf("invalid network name \"%s\"", conn.Network)
+	}
+	return nil
+}
+
+func createTap(machine machina.MachineName, privs machina.Privileges, conn machina.Connection) error {
+	return errors.New("not supported on systems without netlink")
+}
+
+func removeTap(machine machina.MachineName, conn machina.Connection) error {
+	return errors.New("not supported on systems without netlink")
+}
+
+func readLinkStats(name string) (linkStats, error) {
+	return linkStats{}, errors.New("not supported on systems without netlink")
+}
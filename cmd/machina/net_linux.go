@@ -9,7 +9,14 @@
 package main
 
 import (
+	"bytes"
 	"fmt"
+	"net"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"syscall"
 
 	"github.com/gentlemanautomaton/machina"
 	"github.com/vishvananda/netlink"
@@ -69,3 +76,204 @@ func disableConnection(machine machina.MachineName, conn machina.Connection, sys
 
 	return nil
 }
+
+// checkConnection performs a set of pre-flight checks for a connection
+// before QEMU starts. It verifies that the connection's bridge exists and is
+// up, that its link name isn't already in use by another process, and that
+// its hardware address isn't already present on the bridge.
+func checkConnection(machine machina.MachineName, conn machina.Connection, sys machina.System) error {
+	network, ok := sys.Network[conn.Network]
+	if !ok {
+		return fmt.Errorf("invalid network name \"%s\"", conn.Network)
+	}
+
+	// User-mode networks don't rely on host network interfaces
+	if network.EffectiveType().UserMode() {
+		return nil
+	}
+
+	// Make sure the bridge exists and is up
+	bridgeLink, err := netlink.LinkByName(network.Device)
+	if err != nil {
+		return fmt.Errorf("bridge \"%s\" for network \"%s\" not found: %v", network.Device, conn.Network, err)
+	}
+	bridge, ok := bridgeLink.(*netlink.Bridge)
+	if !ok {
+		return fmt.Errorf("network link \"%s\" for network \"%s\" is not a bridge", network.Device, conn.Network)
+	}
+	if bridge.Attrs().Flags&net.FlagUp == 0 {
+		return fmt.Errorf("bridge \"%s\" for network \"%s\" is down", network.Device, conn.Network)
+	}
+
+	// Make sure the link name isn't already in use by another interface or
+	// process
+	linkName := machina.MakeLinkName(machine, conn)
+	linkIndex := 0
+	if link, err := netlink.LinkByName(linkName); err == nil {
+		if _, isTap := link.(*netlink.Tuntap); !isTap {
+			return fmt.Errorf("link name \"%s\" is already in use by a network interface of type \"%s\"", linkName, link.Type())
+		}
+		if pid, command, found := findTapProcess(linkName); found {
+			return fmt.Errorf("link name \"%s\" is already in use by process %d (%s)", linkName, pid, command)
+		}
+		linkIndex = link.Attrs().Index
+	} else if _, notFound := err.(netlink.LinkNotFoundError); !notFound {
+		return fmt.Errorf("failed to look up link \"%s\": %v", linkName, err)
+	}
+
+	// Make sure the hardware address isn't already present on the bridge
+	if conn.MAC != "" {
+		mac, err := net.ParseMAC(conn.MAC)
+		if err != nil {
+			return fmt.Errorf("invalid hardware address \"%s\": %v", conn.MAC, err)
+		}
+
+		entries, err := netlink.NeighList(0, syscall.AF_BRIDGE)
+		if err != nil {
+			return fmt.Errorf("failed to retrieve the forwarding database for bridge \"%s\": %v", network.Device, err)
+		}
+
+		for _, entry := range entries {
+			if entry.MasterIndex != bridge.Attrs().Index {
+				continue
+			}
+			if entry.LinkIndex == linkIndex || !bytes.Equal(entry.HardwareAddr, mac) {
+				continue
+			}
+			port := strconv.Itoa(entry.LinkIndex)
+			if portLink, err := netlink.LinkByIndex(entry.LinkIndex); err == nil {
+				port = portLink.Attrs().Name
+			}
+			return fmt.Errorf("hardware address %s is already present on bridge \"%s\" via port \"%s\"", conn.MAC, network.Device, port)
+		}
+	}
+
+	return nil
+}
+
+// findTapProcess searches the file descriptors of running processes for one
+// that is attached to the tap device with the given interface name.
+//
+// If a process is found, its process ID and command name are returned.
+func findTapProcess(ifname string) (pid int, command string, found bool) {
+	procs, err := os.ReadDir("/proc")
+	if err != nil {
+		return 0, "", false
+	}
+
+	want := "iff:\t" + ifname
+	for _, proc := range procs {
+		pid, err := strconv.Atoi(proc.Name())
+		if err != nil {
+			continue
+		}
+
+		fdinfoDir := filepath.Join("/proc", proc.Name(), "fdinfo")
+		fds, err := os.ReadDir(fdinfoDir)
+		if err != nil {
+			continue
+		}
+
+		for _, fd := range fds {
+			data, err := os.ReadFile(filepath.Join(fdinfoDir, fd.Name()))
+			if err != nil {
+				continue
+			}
+			for _, line := range strings.Split(string(data), "\n") {
+				if line != want {
+					continue
+				}
+				comm, _ := os.ReadFile(filepath.Join("/proc", proc.Name(), "comm"))
+				return pid, strings.TrimSpace(string(comm)), true
+			}
+		}
+	}
+
+	return 0, "", false
+}
+
+// createTap creates a persistent tap device for a connection. The tap device
+// will be owned by the owner and group specified in the machine's privileges.
+//
+// If the tap device already exists it is left as-is.
+func createTap(machine machina.MachineName, privs machina.Privileges, conn machina.Connection) error {
+	name := machina.MakeLinkName(machine, conn)
+
+	// Check whether the tap device already exists
+	if link, err := netlink.LinkByName(name); err == nil {
+		if _, isTap := link.(*netlink.Tuntap); !isTap {
+			return fmt.Errorf("link name \"%s\" is already in use by a network interface of type \"%s\"", name, link.Type())
+		}
+		return nil
+	}
+
+	tap := &netlink.Tuntap{
+		LinkAttrs: netlink.LinkAttrs{Name: name},
+		Mode:      netlink.TUNTAP_MODE_TAP,
+		Flags:     netlink.TUNTAP_NO_PI | netlink.TUNTAP_VNET_HDR,
+		Owner:     uint32(privs.Network.Owner),
+		Group:     uint32(privs.FileSystem.Group.ID),
+	}
+	if err := netlink.LinkAdd(tap); err != nil {
+		return fmt.Errorf("failed to create tap device \"%s\": %v", name, err)
+	}
+
+	// Release the file descriptors that were opened while creating the
+	// tap device, so that QEMU can attach to it
+	for _, fd := range tap.Fds {
+		fd.Close()
+	}
+
+	return nil
+}
+
+// removeTap removes the persistent tap device for a connection, if it
+// exists.
+func removeTap(machine machina.MachineName, conn machina.Connection) error {
+	name := machina.MakeLinkName(machine, conn)
+
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		if _, notFound := err.(netlink.LinkNotFoundError); notFound {
+			return nil
+		}
+		return fmt.Errorf("failed to look up link \"%s\": %v", name, err)
+	}
+	if _, isTap := link.(*netlink.Tuntap); !isTap {
+		return fmt.Errorf("link \"%s\" is not a tap device", name)
+	}
+
+	if err := netlink.LinkDel(link); err != nil {
+		return fmt.Errorf("failed to remove tap device \"%s\": %v", name, err)
+	}
+
+	return nil
+}
+
+// readLinkStats returns the current statistics for the named link. If the
+// link does not exist, errLinkNotPresent is returned.
+func readLinkStats(name string) (linkStats, error) {
+	link, err := netlink.LinkByName(name)
+	if err != nil {
+		if _, notFound := err.(netlink.LinkNotFoundError); notFound {
+			return linkStats{}, errLinkNotPresent
+		}
+		return linkStats{}, fmt.Errorf("failed to look up link \"%s\": %v", name, err)
+	}
+
+	stats := link.Attrs().Statistics
+	if stats == nil {
+		return linkStats{}, fmt.Errorf("statistics are not available for link \"%s\"", name)
+	}
+
+	return linkStats{
+		RxBytes:   stats.RxBytes,
+		RxPackets: stats.RxPackets,
+		RxErrors:  stats.RxErrors,
+		RxDropped: stats.RxDropped,
+		TxBytes:   stats.TxBytes,
+		TxPackets: stats.TxPackets,
+		TxErrors:  stats.TxErrors,
+		TxDropped: stats.TxDropped,
+	}, nil
+}
@@ -0,0 +1,264 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/gentlemanautomaton/machina"
+)
+
+// errLinkNotPresent is returned when a connection's link does not exist on
+// the host, which is typically because its virtual machine isn't running.
+var errLinkNotPresent = errors.New("link not present")
+
+// NetCmd reports on the network connections of virtual machines.
+type NetCmd struct {
+	Stats NetStatsCmd `kong:"cmd,help='Reports traffic statistics for virtual machine network connections.'"`
+}
+
+// NetStatsCmd reports traffic statistics for the tap devices of virtual
+// machine network connections.
+//
+// Statistics are reported from the perspective of the host. Traffic
+// received by a tap device was transmitted by the guest, and traffic
+// transmitted by a tap device was received by the guest.
+type NetStatsCmd struct {
+	MachinesOrConnections []string      `kong:"arg,optional,predictor=machines,help='Machines or individual connections to report on. Use [machine] or [machine].[conn]. All machines are included if none are provided.'"`
+	Interval              time.Duration `kong:"interval,short='i',help='Report rates of change at the given interval instead of totals.'"`
+	Count                 int           `kong:"count,short='c',help='Stop after the given number of intervals. Implies interval.'"`
+	JSON                  bool          `kong:"json,help='Write each sample as a JSON object on its own line.'"`
+}
+
+// Run executes the net stats command.
+func (cmd NetStatsCmd) Run(ctx context.Context) error {
+	names := cmd.MachinesOrConnections
+	if len(names) == 0 {
+		machines, err := EnumMachines()
+		if err != nil {
+			return err
+		}
+		for _, machine := range machines {
+			names = append(names, string(machine))
+		}
+	}
+
+	mconns, sys, err := LoadMachineConnections(names...)
+	if err != nil {
+		return err
+	}
+
+	// User-mode networks don't have a link on the host, so there's nothing
+	// for us to report on.
+	var targets []netStatsTarget
+	for _, mconn := range mconns {
+		if network, ok := sys.Network[mconn.Network]; ok && network.EffectiveType().UserMode() {
+			continue
+		}
+		targets = append(targets, netStatsTarget{
+			Connection: fmt.Sprintf("%s.%s", mconn.Machine, mconn.Name),
+			Link:       machina.MakeLinkName(mconn.Machine, mconn.Connection),
+		})
+	}
+
+	interval := cmd.Interval
+	if interval <= 0 && cmd.Count > 0 {
+		interval = time.Second
+	}
+
+	// Report totals if an interval wasn't requested.
+	if interval <= 0 {
+		return cmd.print(collectNetStats(targets), nil)
+	}
+
+	// Report rates of change for each interval.
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	previous := collectNetStats(targets)
+	for i := 0; cmd.Count <= 0 || i < cmd.Count; i++ {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		current := collectNetStats(targets)
+		if err := cmd.print(current, previous); err != nil {
+			return err
+		}
+		previous = current
+	}
+
+	return nil
+}
+
+// print writes a set of samples to stdout. If previous samples are
+// provided, rates of change are reported instead of totals.
+func (cmd NetStatsCmd) print(samples, previous []netStatsSample) error {
+	return cmd.write(os.Stdout, samples, previous)
+}
+
+// write writes a set of samples to out. If previous samples are provided,
+// rates of change are reported instead of totals.
+func (cmd NetStatsCmd) write(out io.Writer, samples, previous []netStatsSample) error {
+	records := make([]netStatsRecord, len(samples))
+	for i, sample := range samples {
+		records[i] = netStatsRecord{
+			Time:       sample.Time,
+			Connection: sample.Connection,
+			Link:       sample.Link,
+			Present:    sample.Err == nil,
+		}
+		switch {
+		case errors.Is(sample.Err, errLinkNotPresent):
+		case sample.Err != nil:
+			records[i].Error = sample.Err.Error()
+		case previous == nil:
+			totals := sample.Stats
+			records[i].Totals = &totals
+		case previous[i].Err == nil:
+			rate := sample.Stats.RateSince(previous[i].Stats, sample.Time.Sub(previous[i].Time))
+			records[i].Rate = &rate
+		}
+	}
+
+	if cmd.JSON {
+		enc := json.NewEncoder(out)
+		for _, record := range records {
+			if err := enc.Encode(record); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+	if previous == nil {
+		fmt.Fprintln(w, "CONNECTION\tLINK\tRX BYTES\tRX PACKETS\tRX ERRORS\tRX DROPPED\tTX BYTES\tTX PACKETS\tTX ERRORS\tTX DROPPED\t")
+	} else {
+		fmt.Fprintln(w, "CONNECTION\tLINK\tRX B/s\tRX PKT/s\tRX ERR/s\tRX DROP/s\tTX B/s\tTX PKT/s\tTX ERR/s\tTX DROP/s\t")
+	}
+	for _, record := range records {
+		switch {
+		case record.Totals != nil:
+			s := record.Totals
+			fmt.Fprintf(w, "%s\t%s\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t%d\t\n", record.Connection, record.Link,
+				s.RxBytes, s.RxPackets, s.RxErrors, s.RxDropped, s.TxBytes, s.TxPackets, s.TxErrors, s.TxDropped)
+		case record.Rate != nil:
+			r := record.Rate
+			fmt.Fprintf(w, "%s\t%s\t%.0f\t%.0f\t%.0f\t%.0f\t%.0f\t%.0f\t%.0f\t%.0f\t\n", record.Connection, record.Link,
+				r.RxBytes, r.RxPackets, r.RxErrors, r.RxDropped, r.TxBytes, r.TxPackets, r.TxErrors, r.TxDropped)
+		case record.Error != "":
+			fmt.Fprintf(w, "%s\t%s\tfailed: %s\t\n", record.Connection, record.Link, record.Error)
+		case !record.Present:
+			fmt.Fprintf(w, "%s\t%s\t-\t-\t-\t-\t-\t-\t-\t-\t\n", record.Connection, record.Link)
+		default:
+			// The link has only just appeared, so there's no rate yet.
+			fmt.Fprintf(w, "%s\t%s\t...\t\n", record.Connection, record.Link)
+		}
+	}
+	if previous != nil {
+		fmt.Fprintln(w)
+	}
+	return w.Flush()
+}
+
+// netStatsTarget identifies a connection and the link on the host that
+// carries its traffic.
+type netStatsTarget struct {
+	Connection string
+	Link       string
+}
+
+// netStatsSample holds statistics collected for a target at a point in
+// time.
+type netStatsSample struct {
+	netStatsTarget
+	Time  time.Time
+	Stats linkStats
+	Err   error
+}
+
+// collectNetStats samples the current statistics for each target.
+func collectNetStats(targets []netStatsTarget) []netStatsSample {
+	samples := make([]netStatsSample, len(targets))
+	for i, target := range targets {
+		stats, err := readLinkStats(target.Link)
+		samples[i] = netStatsSample{
+			netStatsTarget: target,
+			Time:           time.Now(),
+			Stats:          stats,
+			Err:            err,
+		}
+	}
+	return samples
+}
+
+// netStatsRecord is a single line of output for the net stats command.
+type netStatsRecord struct {
+	Time       time.Time  `json:"time"`
+	Connection string     `json:"connection"`
+	Link       string     `json:"link"`
+	Present    bool       `json:"present"`
+	Totals     *linkStats `json:"totals,omitempty"`
+	Rate       *linkRate  `json:"rate,omitempty"`
+	Error      string     `json:"error,omitempty"`
+}
+
+// linkStats holds traffic counters for a network link.
+type linkStats struct {
+	RxBytes   uint64 `json:"rx-bytes"`
+	RxPackets uint64 `json:"rx-packets"`
+	RxErrors  uint64 `json:"rx-errors"`
+	RxDropped uint64 `json:"rx-dropped"`
+	TxBytes   uint64 `json:"tx-bytes"`
+	TxPackets uint64 `json:"tx-packets"`
+	TxErrors  uint64 `json:"tx-errors"`
+	TxDropped uint64 `json:"tx-dropped"`
+}
+
+// RateSince returns the per-second rate of change from previous to s over
+// the given elapsed time.
+func (s linkStats) RateSince(previous linkStats, elapsed time.Duration) linkRate {
+	seconds := elapsed.Seconds()
+	if seconds <= 0 {
+		return linkRate{}
+	}
+	rate := func(current, previous uint64) float64 {
+		// If the counter went backwards the link was probably recreated,
+		// in which case its counters started over from zero.
+		if current < previous {
+			return float64(current) / seconds
+		}
+		return float64(current-previous) / seconds
+	}
+	return linkRate{
+		RxBytes:   rate(s.RxBytes, previous.RxBytes),
+		RxPackets: rate(s.RxPackets, previous.RxPackets),
+		RxErrors:  rate(s.RxErrors, previous.RxErrors),
+		RxDropped: rate(s.RxDropped, previous.RxDropped),
+		TxBytes:   rate(s.TxBytes, previous.TxBytes),
+		TxPackets: rate(s.TxPackets, previous.TxPackets),
+		TxErrors:  rate(s.TxErrors, previous.TxErrors),
+		TxDropped: rate(s.TxDropped, previous.TxDropped),
+	}
+}
+
+// linkRate holds per-second rates of change for the traffic counters of a
+// network link.
+type linkRate struct {
+	RxBytes   float64 `json:"rx-bytes"`
+	RxPackets float64 `json:"rx-packets"`
+	RxErrors  float64 `json:"rx-errors"`
+	RxDropped float64 `json:"rx-dropped"`
+	TxBytes   float64 `json:"tx-bytes"`
+	TxPackets float64 `json:"tx-packets"`
+	TxErrors  float64 `json:"tx-errors"`
+	TxDropped float64 `json:"tx-dropped"`
+}
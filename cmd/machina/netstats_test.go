@@ -0,0 +1,108 @@
+package main
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestLinkStatsRateSince(t *testing.T) {
+	tests := []struct {
+		Name     string
+		Previous linkStats
+		Current  linkStats
+		Elapsed  time.Duration
+		Want     linkRate
+	}{
+		{"steady", linkStats{RxBytes: 1000, TxPackets: 10}, linkStats{RxBytes: 3000, TxPackets: 30}, 2 * time.Second, linkRate{RxBytes: 1000, TxPackets: 10}},
+		{"idle", linkStats{RxBytes: 1000}, linkStats{RxBytes: 1000}, time.Second, linkRate{}},
+		{"recreated", linkStats{RxBytes: 5000, TxDropped: 4}, linkStats{RxBytes: 500, TxDropped: 1}, time.Second, linkRate{RxBytes: 500, TxDropped: 1}},
+		{"no time elapsed", linkStats{RxBytes: 1000}, linkStats{RxBytes: 2000}, 0, linkRate{}},
+	}
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			if got := test.Current.RateSince(test.Previous, test.Elapsed); got != test.Want {
+				t.Errorf("got %+v, want %+v", got, test.Want)
+			}
+		})
+	}
+}
+
+func TestNetStatsWrite(t *testing.T) {
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	sample := func(conn string, at time.Duration, stats linkStats, err error) netStatsSample {
+		return netStatsSample{
+			netStatsTarget: netStatsTarget{Connection: conn, Link: "tap-" + conn},
+			Time:           start.Add(at),
+			Stats:          stats,
+			Err:            err,
+		}
+	}
+	stats := linkStats{RxBytes: 2048, RxPackets: 16, RxErrors: 1, TxBytes: 4096, TxPackets: 32, TxDropped: 2}
+
+	tests := []struct {
+		Name     string
+		JSON     bool
+		Samples  []netStatsSample
+		Previous []netStatsSample
+		Want     string
+	}{
+		{
+			Name:    "totals",
+			Samples: []netStatsSample{sample("a", 0, stats, nil)},
+			Want: "" +
+				"CONNECTION  LINK   RX BYTES  RX PACKETS  RX ERRORS  RX DROPPED  TX BYTES  TX PACKETS  TX ERRORS  TX DROPPED  \n" +
+				"a           tap-a  2048      16          1          0           4096      32          0          2           \n",
+		},
+		{
+			Name: "absent and failed",
+			Samples: []netStatsSample{
+				sample("a", 0, linkStats{}, errLinkNotPresent),
+				sample("b", 0, linkStats{}, errors.New("boom")),
+			},
+			Want: "" +
+				"CONNECTION  LINK   RX BYTES      RX PACKETS  RX ERRORS  RX DROPPED  TX BYTES  TX PACKETS  TX ERRORS  TX DROPPED  \n" +
+				"a           tap-a  -             -           -          -           -         -           -          -           \n" +
+				"b           tap-b  failed: boom  \n",
+		},
+		{
+			Name:     "rates",
+			Samples:  []netStatsSample{sample("a", 2*time.Second, stats, nil), sample("b", 2*time.Second, stats, nil)},
+			Previous: []netStatsSample{sample("a", 0, linkStats{}, nil), sample("b", 0, linkStats{}, errLinkNotPresent)},
+			Want: "" +
+				"CONNECTION  LINK   RX B/s  RX PKT/s  RX ERR/s  RX DROP/s  TX B/s  TX PKT/s  TX ERR/s  TX DROP/s  \n" +
+				"a           tap-a  1024    8         0         0          2048    16        0         1          \n" +
+				"b           tap-b  ...     \n" +
+				"\n",
+		},
+		{
+			Name:    "json totals",
+			JSON:    true,
+			Samples: []netStatsSample{sample("a", 0, stats, nil), sample("b", 0, linkStats{}, errLinkNotPresent)},
+			Want: "" +
+				`{"time":"2024-01-01T00:00:00Z","connection":"a","link":"tap-a","present":true,"totals":{"rx-bytes":2048,"rx-packets":16,"rx-errors":1,"rx-dropped":0,"tx-bytes":4096,"tx-packets":32,"tx-errors":0,"tx-dropped":2}}` + "\n" +
+				`{"time":"2024-01-01T00:00:00Z","connection":"b","link":"tap-b","present":false}` + "\n",
+		},
+		{
+			Name:     "json rates",
+			JSON:     true,
+			Samples:  []netStatsSample{sample("a", time.Second, linkStats{RxBytes: 100}, nil)},
+			Previous: []netStatsSample{sample("a", 0, linkStats{RxBytes: 40}, nil)},
+			Want: "" +
+				`{"time":"2024-01-01T00:00:01Z","connection":"a","link":"tap-a","present":true,"rate":{"rx-bytes":60,"rx-packets":0,"rx-errors":0,"rx-dropped":0,"tx-bytes":0,"tx-packets":0,"tx-errors":0,"tx-dropped":0}}` + "\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			var out strings.Builder
+			if err := (NetStatsCmd{JSON: test.JSON}).write(&out, test.Samples, test.Previous); err != nil {
+				t.Fatal(err)
+			}
+			if got := out.String(); got != test.Want {
+				t.Errorf("unexpected output:\n%s\nwant:\n%s", got, test.Want)
+			}
+		})
+	}
+}
@@ -0,0 +1,215 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/pcifs"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+// deviceClaim describes a claim made by a machine device on a host device.
+type deviceClaim struct {
+	Machine machina.MachineName
+	Device  machina.DeviceName
+}
+
+// String returns a string representation of the claim.
+func (claim deviceClaim) String() string {
+	return fmt.Sprintf("%s (device %s)", claim.Machine, claim.Device)
+}
+
+// passthroughClaimMap maps the PCI addresses of passthrough devices to the
+// machine devices that claim them.
+type passthroughClaimMap map[machina.DeviceAddress][]deviceClaim
+
+// Add adds the passthrough devices claimed by a machine to the map.
+func (m passthroughClaimMap) Add(machine machina.MachineName, devices []machina.Device, sys machina.System) error {
+	for _, device := range devices {
+		for _, pdev := range sys.PassthroughDevices.WithClass(device.Class) {
+			host, err := pcifs.Locate(pdev)
+			if err != nil {
+				return fmt.Errorf("failed to locate passthrough device %s for device %s.%s: %v", pdev, machine, device.Name, err)
+			}
+			m[host.Address()] = append(m[host.Address()], deviceClaim{
+				Machine: machine,
+				Device:  device.Name,
+			})
+		}
+	}
+	return nil
+}
+
+// Others returns the claims made on the device with the given address by
+// machines other than the given machine.
+func (m passthroughClaimMap) Others(address machina.DeviceAddress, machine machina.MachineName) (others []deviceClaim) {
+	for _, claim := range m[address] {
+		if claim.Machine != machine {
+			others = append(others, claim)
+		}
+	}
+	return others
+}
+
+// Active returns the claims in the map that are made by active machines.
+func (m passthroughClaimMap) Active(active map[machina.MachineName]bool) passthroughClaimMap {
+	filtered := make(passthroughClaimMap)
+	for address, claims := range m {
+		for _, claim := range claims {
+			if active[claim.Machine] {
+				filtered[address] = append(filtered[address], claim)
+			}
+		}
+	}
+	return filtered
+}
+
+// collectPassthroughClaims loads the configuration of every machine on the
+// local system and returns the passthrough devices that they claim.
+//
+// Machines with configuration that cannot be loaded are skipped.
+func collectPassthroughClaims(sys machina.System) (passthroughClaimMap, error) {
+	claims := make(passthroughClaimMap)
+	if len(sys.PassthroughDevices) == 0 {
+		return claims, nil
+	}
+
+	definitions, err := loadAllDefinitions(sys)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(definitions)) {
+		if err := claims.Add(name, definitions[name].Devices, sys); err != nil {
+			return nil, err
+		}
+	}
+
+	return claims, nil
+}
+
+// loadAllDefinitions loads and builds the definition of every machine on
+// the local system. Building a definition populates its device IDs, which
+// allows host devices to be traced back to the machines that use them.
+//
+// Machines with configuration that cannot be loaded are skipped.
+func loadAllDefinitions(sys machina.System) (map[machina.MachineName]machina.Definition, error) {
+	names, err := EnumMachines()
+	if err != nil {
+		return nil, err
+	}
+
+	definitions := make(map[machina.MachineName]machina.Definition, len(names))
+	for _, name := range names {
+		machine, err := LoadMachine(name)
+		if err != nil {
+			continue
+		}
+		definition, err := machina.Build(machine, sys)
+		if err != nil {
+			continue
+		}
+		definitions[name] = definition
+	}
+
+	return definitions, nil
+}
+
+// checkPassthroughDevices verifies that the passthrough devices claimed by
+// a machine can be handed to vfio. The IOMMU groups of the devices are
+// read from fsys, which must be rooted at the sysfs mount point. Every other member of their IOMMU groups
+// must be a bridge or must be usable by vfio, and must not be claimed by a
+// different machine that is active.
+//
+// Claims made by machines that aren't active are ignored, which allows more
+// than one machine to be configured with the same passthrough devices as
+// long as only one of them runs at a time.
+func checkPassthroughDevices(ctx context.Context, fsys fs.FS, machine machina.MachineName, devices []machina.Device, sys machina.System) error {
+	own := make(passthroughClaimMap)
+	if err := own.Add(machine, devices, sys); err != nil {
+		return err
+	}
+	if len(own) == 0 {
+		return nil
+	}
+
+	claims, err := collectPassthroughClaims(sys)
+	if err != nil {
+		return err
+	}
+
+	active, err := collectActiveMachines(ctx)
+	if err != nil {
+		return err
+	}
+
+	return checkIOMMUGroups(fsys, machine, own, claims.Active(active))
+}
+
+// checkIOMMUGroups examines the IOMMU groups of the passthrough devices in
+// own, which are claimed by machine. The remaining members of each group
+// are compared against the given claims, which are typically those made
+// by active machines.
+func checkIOMMUGroups(fsys fs.FS, machine machina.MachineName, own, claims passthroughClaimMap) error {
+	checked := make(map[int]bool)
+	for address := range own {
+		group, err := sysfs.FindIOMMUGroup(fsys, string(address))
+		if err != nil {
+			if errors.Is(err, sysfs.ErrNoIOMMUGroup) {
+				return fmt.Errorf("passthrough device %s does not belong to an IOMMU group: the IOMMU may be disabled", address)
+			}
+			return err
+		}
+		if checked[group.ID] {
+			continue
+		}
+		checked[group.ID] = true
+
+		var conflicts []string
+		for _, member := range group.Devices {
+			memberAddress := machina.DeviceAddress(member)
+
+			// Devices claimed by other active machines can't be shared, not
+			// even when they're claimed by this machine as well.
+			if others := claims.Others(memberAddress, machine); len(others) > 0 {
+				conflicts = append(conflicts, fmt.Sprintf("%s is claimed by %s", member, joinClaims(others)))
+				continue
+			}
+
+			// Devices claimed by this machine will be handed to vfio.
+			if _, claimed := own[memberAddress]; claimed {
+				continue
+			}
+
+			// Everything else must be left in a state that vfio can live
+			// with.
+			dev, err := sysfs.ReadPCIDevice(fsys, member)
+			if err != nil {
+				return err
+			}
+			if !dev.VFIOViable() {
+				conflicts = append(conflicts, fmt.Sprintf("%s [%s] is bound to %s and is not claimed by %s", dev.Address, dev.ID, dev.Driver, machine))
+			}
+		}
+
+		if len(conflicts) > 0 {
+			return fmt.Errorf("IOMMU group %d of passthrough device %s has conflicting members: %s", group.ID, address, strings.Join(conflicts, "; "))
+		}
+	}
+
+	return nil
+}
+
+func joinClaims(claims []deviceClaim) string {
+	entries := make([]string, len(claims))
+	for i, claim := range claims {
+		entries[i] = claim.String()
+	}
+	return strings.Join(entries, ", ")
+}
@@ -0,0 +1,76 @@
+package main
+
+import (
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+func TestCheckIOMMUGroups(t *testing.T) {
+	// The GPU shares IOMMU group 1 with its audio function and a root
+	// port. The network adapter is alone in group 10. The USB controller
+	// doesn't belong to a group.
+	uevent := func(driver, class, id, address string) *fstest.MapFile {
+		data := "PCI_CLASS=" + class + "\nPCI_ID=" + id + "\nPCI_SLOT_NAME=" + address + "\n"
+		if driver != "" {
+			data = "DRIVER=" + driver + "\n" + data
+		}
+		return &fstest.MapFile{Data: []byte(data)}
+	}
+	sysfs := func(audioDriver string) fstest.MapFS {
+		return fstest.MapFS{
+			"kernel/iommu_groups/1/devices/0000:00:01.0":  {},
+			"kernel/iommu_groups/1/devices/0000:01:00.0":  {},
+			"kernel/iommu_groups/1/devices/0000:01:00.1":  {},
+			"kernel/iommu_groups/10/devices/0000:02:00.0": {},
+			"bus/pci/devices/0000:00:01.0/uevent":         uevent("pcieport", "60400", "8086:1901", "0000:00:01.0"),
+			"bus/pci/devices/0000:01:00.0/uevent":         uevent("vfio-pci", "30000", "10DE:1EB8", "0000:01:00.0"),
+			"bus/pci/devices/0000:01:00.1/uevent":         uevent(audioDriver, "40300", "10DE:10F8", "0000:01:00.1"),
+			"bus/pci/devices/0000:02:00.0/uevent":         uevent("", "20000", "8086:1533", "0000:02:00.0"),
+			"bus/pci/devices/0000:03:00.0/uevent":         uevent("xhci_hcd", "C0330", "1912:0014", "0000:03:00.0"),
+		}
+	}
+
+	gpu := passthroughClaimMap{"0000:01:00.0": {{Machine: "alpha", Device: "gpu"}}}
+	gpuAndAudio := passthroughClaimMap{
+		"0000:01:00.0": {{Machine: "alpha", Device: "gpu"}},
+		"0000:01:00.1": {{Machine: "alpha", Device: "gpu"}},
+	}
+	nic := passthroughClaimMap{"0000:02:00.0": {{Machine: "alpha", Device: "nic"}}}
+	usb := passthroughClaimMap{"0000:03:00.0": {{Machine: "alpha", Device: "usb"}}}
+
+	tests := []struct {
+		Name        string
+		AudioDriver string
+		Own         passthroughClaimMap
+		Claims      passthroughClaimMap
+		Err         string
+	}{
+		{"bridge and vfio member", "vfio-pci", gpu, gpu, ""},
+		{"unbound member", "", gpu, gpu, ""},
+		{"member bound to host driver", "snd_hda_intel", gpu, gpu, "0000:01:00.1 [10de:10f8] is bound to snd_hda_intel and is not claimed by alpha"},
+		{"member claimed by the machine", "snd_hda_intel", gpuAndAudio, gpuAndAudio, ""},
+		{"member claimed by another active machine", "vfio-pci", gpu, passthroughClaimMap{
+			"0000:01:00.0": {{Machine: "alpha", Device: "gpu"}},
+			"0000:01:00.1": {{Machine: "beta", Device: "audio"}},
+		}, "0000:01:00.1 is claimed by beta (device audio)"},
+		{"device claimed by another active machine", "vfio-pci", nic, passthroughClaimMap{
+			"0000:02:00.0": {{Machine: "alpha", Device: "nic"}, {Machine: "beta", Device: "nic"}},
+		}, "0000:02:00.0 is claimed by beta (device nic)"},
+		{"no IOMMU group", "vfio-pci", usb, usb, "passthrough device 0000:03:00.0 does not belong to an IOMMU group"},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			err := checkIOMMUGroups(sysfs(test.AudioDriver), "alpha", test.Own, test.Claims)
+			switch {
+			case test.Err == "" && err != nil:
+				t.Errorf("unexpected error: %v", err)
+			case test.Err != "" && err == nil:
+				t.Errorf("expected an error containing %q", test.Err)
+			case test.Err != "" && !strings.Contains(err.Error(), test.Err):
+				t.Errorf("unexpected error: %v (want %q)", err, test.Err)
+			}
+		})
+	}
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"maps"
+	"os"
+	"path/filepath"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/pcifs"
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+	"github.com/gentlemanautomaton/machina/qemu/qvm"
+	"github.com/gentlemanautomaton/machina/qemugen"
+)
+
+// buildQEMU prepares the QEMU configuration for machine. Devices are
+// assigned the PCI addresses that were previously recorded for them, so
+// that they keep their addresses when other devices are added to the
+// machine.
+//
+// If record is true, the addresses assigned to the machine's devices are
+// recorded for future builds.
+func buildQEMU(machine machina.Machine, sys machina.System, record bool) (qvm.Definition, error) {
+	path := machina.MakePCIAddressMapPath(machine.Info())
+
+	addrs, err := loadPCIAddressMap(path)
+	if err != nil {
+		return qvm.Definition{}, err
+	}
+
+	vm, err := qemugen.BuildPinned(machine, sys, addrs, pcifs.Local())
+	if err != nil {
+		return qvm.Definition{}, err
+	}
+
+	if record {
+		if assigned := vm.Topology.Addrs(); !maps.Equal(addrs, assigned) {
+			if err := savePCIAddressMap(path, assigned); err != nil {
+				return qvm.Definition{}, err
+			}
+		}
+	}
+
+	return vm, nil
+}
+
+// loadPCIAddressMap reads the PCI address map recorded at path. If no map
+// has been recorded it returns nil.
+func loadPCIAddressMap(path string) (qdev.AddrMap, error) {
+	if path == "" {
+		return nil, nil
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read PCI address map: %w", err)
+	}
+
+	var addrs qdev.AddrMap
+	if err := json.Unmarshal(data, &addrs); err != nil {
+		return nil, fmt.Errorf("failed to parse PCI address map \"%s\": %w", path, err)
+	}
+
+	return addrs, nil
+}
+
+// savePCIAddressMap records addrs at path.
+func savePCIAddressMap(path string, addrs qdev.AddrMap) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(addrs, "", "\t")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return fmt.Errorf("failed to create state directory for PCI address map: %w", err)
+	}
+	if err := os.WriteFile(path, append(data, '\n'), 0644); err != nil {
+		return fmt.Errorf("failed to record PCI address map: %w", err)
+	}
+
+	return nil
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"time"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+	"github.com/gentlemanautomaton/machina/qmp"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+// PinCmd pins the vCPU and I/O threads of running virtual machines to the
+// host CPUs specified by their pinning attributes.
+type PinCmd struct {
+	Machines []machina.MachineName `kong:"arg,predictor=machines,help='Virtual machines to pin.'"`
+	Wait     bool                  `kong:"help='Wait for the virtual machines to accept QMP connections.'"`
+}
+
+// pinWaitTimeout is the amount of time that the pin command waits for
+// a machine's QMP socket to become available when --wait is specified.
+const pinWaitTimeout = 30 * time.Second
+
+// Run executes the pin command.
+func (cmd PinCmd) Run(ctx context.Context) error {
+	vms, _, err := LoadAndComposeMachines(cmd.Machines...)
+	if err != nil {
+		return err
+	}
+
+	var firstError error
+	for _, vm := range vms {
+		if err := pinMachine(ctx, vm, cmd.Wait); err != nil {
+			if firstError == nil {
+				firstError = err
+			}
+			fmt.Printf("%s: failed: %v\n", vm.Name, err)
+		}
+	}
+	return firstError
+}
+
+func pinMachine(ctx context.Context, vm ComposedMachine, wait bool) error {
+	pinning := vm.Attributes.Pinning
+	if err := pinning.Validate(); err != nil {
+		return err
+	}
+	vcpus, _ := sysfs.ParseCPUList(pinning.VCPUs)
+	iothreads, _ := sysfs.ParseCPUList(pinning.IOThreads)
+	if len(vcpus) == 0 && len(iothreads) == 0 {
+		fmt.Printf("%s: skipped: no vCPU or I/O thread pinning\n", vm.Name)
+		return nil
+	}
+
+	var (
+		client *qmp.Client
+		err    error
+	)
+	if wait {
+		client, err = waitForMachineQMP(ctx, vm, pinWaitTimeout)
+	} else {
+		client, err = connectToMachineQMP(vm)
+	}
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	if len(vcpus) > 0 {
+		var query qmpcmd.QueryCPU
+		if err := client.Execute(ctx, &query); err != nil {
+			return fmt.Errorf("failed to query vCPUs: %w", err)
+		}
+		cpus, err := query.CPUs()
+		if err != nil {
+			return fmt.Errorf("failed to parse vCPU query response: %w", err)
+		}
+		for _, cpu := range cpus {
+			set := vcpuAffinity(cpus, cpu, vcpus)
+			if err := setThreadAffinity(cpu.ThreadID, set); err != nil {
+				return fmt.Errorf("failed to pin vCPU %d to host CPUs %s: %w", cpu.CPUIndex, set, err)
+			}
+			fmt.Printf("%s: pinned vCPU %d to host CPUs %s\n", vm.Name, cpu.CPUIndex, set)
+		}
+	}
+
+	if len(iothreads) > 0 {
+		var query qmpcmd.QueryIOThreads
+		if err := client.Execute(ctx, &query); err != nil {
+			return fmt.Errorf("failed to query I/O threads: %w", err)
+		}
+		for _, thread := range query.Response {
+			if err := setThreadAffinity(thread.ThreadID, iothreads); err != nil {
+				return fmt.Errorf("failed to pin I/O thread %s to host CPUs %s: %w", thread.ID, iothreads, err)
+			}
+			fmt.Printf("%s: pinned I/O thread %s to host CPUs %s\n", vm.Name, thread.ID, iothreads)
+		}
+	}
+
+	return nil
+}
+
+// vcpuAffinity returns the host CPUs that cpu should be pinned to. If
+// there are at least as many host CPUs as vCPUs, each vCPU is pinned to its
+// own host CPU in vCPU index order. Otherwise every vCPU may run on any of
+// the host CPUs.
+func vcpuAffinity(cpus []qmpcmd.CPUInfo, cpu qmpcmd.CPUInfo, host sysfs.CPUList) sysfs.CPUList {
+	if len(host) < len(cpus) {
+		return host
+	}
+	indices := make([]int, 0, len(cpus))
+	for _, other := range cpus {
+		indices = append(indices, other.CPUIndex)
+	}
+	slices.Sort(indices)
+	position, _ := slices.BinarySearch(indices, cpu.CPUIndex)
+	return sysfs.CPUList{host[position]}
+}
+
+// waitForMachineQMP connects to the QMP command socket of a machine that
+// is starting. It retries until the socket accepts a connection or the
+// timeout elapses.
+//
+// It is the caller's responsibility to close the client when finished.
+func waitForMachineQMP(ctx context.Context, vm ComposedMachine, timeout time.Duration) (*qmp.Client, error) {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	for {
+		client, err := connectToMachineQMP(vm)
+		if err == nil {
+			return client, nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil, err
+		case <-time.After(250 * time.Millisecond):
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package main
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+func TestVCPUAffinity(t *testing.T) {
+	cpus := []qmpcmd.CPUInfo{
+		{CPUIndex: 2, ThreadID: 1003},
+		{CPUIndex: 0, ThreadID: 1001},
+		{CPUIndex: 1, ThreadID: 1002},
+	}
+
+	// With enough host CPUs, each vCPU gets its own host CPU in order.
+	host := sysfs.CPUList{4, 5, 6, 7}
+	for _, cpu := range cpus {
+		got := vcpuAffinity(cpus, cpu, host)
+		want := sysfs.CPUList{host[cpu.CPUIndex]}
+		if !slices.Equal(got, want) {
+			t.Errorf("vCPU %d: unexpected affinity %s (want %s)", cpu.CPUIndex, got, want)
+		}
+	}
+
+	// With fewer host CPUs than vCPUs, every vCPU shares the host CPUs.
+	host = sysfs.CPUList{4, 5}
+	for _, cpu := range cpus {
+		if got := vcpuAffinity(cpus, cpu, host); !slices.Equal(got, host) {
+			t.Errorf("vCPU %d: unexpected affinity %s (want %s)", cpu.CPUIndex, got, host)
+		}
+	}
+}
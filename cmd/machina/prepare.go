@@ -15,6 +15,8 @@ import (
 	"github.com/gentlemanautomaton/lockfile"
 	"github.com/gentlemanautomaton/machina"
 	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+	"github.com/gentlemanautomaton/machina/filesystem/pcifs"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
 	"github.com/gentlemanautomaton/machina/swtpmgen"
 )
 
@@ -148,13 +150,25 @@ func (cmd PrepareQEMUCmd) Run(ctx context.Context) error {
 }
 
 func prepareMachine(ctx context.Context, info machina.MachineInfo, definition machina.Definition, sys machina.System) error {
+	if err := checkPassthroughDevices(ctx, sysfs.Local(), info.Name, definition.Devices, sys); err != nil {
+		return fmt.Errorf("machine %s failed pre-flight checks: %w", info.Name, err)
+	}
+	if err := checkHugePages(sysfs.Local(), definition.Attributes); err != nil {
+		return fmt.Errorf("machine %s failed pre-flight checks: %w", info.Name, err)
+	}
+	if err := checkVirtualization(sysfs.Local(), definition.Attributes); err != nil {
+		return fmt.Errorf("machine %s failed pre-flight checks: %w", info.Name, err)
+	}
+	if err := prepareFirmware(info, definition, sys); err != nil {
+		return fmt.Errorf("machine %s: %w", info.Name, err)
+	}
 	for _, device := range definition.Devices {
 		if err := prepareDevice(ctx, device, sys); err != nil {
 			return err
 		}
 	}
 	for _, conn := range definition.Connections {
-		if err := prepareConnection(conn, sys); err != nil {
+		if err := prepareConnection(info.Name, definition.Privileges, conn, sys); err != nil {
 			return err
 		}
 	}
@@ -162,6 +176,23 @@ func prepareMachine(ctx context.Context, info machina.MachineInfo, definition ma
 }
 
 func prepareDevice(ctx context.Context, device machina.Device, sys machina.System) error {
+	// Passthrough devices are handed to the vfio-pci driver in their
+	// entirety.
+	if pdevs := sys.PassthroughDevices.WithClass(device.Class); len(pdevs) > 0 {
+		return preparePassthroughDevices(device, pdevs)
+	}
+
+	// USB devices are claimed by QEMU when it starts, so there's nothing to
+	// prepare beyond checking their configuration.
+	if udevs := sys.USBDevices.WithClass(device.Class); len(udevs) > 0 {
+		for _, udev := range udevs {
+			if err := udev.Validate(); err != nil {
+				return fmt.Errorf("device %s: %w", device.Name, err)
+			}
+		}
+		return nil
+	}
+
 	// Mediated devices require a device identifier.
 	if device.ID.IsZero() {
 		return nil
@@ -175,26 +206,58 @@ func prepareDevice(ctx context.Context, device machina.Device, sys machina.Syste
 	}
 
 	// Check whether mediated devices are supported.
-	if !mdevfs.Supported() {
+	bus := mdevfs.Local()
+	if !bus.Supported() {
 		return errors.New("mediated devices are not supported on the local system")
 	}
 
 	// Create and hold a lock file while initializing the device.
-	// Wait up to 10 seconds to acquire the lock.
-	{
-		ctx, cancel := context.WithTimeout(ctx, time.Second*10)
-		defer cancel()
-		filePath := path.Join(machina.LinuxRunDir, "mdev.lock")
-		lock, err := lockfile.WaitCtx(ctx, filePath)
-		if err != nil {
-			return fmt.Errorf("failed to create lock file \"%s\": %w", filePath, err)
+	lock, err := lockMediatedDevices(ctx)
+	if err != nil {
+		return err
+	}
+	defer lock.Close()
+
+	// Determine which CPUs the machine runs on if the physical device is
+	// selected by NUMA locality. If they can't be determined every
+	// physical device is treated as remote.
+	var cpus sysfs.CPUList
+	if mdevs.Policy(device.Class) == machina.NUMALocalMediatedDevicePolicy {
+		if cpus, err = allowedCPUs(); err != nil {
+			fmt.Printf("Unable to determine NUMA locality for device %s: %v\n", device.Name, err)
 		}
-		defer lock.Close()
 	}
 
+	return prepareMediatedDevice(ctx, bus, device, mdevs, cpus)
+}
+
+// lockMediatedDevices creates and acquires the lock file that coordinates
+// changes to mediated devices. It waits up to 10 seconds to acquire the
+// lock.
+//
+// It is the caller's responsibility to close the lock when finished.
+func lockMediatedDevices(ctx context.Context) (*lockfile.File, error) {
+	ctx, cancel := context.WithTimeout(ctx, time.Second*10)
+	defer cancel()
+	filePath := path.Join(machina.LinuxRunDir, "mdev.lock")
+	lock, err := lockfile.WaitCtx(ctx, filePath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create lock file \"%s\": %w", filePath, err)
+	}
+	return lock, nil
+}
+
+// prepareMediatedDevice creates a mediated device for device on one of the
+// given physical devices, unless it already exists. The physical devices
+// are tried in the order of preference determined by the policy of the
+// device class, until the mediated device is created on one of them. The
+// cpus are the host CPUs that the virtual machine runs on, which are used
+// by NUMA-local policies. The caller is expected to hold the mediated
+// device lock.
+func prepareMediatedDevice(ctx context.Context, bus mdevfs.System, device machina.Device, mdevs machina.MediatedDeviceList, cpus sysfs.CPUList) error {
 	// Check whether the mediated device already exists.
 	{
-		dev := mdevfs.NewMediatedDevice(device.ID)
+		dev := bus.MediatedDevice(device.ID)
 		if exists, err := dev.Exists(); err != nil {
 			return err
 		} else if exists {
@@ -202,104 +265,181 @@ func prepareDevice(ctx context.Context, device machina.Device, sys machina.Syste
 		}
 	}
 
-	// Loop through all of the mediated devices that were matched.
-	for _, mdev := range mdevs {
-		// Translate the device class to a mediated device type supplied by
-		// machina's system configuration.
-		wantedType, found := mdev.Classes[device.Class]
-		if !found || wantedType.Name == "" {
-			continue
-		}
+	policy := mdevs.Policy(device.Class)
+	if !policy.Valid() {
+		return fmt.Errorf("device class %s uses an unrecognized mediated device policy: \"%s\"", device.Class, policy)
+	}
 
-		// Prepare access to the physical device through sysfs.
-		parent := mdevfs.NewPhysicalDevice(mdev.Address)
-		if exists, err := parent.Exists(); err != nil {
-			return fmt.Errorf("failed to open mediated device file system for %s: %v", mdev.Address, err)
-		} else if !exists {
-			continue
-		}
+	// Find the physical devices with capacity for the device, in order of
+	// preference.
+	candidates, err := collectMediatedDeviceCandidates(bus, device, mdevs, policy, cpus)
+	if err != nil {
+		return err
+	}
 
-		// Enumerate the supported types via sysfs.
-		supportedTypes, err := parent.Types()
-		if err != nil {
-			return fmt.Errorf("failed to enumerate mediated device types supported by physical device %s: %v", mdev.Address, err)
+	// Fall back to the next physical device if the mediated device can't
+	// be created on one of them.
+	var errs []error
+	for _, candidate := range candidates {
+		err := createMediatedDevice(ctx, bus, device, candidate)
+		if err == nil {
+			return nil
+		}
+		if len(candidates) > 1 {
+			fmt.Printf("Unable to create mediated device %s on physical device %s: %v\n", device.ID, candidate.Config.Address, err)
 		}
+		errs = append(errs, fmt.Errorf("%s: %w", candidate.Config.Address, err))
+	}
 
-		// Search for an enumerated type with the requested name.
-		supportedType, found := supportedTypes.FindName(wantedType.Name)
-		if !found {
-			continue
+	return fmt.Errorf("failed to create mediated device %s for device %s: %w", device.ID, device.Name, errors.Join(errs...))
+}
+
+// createMediatedDevice creates a mediated device for device on the physical
+// device of the given candidate and applies its preferred placement.
+//
+// If the mediated device is created but its placement can't be applied,
+// the mediated device is removed again so that another physical device can
+// be tried.
+func createMediatedDevice(ctx context.Context, bus mdevfs.System, device machina.Device, candidate mediatedDeviceCandidate) error {
+	mdev, driver, parent := candidate.Config, candidate.Driver, candidate.Parent
+	wantedType := candidate.Wanted
+
+	// Enumerate the existing devices via sysfs.
+	existingDevices, err := candidate.Types.Devices()
+	if err != nil {
+		return fmt.Errorf("failed to enumerate mediated devices for physcial device %s: %v", mdev.Address, err)
+	}
+
+	// Discover the vendor-specific features of the physical device.
+	caps, err := driver.Capabilities(parent)
+	if err != nil {
+		return fmt.Errorf("failed to discover the capabilities of physical device %s: %v", mdev.Address, err)
+	}
+	if mdev.Heterogeneous && !caps.Heterogeneous {
+		return fmt.Errorf("heterogeneous mode is configured for physical device %s but is not supported by the device", mdev.Address)
+	}
+
+	// Let the vendor driver prepare the mode of the physical device,
+	// such as heterogeneous mode, before the mediated device is created.
+	if err := driver.PrepareMode(ctx, parent, mdev, existingDevices); err != nil {
+		return err
+	}
+
+	// If the device type has provided a set of preferred placements,
+	// collect the set of active placements so that we can search for
+	// an available placement later.
+	wantedPlacement := machina.MediatedDevicePlacementID(-1)
+	if len(wantedType.Placements) > 0 {
+		if !caps.Placement {
+			return fmt.Errorf("placements are configured for physical device %s but are not supported by the device or its %s vendor driver", mdev.Address, mdev.EffectiveDriver())
 		}
 
-		// Enumerate the existing devices via sysfs.
-		existingDevices, err := supportedTypes.Devices()
+		activePlacements, err := driver.Placements(existingDevices)
 		if err != nil {
-			return fmt.Errorf("failed to enumerate mediated devices for physcial device %s: %v", mdev.Address, err)
+			return fmt.Errorf("failed to collect active placements for mediated device %s: %v", mdev.Address, err)
 		}
 
-		// If the physical device needs be in heterogeneous mode and mediated
-		// devices haven't been created yet, attempt to set heterogeneous mode
-		// for the device now.
-		if mdev.Heterogeneous && len(existingDevices) == 0 {
-			if err := nvidiasmi(ctx, "vgpu", []string{"--id", string(mdev.Address), "-shm", "1"}); err != nil {
-				return fmt.Errorf("failed to set heterogeneous mode for physcial device %s: %v", mdev.Address, err)
+		for _, wanted := range wantedType.Placements {
+			if !activePlacements.Contains(wanted) {
+				wantedPlacement = wanted
+				break
 			}
 		}
+	}
 
-		// If the device type has provided a set of preferred placements,
-		// collect the set of active placements so that we can search for
-		// an available placement later.
-		wantedPlacement := machina.MediatedDevicePlacementID(-1)
-		if len(wantedType.Placements) > 0 {
-			activePlacements, err := existingDevices.Placements()
-			if err != nil {
-				return fmt.Errorf("failed to collect active placements for mediated device %s: %v", mdev.Address, err)
-			}
+	// Create the mediated device.
+	if err := candidate.Type.Create(device.ID); err != nil {
+		return err
+	}
+
+	// Verify that a mediated device with the device ID was created.
+	dev := bus.MediatedDevice(device.ID)
+	if exists, err := dev.Exists(); err != nil {
+		return fmt.Errorf("the mediated device %s was created but its existence could not be verified: %w", device.ID, err)
+	} else if !exists {
+		return fmt.Errorf("the mediated device %s should have been created but its existence could not be verified", device.ID)
+	}
 
-			for _, wanted := range wantedType.Placements {
-				if !activePlacements.Contains(wanted) {
-					wantedPlacement = wanted
-					break
-				}
+	// If a set of perferred device placements have been specified and a
+	// placement was selected, try to apply it.
+	if wantedPlacement >= 0 {
+		existingPlacement, err := driver.Placement(dev)
+		if err != nil {
+			fmt.Printf("Setting placement of mediated device %s to %d.\n", device.ID, wantedPlacement)
+			if err := driver.ChangePlacement(dev, wantedPlacement); err != nil {
+				err = fmt.Errorf("the mediated device %s was created but its placement ID could not be set to %d: %w", device.ID, wantedPlacement, err)
+				return errors.Join(err, dev.Remove())
+			}
+		} else if existingPlacement != wantedPlacement {
+			fmt.Printf("Changing placement of mediated device %s from %d to %d.\n", device.ID, existingPlacement, wantedPlacement)
+			if err := driver.ChangePlacement(dev, wantedPlacement); err != nil {
+				err = fmt.Errorf("the mediated device %s was created but its placement ID could not be changed from %d to %d: %w", device.ID, existingPlacement, wantedPlacement, err)
+				return errors.Join(err, dev.Remove())
 			}
 		}
+	}
 
-		// Create the mediated device.
-		if err := supportedType.Create(device.ID); err != nil {
-			return err
-		}
+	return nil
+}
 
-		// Verify that a mediated device with the device ID was created.
-		dev := mdevfs.NewMediatedDevice(device.ID)
-		if exists, err := dev.Exists(); err != nil {
-			return fmt.Errorf("the mediated device %s was created but its existence could not be verified: %w", device.ID, err)
+func preparePassthroughDevices(device machina.Device, pdevs machina.PassthroughDeviceList) error {
+	// Check whether PCI passthrough is supported.
+	if !pcifs.Supported() {
+		return fmt.Errorf("PCI passthrough is not supported on the local system: the %s driver is not loaded", pcifs.VFIODriver)
+	}
+
+	for _, pdev := range pdevs {
+		// Find the PCI device on the host.
+		host, err := pcifs.Locate(pdev)
+		if err != nil {
+			return fmt.Errorf("failed to locate passthrough device %s for device %s: %v", pdev, device.Name, err)
+		}
+		if exists, err := host.Exists(); err != nil {
+			return fmt.Errorf("failed to open PCI device file system for %s: %v", host.Address(), err)
 		} else if !exists {
-			return fmt.Errorf("the mediated device %s should have been created but its existence could not be verified", device.ID)
+			return fmt.Errorf("the passthrough device %s for device %s is not present", host.Address(), device.Name)
 		}
 
-		// If a set of perferred device placements have been specified and a
-		// placement was selected, try to apply it.
-		if wantedPlacement >= 0 {
-			existingPlacement, err := dev.Placement()
-			if err != nil {
-				fmt.Printf("Setting placement of mediated device %s to %d.\n", device.ID, wantedPlacement)
-				if err := dev.ChangePlacement(wantedPlacement); err != nil {
-					return fmt.Errorf("the mediated device %s was created but its placement ID could not be set to %d: %w", device.ID, wantedPlacement, err)
-				}
-			} else if existingPlacement != wantedPlacement {
-				fmt.Printf("Changing placement of mediated device %s from %d to %d.\n", device.ID, existingPlacement, wantedPlacement)
-				if err := dev.ChangePlacement(wantedPlacement); err != nil {
-					return fmt.Errorf("the mediated device %s was created but its placement ID could not be changed from %d to %d: %w", device.ID, existingPlacement, wantedPlacement, err)
-				}
-			}
+		// Hand the device over to vfio-pci if it's bound to something else.
+		driver, err := host.Driver()
+		if err != nil {
+			return err
+		}
+		if driver == pcifs.VFIODriver {
+			continue
+		}
+		if driver != "" {
+			fmt.Printf("Unbinding PCI device %s from %s and binding it to %s.\n", host.Address(), driver, pcifs.VFIODriver)
+		} else {
+			fmt.Printf("Binding PCI device %s to %s.\n", host.Address(), pcifs.VFIODriver)
+		}
+		if err := host.BindVFIO(); err != nil {
+			return fmt.Errorf("failed to prepare passthrough device %s for device %s: %w", host.Address(), device.Name, err)
 		}
-
-		return nil
 	}
 
 	return nil
 }
 
-func prepareConnection(conn machina.Connection, sys machina.System) error {
+func prepareConnection(machine machina.MachineName, privs machina.Privileges, conn machina.Connection, sys machina.System) error {
+	// Verify that the host network is ready for the connection before QEMU
+	// starts, so that problems are reported clearly instead of failing
+	// inside of the ifup script.
+	if err := checkConnection(machine, conn, sys); err != nil {
+		return fmt.Errorf("connection %s.%s failed pre-flight checks: %w", machine, conn.Name, err)
+	}
+
+	// If the network relies on persistent taps, create the tap device and
+	// attach it to the network now. QEMU will open it without running any
+	// scripts.
+	if network := sys.Network[conn.Network]; network.PersistentTaps() {
+		if err := createTap(machine, privs, conn); err != nil {
+			return fmt.Errorf("connection %s.%s: %w", machine, conn.Name, err)
+		}
+		if err := enableConnection(machine, conn, sys); err != nil {
+			return fmt.Errorf("connection %s.%s: %w", machine, conn.Name, err)
+		}
+	}
+
 	return nil
 }
@@ -1,13 +1,17 @@
 package main
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"math/rand"
 	"net"
 	"time"
 
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemugen"
 	"github.com/gentlemanautomaton/machina/qmp"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
 )
 
 // connectToQMP tries each socket in order until it finds one that's
@@ -46,3 +50,39 @@ func connectToQMP(socketPaths []string) (*qmp.Client, error) {
 
 	return nil, socketErr
 }
+
+// connectToMachineQMP connects to the QMP command socket of a running
+// machine.
+//
+// It is the caller's responsibility to close the client when finished.
+func connectToMachineQMP(vm ComposedMachine) (*qmp.Client, error) {
+	attrs := vm.Attributes.QMP
+	sockets := attrs.CommandSocketPaths(vm.MachineInfo)
+	if !attrs.Enabled || len(sockets) == 0 {
+		return nil, errors.New("no QMP socket available")
+	}
+	return connectToQMP(sockets)
+}
+
+// setGuestLink sets the link state of the virtual network adapter for conn
+// as seen by the guest. It communicates with the running machine via QMP.
+func setGuestLink(ctx context.Context, machine machina.MachineName, conn machina.Connection, up bool) error {
+	vms, _, err := LoadAndComposeMachines(machine)
+	if err != nil {
+		return err
+	}
+	if len(vms) != 1 {
+		return fmt.Errorf("failed to load machine \"%s\"", machine)
+	}
+
+	client, err := connectToMachineQMP(vms[0])
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	return client.Execute(ctx, qmpcmd.SetLink{
+		Name: string(qemugen.NetworkDeviceID(conn)),
+		Up:   up,
+	})
+}
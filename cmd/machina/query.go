@@ -42,27 +42,34 @@ func (cmd QueryPCICmd) Run(ctx context.Context) error {
 	})
 }
 
-// QueryCPUCmd returns information about the virtual CPUs in the given virtual machines.
+// QueryCPUCmd returns information about the virtual CPUs in the given virtual
+// machines, including the offline vCPUs that can be hotplugged.
 type QueryCPUCmd struct {
 	Machines []machina.MachineName `kong:"arg,predictor=machines,help='Virtual machines to query.'"`
 }
 
-// Run executes the query pci command.
+// Run executes the query cpu command.
 func (cmd QueryCPUCmd) Run(ctx context.Context) error {
 	return qmpQuery(ctx, cmd.Machines, func(c *qmp.Client, name machina.MachineName) {
+		slots, err := queryCPUSlots(ctx, c)
+		if err != nil {
+			fmt.Printf("Failed to query %s: %v\n", name, err)
+			return
+		}
+
 		var query qmpcmd.QueryCPU
 		if err := c.Execute(ctx, &query); err != nil {
 			fmt.Printf("Failed to query %s: %v\n", name, err)
 			return
 		}
 
-		data, err := indentJSON([]byte(query.Response))
+		cpus, err := query.CPUs()
 		if err != nil {
 			fmt.Printf("Failed to query %s: %v\n", name, err)
 			return
 		}
 
-		fmt.Printf("----%s----\n%s\n", name, data)
+		fmt.Printf("----%s----\n%s", name, formatCPUSlots(slots, cpus))
 	})
 }
 
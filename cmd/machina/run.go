@@ -7,7 +7,6 @@ import (
 	"os/exec"
 
 	"github.com/gentlemanautomaton/machina"
-	"github.com/gentlemanautomaton/machina/qemugen"
 )
 
 // RunCmd starts a QEMU virtual machine.
@@ -32,7 +31,11 @@ func (cmd RunCmd) Run(ctx context.Context) (err error) {
 		return fmt.Errorf("failed to build configuration for \"%s\": %v", cmd.Machine, err)
 	}
 
-	vm, err := qemugen.Build(machine, sys)
+	// Previously recorded PCI addresses are honored, but new ones are not
+	// recorded because the state directory is only writable by root and
+	// run is commonly used by unprivileged users. Addresses are recorded
+	// by generate when the machine is installed as a service.
+	vm, err := buildQEMU(machine, sys, false)
 	if err != nil {
 		return fmt.Errorf("failed to build configuration for \"%s\": %v", cmd.Machine, err)
 	}
@@ -44,11 +47,18 @@ func (cmd RunCmd) Run(ctx context.Context) (err error) {
 	defer func(err *error) {
 		for _, device := range composed.Devices {
 			if devErr := teardownDevice(device, sys); devErr != nil {
-				if *err != nil {
+				if *err == nil {
 					*err = devErr
 				}
 			}
 		}
+		for _, conn := range composed.Connections {
+			if connErr := teardownConnection(machine.Name, conn, sys); connErr != nil {
+				if *err == nil {
+					*err = connErr
+				}
+			}
+		}
 	}(&err)
 
 	args := vm.Options().Args()
@@ -3,9 +3,11 @@ package main
 import (
 	"context"
 	"errors"
+	"fmt"
 
 	"github.com/gentlemanautomaton/machina"
 	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+	"github.com/gentlemanautomaton/machina/filesystem/pcifs"
 )
 
 // TeardownCmd removes host resources that were previously prepared for a
@@ -29,21 +31,33 @@ func (cmd TeardownCmd) Run(ctx context.Context) error {
 }
 
 func teardownMachine(info machina.MachineInfo, definition machina.Definition, sys machina.System) error {
-	// TODO: Clean up as many devices and connections as possible, even if one hits an error
+	// Clean up as many devices and connections as possible, even if some
+	// of them hit an error.
+	var errs []error
 	for _, device := range definition.Devices {
 		if err := teardownDevice(device, sys); err != nil {
-			return err
+			errs = append(errs, err)
 		}
 	}
 	for _, conn := range definition.Connections {
-		if err := teardownConnection(conn, sys); err != nil {
-			return err
+		if err := teardownConnection(info.Name, conn, sys); err != nil {
+			errs = append(errs, err)
 		}
 	}
-	return nil
+	return errors.Join(errs...)
 }
 
 func teardownDevice(device machina.Device, sys machina.System) error {
+	// Passthrough devices are returned to their original host drivers.
+	if pdevs := sys.PassthroughDevices.WithClass(device.Class); len(pdevs) > 0 {
+		return teardownPassthroughDevices(device, pdevs)
+	}
+
+	// USB devices are released by QEMU when it exits.
+	if udevs := sys.USBDevices.WithClass(device.Class); len(udevs) > 0 {
+		return nil
+	}
+
 	// If a device ID has not been provided in the machina configuration
 	// we don't have any way of inspecting its condition.
 	if device.ID.IsZero() {
@@ -51,12 +65,19 @@ func teardownDevice(device machina.Device, sys machina.System) error {
 	}
 
 	// Check whether mediated devices are supported
-	if !mdevfs.Supported() {
+	bus := mdevfs.Local()
+	if !bus.Supported() {
 		return errors.New("mediated devices are not supported on the local system")
 	}
 
+	return teardownMediatedDevice(bus, device)
+}
+
+// teardownMediatedDevice removes the mediated device for device, if it
+// exists.
+func teardownMediatedDevice(bus mdevfs.System, device machina.Device) error {
 	// Check whether there is a mediated device with the device ID
-	dev := mdevfs.NewMediatedDevice(device.ID)
+	dev := bus.MediatedDevice(device.ID)
 	exists, err := dev.Exists()
 	if err != nil {
 		return err
@@ -73,6 +94,29 @@ func teardownDevice(device machina.Device, sys machina.System) error {
 	return nil
 }
 
-func teardownConnection(conn machina.Connection, sys machina.System) error {
+func teardownPassthroughDevices(device machina.Device, pdevs machina.PassthroughDeviceList) error {
+	for _, pdev := range pdevs {
+		host, err := pcifs.Locate(pdev)
+		if err != nil {
+			return fmt.Errorf("failed to locate passthrough device %s for device %s: %v", pdev, device.Name, err)
+		}
+		if exists, err := host.Exists(); err != nil || !exists {
+			continue
+		}
+		if err := host.ReleaseVFIO(); err != nil {
+			return fmt.Errorf("failed to restore the host driver for passthrough device %s of device %s: %w", host.Address(), device.Name, err)
+		}
+	}
+	return nil
+}
+
+func teardownConnection(machine machina.MachineName, conn machina.Connection, sys machina.System) error {
+	// Persistent taps are created by machina, so they must also be removed
+	// by machina.
+	if network := sys.Network[conn.Network]; network.PersistentTaps() {
+		if err := removeTap(machine, conn); err != nil {
+			return fmt.Errorf("connection %s.%s: %w", machine, conn.Name, err)
+		}
+	}
 	return nil
 }
@@ -0,0 +1,135 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qvm"
+	"github.com/gentlemanautomaton/machina/qemugen"
+	"github.com/gentlemanautomaton/machina/qmp/qmpcmd"
+)
+
+// USBCmd manages USB host devices passed through to running virtual
+// machines.
+type USBCmd struct {
+	Attach USBAttachCmd `kong:"cmd,help='Passes USB host devices through to running virtual machines.'"`
+	Detach USBDetachCmd `kong:"cmd,help='Removes USB host devices from running virtual machines.'"`
+}
+
+// USBAttachCmd adds the USB host devices claimed by machine devices to
+// running virtual machines.
+type USBAttachCmd struct {
+	Devices []string `kong:"arg,predictor=machines,help='Machine devices that claim USB host devices. Use [machine].[device].'"`
+}
+
+// Run executes the usb attach command.
+func (cmd USBAttachCmd) Run(ctx context.Context) error {
+	return hotplugUSBDevices(ctx, cmd.Devices, true)
+}
+
+// USBDetachCmd removes the USB host devices claimed by machine devices from
+// running virtual machines.
+type USBDetachCmd struct {
+	Devices []string `kong:"arg,predictor=machines,help='Machine devices that claim USB host devices. Use [machine].[device].'"`
+}
+
+// Run executes the usb detach command.
+func (cmd USBDetachCmd) Run(ctx context.Context) error {
+	return hotplugUSBDevices(ctx, cmd.Devices, false)
+}
+
+// hotplugUSBDevices adds or removes the USB host devices claimed by each of
+// the named machine devices through QMP.
+func hotplugUSBDevices(ctx context.Context, names []string, attach bool) error {
+	sys, err := LoadSystem()
+	if err != nil {
+		return fmt.Errorf("failed to load system configuration: %v", err)
+	}
+
+	var firstError error
+	for _, name := range names {
+		if err := hotplugUSBDevice(ctx, sys, name, attach); err != nil {
+			if firstError == nil {
+				firstError = err
+			}
+			fmt.Printf("%s: failed: %v\n", name, err)
+		}
+	}
+	return firstError
+}
+
+func hotplugUSBDevice(ctx context.Context, sys machina.System, name string, attach bool) error {
+	machineName, deviceName, ok := strings.Cut(name, ".")
+	if !ok || deviceName == "" {
+		return fmt.Errorf("a device must be specified as [machine].[device]")
+	}
+
+	machine, err := LoadMachine(machina.MachineName(machineName))
+	if err != nil {
+		return fmt.Errorf("failed to load machine configuration: %v", err)
+	}
+	definition, err := machina.Build(machine, sys)
+	if err != nil {
+		return fmt.Errorf("failed to build configuration: %v", err)
+	}
+
+	var (
+		device machina.Device
+		found  bool
+	)
+	for _, d := range definition.Devices {
+		if string(d.Name) == deviceName {
+			device, found = d, true
+			break
+		}
+	}
+	if !found {
+		return fmt.Errorf("the machine does not have a device named \"%s\"", deviceName)
+	}
+
+	udevs := sys.USBDevices.WithClass(device.Class)
+	if len(udevs) == 0 {
+		return fmt.Errorf("device class %s is not supplied by any USB devices", device.Class)
+	}
+
+	// Generate the machine's QEMU devices so that hotplugged devices have
+	// the same properties that they would have when the machine starts.
+	var vm qvm.Definition
+	if attach {
+		if vm, err = buildQEMU(machine, sys, false); err != nil {
+			return fmt.Errorf("failed to generate QEMU configuration: %v", err)
+		}
+	}
+
+	client, err := connectToMachineQMP(ComposedMachine{MachineInfo: machine.Info(), Definition: definition})
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+
+	for i, udev := range udevs {
+		id := qemugen.USBHostDeviceID(device, i)
+		if !attach {
+			if err := client.Execute(ctx, qmpcmd.DeviceDel{ID: string(id)}); err != nil {
+				return fmt.Errorf("failed to detach USB device %s: %w", udev, err)
+			}
+			fmt.Printf("%s: detached USB device %s\n", name, udev)
+			continue
+		}
+
+		generated, found := findGeneratedDevice(&vm.Topology, id)
+		if !found {
+			return fmt.Errorf("the QEMU configuration for USB device %s could not be found", udev)
+		}
+
+		// Let QEMU choose a free port on the controller.
+		if err := client.Execute(ctx, deviceAddCommand(generated, "port")); err != nil {
+			return fmt.Errorf("failed to attach USB device %s: %w", udev, err)
+		}
+		fmt.Printf("%s: attached USB device %s\n", name, udev)
+	}
+
+	return nil
+}
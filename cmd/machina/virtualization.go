@@ -0,0 +1,56 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+// checkVirtualization returns an error if the host's KVM modules don't
+// support the nested virtualization or secure encrypted virtualization
+// features requested by a machine.
+func checkVirtualization(fsys fs.FS, attrs machina.Attributes) error {
+	if attrs.Nested.Enabled {
+		var module string
+		switch {
+		case sysfs.ModuleLoaded(fsys, "kvm_intel"):
+			module = "kvm_intel"
+		case sysfs.ModuleLoaded(fsys, "kvm_amd"):
+			module = "kvm_amd"
+		default:
+			return errors.New("nested virtualization requires the kvm_intel or kvm_amd module to be loaded")
+		}
+		if err := checkModuleFeature(fsys, module, "nested", "nested virtualization"); err != nil {
+			return err
+		}
+	}
+
+	if attrs.SEV.Enabled {
+		if err := checkModuleFeature(fsys, "kvm_amd", "sev", "secure encrypted virtualization"); err != nil {
+			return err
+		}
+		if attrs.SEV.ES {
+			if err := checkModuleFeature(fsys, "kvm_amd", "sev_es", "SEV-ES"); err != nil {
+				return err
+			}
+		}
+	}
+
+	return nil
+}
+
+// checkModuleFeature returns an error if a boolean parameter of a kernel
+// module is not enabled.
+func checkModuleFeature(fsys fs.FS, module, param, feature string) error {
+	enabled, err := sysfs.ModuleFeatureEnabled(fsys, module, param)
+	if err != nil {
+		return fmt.Errorf("failed to determine whether %s is enabled in the %s module: %w", feature, module, err)
+	}
+	if !enabled {
+		return fmt.Errorf("%s is not enabled in the %s module; load it with %s=1", feature, module, param)
+	}
+	return nil
+}
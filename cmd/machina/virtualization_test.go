@@ -0,0 +1,46 @@
+package main
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/gentlemanautomaton/machina"
+)
+
+func TestCheckVirtualization(t *testing.T) {
+	intel := fstest.MapFS{
+		"module/kvm_intel/parameters/nested": {Data: []byte("N\n")},
+	}
+	amd := fstest.MapFS{
+		"module/kvm_amd/parameters/nested": {Data: []byte("1\n")},
+		"module/kvm_amd/parameters/sev":    {Data: []byte("Y\n")},
+		"module/kvm_amd/parameters/sev_es": {Data: []byte("N\n")},
+	}
+
+	nested := machina.Attributes{Nested: machina.Nested{Enabled: true}}
+	sev := machina.Attributes{SEV: machina.SEV{Enabled: true}}
+	sevES := machina.Attributes{SEV: machina.SEV{Enabled: true, ES: true}}
+
+	tests := []struct {
+		Name  string
+		FS    fstest.MapFS
+		Attrs machina.Attributes
+		OK    bool
+	}{
+		{"nothing requested", fstest.MapFS{}, machina.Attributes{}, true},
+		{"intel nested disabled", intel, nested, false},
+		{"amd nested enabled", amd, nested, true},
+		{"no kvm module", fstest.MapFS{}, nested, false},
+		{"amd sev", amd, sev, true},
+		{"amd sev-es disabled", amd, sevES, false},
+		{"intel sev", intel, sev, false},
+	}
+	for _, test := range tests {
+		err := checkVirtualization(test.FS, test.Attrs)
+		if test.OK && err != nil {
+			t.Errorf("%s: unexpected error: %v", test.Name, err)
+		} else if !test.OK && err == nil {
+			t.Errorf("%s: expected an error", test.Name)
+		}
+	}
+}
@@ -3,6 +3,7 @@ package machina
 import (
 	"encoding/base64"
 	"fmt"
+	"strings"
 
 	"github.com/gentlemanautomaton/machina/summary"
 	"golang.org/x/crypto/sha3"
@@ -12,16 +13,26 @@ import (
 type ConnectionName string
 
 // Connection describes a network connection.
+//
+// Forwards are only supported by connections to user-mode networks.
 type Connection struct {
-	Name    ConnectionName `json:"name"`
-	Network NetworkName    `json:"network"`
-	IP      string         `json:"ip"`
-	MAC     string         `json:"mac"`
+	Name     ConnectionName `json:"name"`
+	Network  NetworkName    `json:"network"`
+	IP       string         `json:"ip"`
+	MAC      string         `json:"mac"`
+	Forwards []PortForward  `json:"forwards,omitempty"`
 }
 
 // String returns a string representation of the network connection
 // configuration.
 func (c Connection) String() string {
+	if len(c.Forwards) > 0 {
+		forwards := make([]string, 0, len(c.Forwards))
+		for _, forward := range c.Forwards {
+			forwards = append(forwards, forward.String())
+		}
+		return fmt.Sprintf("%s: %s (ip: %s, mac: %s, forwards: %s)", c.Name, c.Network, c.IP, c.MAC, strings.Join(forwards, ", "))
+	}
 	return fmt.Sprintf("%s: %s (ip: %s, mac: %s)", c.Name, c.Network, c.IP, c.MAC)
 }
 
@@ -56,6 +67,62 @@ func MergeConnections(conns ...Connection) []Connection {
 	return out
 }
 
+// PortProtocol identifies the transport protocol of a forwarded port.
+type PortProtocol string
+
+// Port protocols.
+const (
+	TCP = PortProtocol("tcp")
+	UDP = PortProtocol("udp")
+)
+
+// PortForward describes a port on the host that is forwarded to a port
+// on the guest by a user-mode network.
+//
+// If a protocol is not specified, TCP is assumed. If a host address is not
+// specified, the port will be bound to all host addresses. If a guest
+// address is not specified, the guest's address on the user-mode network is
+// used.
+type PortForward struct {
+	Protocol  PortProtocol `json:"protocol,omitempty"`
+	HostAddr  string       `json:"host-addr,omitempty"`
+	HostPort  int          `json:"host-port"`
+	GuestAddr string       `json:"guest-addr,omitempty"`
+	GuestPort int          `json:"guest-port"`
+}
+
+// EffectiveProtocol returns the protocol of the forwarded port. If a
+// protocol has not been specified it returns TCP.
+func (f PortForward) EffectiveProtocol() PortProtocol {
+	if f.Protocol == "" {
+		return TCP
+	}
+	return f.Protocol
+}
+
+// Validate returns an error if the port forward is invalid.
+func (f PortForward) Validate() error {
+	const min, max = 1, 65535
+	switch protocol := f.EffectiveProtocol(); protocol {
+	case TCP, UDP:
+	default:
+		return fmt.Errorf("port forward %s: unsupported protocol \"%s\"", f, protocol)
+	}
+	if f.HostPort < min || f.HostPort > max {
+		return fmt.Errorf("port forward %s: invalid host port number %d", f, f.HostPort)
+	}
+	if f.GuestPort < min || f.GuestPort > max {
+		return fmt.Errorf("port forward %s: invalid guest port number %d", f, f.GuestPort)
+	}
+	return nil
+}
+
+// String returns a string representation of the port forward in the form
+// [protocol]:[host-addr]:[host-port]->[guest-addr]:[guest-port].
+func (f PortForward) String() string {
+	return fmt.Sprintf("%s:%s:%d->%s:%d", f.EffectiveProtocol(), f.HostAddr, f.HostPort, f.GuestAddr, f.GuestPort)
+}
+
 // MachineConnection describes a connection for a machine.
 type MachineConnection struct {
 	Machine MachineName
@@ -0,0 +1,163 @@
+package mdevfs_test
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs/mdevtest"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+var _ sysfs.FS = (*mdevtest.Bus)(nil)
+
+const gpu = machina.DeviceAddress("0000:3b:00.0")
+
+func newBus() *mdevtest.Bus {
+	bus := mdevtest.NewBus()
+	bus.AddPhysicalDevice(gpu,
+		mdevtest.Type{ID: "nvidia-256", Name: "GRID T4-2Q", MaxInstances: 2, Placements: []machina.MediatedDevicePlacementID{0, 2}},
+		mdevtest.Type{ID: "nvidia-257", Name: "GRID T4-4Q", MaxInstances: 1},
+	)
+	return bus
+}
+
+func mustParseID(t *testing.T, s string) machina.DeviceID {
+	t.Helper()
+	var id machina.DeviceID
+	if err := id.UnmarshalText([]byte(s)); err != nil {
+		t.Fatal(err)
+	}
+	return id
+}
+
+func TestTypes(t *testing.T) {
+	system := mdevfs.New(newBus())
+	if !system.Supported() {
+		t.Fatal("mediated devices are not supported by the fake bus")
+	}
+
+	parent := system.PhysicalDevice(gpu)
+	if exists, err := parent.Exists(); err != nil || !exists {
+		t.Fatalf("physical device %s does not exist: %v", gpu, err)
+	}
+	if exists, _ := system.PhysicalDevice("0000:00:00.0").Exists(); exists {
+		t.Errorf("unexpected physical device 0000:00:00.0")
+	}
+
+	types, err := parent.Types()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(types) != 2 {
+		t.Fatalf("unexpected number of types: %d (want 2)", len(types))
+	}
+
+	typ, found := types.FindName("GRID T4-2Q")
+	if !found {
+		t.Fatal("failed to find type \"GRID T4-2Q\"")
+	}
+	if got, want := typ.ID(), "nvidia-256"; got != want {
+		t.Errorf("unexpected type ID \"%s\" (want \"%s\")", got, want)
+	}
+	if got, want := typ.Path(), "/sys/bus/pci/devices/0000:3b:00.0/mdev_supported_types/nvidia-256"; got != want {
+		t.Errorf("unexpected type path \"%s\" (want \"%s\")", got, want)
+	}
+	if avail, err := typ.AvailableInstances(); err != nil || avail != 2 {
+		t.Errorf("unexpected available instances %d (want 2): %v", avail, err)
+	}
+}
+
+func TestCreateAndRemove(t *testing.T) {
+	bus := newBus()
+	system := mdevfs.New(bus)
+
+	types, err := system.PhysicalDevice(gpu).Types()
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ, _ := types.FindName("GRID T4-2Q")
+
+	id1 := mustParseID(t, "79db70f1-92a5-4879-95f7-6325b66c1ff9")
+	id2 := mustParseID(t, "0c1b4ba4-2b1e-4e1a-9d3b-5e0a7f3f5d18")
+	id3 := mustParseID(t, "d2f0b7b4-8e39-4a9c-a7f6-3a9c1de2d8a1")
+
+	for _, id := range []machina.DeviceID{id1, id2} {
+		if err := typ.Create(id); err != nil {
+			t.Fatalf("failed to create %s: %v", id, err)
+		}
+		if exists, err := system.MediatedDevice(id).Exists(); err != nil || !exists {
+			t.Fatalf("mediated device %s was not created: %v", id, err)
+		}
+	}
+
+	if avail, _ := typ.AvailableInstances(); avail != 0 {
+		t.Errorf("unexpected available instances %d (want 0)", avail)
+	}
+	if err := typ.Create(id3); err == nil {
+		t.Errorf("created mediated device %s beyond the capacity of the type", id3)
+	}
+
+	devices, err := types.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(devices) != 2 {
+		t.Fatalf("unexpected number of devices: %d (want 2)", len(devices))
+	}
+	for _, dev := range devices {
+		if _, err := dev.ID(); err != nil {
+			t.Error(err)
+		}
+	}
+
+	if err := system.MediatedDevice(id1).Remove(); err != nil {
+		t.Fatal(err)
+	}
+	if exists, _ := system.MediatedDevice(id1).Exists(); exists {
+		t.Errorf("mediated device %s still exists after removal", id1)
+	}
+	if avail, _ := typ.AvailableInstances(); avail != 1 {
+		t.Errorf("unexpected available instances %d (want 1)", avail)
+	}
+}
+
+func TestPlacements(t *testing.T) {
+	bus := newBus()
+	system := mdevfs.New(bus)
+
+	types, err := system.PhysicalDevice(gpu).Types()
+	if err != nil {
+		t.Fatal(err)
+	}
+	typ, _ := types.FindName("GRID T4-2Q")
+
+	id := mustParseID(t, "79db70f1-92a5-4879-95f7-6325b66c1ff9")
+	if err := typ.Create(id); err != nil {
+		t.Fatal(err)
+	}
+
+	dev := system.MediatedDevice(id)
+	if _, err := dev.Placement(); !errors.Is(err, mdevfs.ErrNotApplicable) {
+		t.Errorf("unexpected placement error for new device: %v (want %v)", err, mdevfs.ErrNotApplicable)
+	}
+	if err := dev.ChangePlacement(2); err != nil {
+		t.Fatal(err)
+	}
+	if placement, err := dev.Placement(); err != nil || placement != 2 {
+		t.Errorf("unexpected placement %d (want 2): %v", placement, err)
+	}
+
+	devices, err := types.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	placements, err := devices.Placements()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !placements.Contains(2) || placements.Contains(0) {
+		t.Errorf("unexpected placements: %s", placements.List())
+	}
+}
@@ -0,0 +1,352 @@
+// Package mdevtest provides an in-memory fake of the sysfs mediated device
+// bus, which allows code that manages mediated devices to be tested without
+// access to mediated device hardware.
+package mdevtest
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"testing/fstest"
+
+	"github.com/gentlemanautomaton/machina"
+)
+
+// Type describes a mediated device type offered by a fake physical device.
+type Type struct {
+	// ID is the identifier of the type, such as "nvidia-256".
+	ID string
+
+	// Name is the friendly name of the type, such as "GRID T4-2Q".
+	Name string
+
+	// Description is the friendly description of the type.
+	Description string
+
+	// MaxInstances is the number of mediated devices of this type that
+	// can be created on the physical device.
+	MaxInstances int
+
+	// Placements is the set of placement IDs offered by the type. If it is
+	// empty, devices of this type will not have a placement ID and the type
+	// will not have NVIDIA's nvidia/creatable_placements attribute.
+	Placements []machina.MediatedDevicePlacementID
+}
+
+// Device describes a mediated device that exists on a fake bus.
+type Device struct {
+	ID        machina.DeviceID
+	Parent    machina.DeviceAddress
+	Type      string
+	Placement machina.MediatedDevicePlacementID // -1 if not applicable
+}
+
+type parent struct {
+	address   machina.DeviceAddress
+	types     []Type
+	localCPUs string
+	createErr error
+}
+
+// Bus is an in-memory fake of the sysfs mediated device bus. It implements
+// the sysfs.FS interface and responds to writes in the same way that the
+// kernel does.
+//
+// It is safe for concurrent use.
+type Bus struct {
+	mu      sync.Mutex
+	parents []parent
+	devices []Device
+}
+
+// NewBus returns a new fake mediated device bus without any physical
+// devices.
+func NewBus() *Bus {
+	return &Bus{}
+}
+
+// AddPhysicalDevice adds a physical device to the bus that offers the given
+// mediated device types.
+func (b *Bus) AddPhysicalDevice(address machina.DeviceAddress, types ...Type) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.parents = append(b.parents, parent{address: address, types: types})
+}
+
+// SetLocalCPUs sets the list of CPUs that are local to the NUMA node of the
+// physical device with the given address, such as "0-7".
+func (b *Bus) SetLocalCPUs(address machina.DeviceAddress, cpus string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.parents {
+		if b.parents[i].address == address {
+			b.parents[i].localCPUs = cpus
+		}
+	}
+}
+
+// RejectCreate causes attempts to create mediated devices on the physical
+// device with the given address to fail with err, as though its vendor
+// driver refused them. A nil err allows creation again.
+func (b *Bus) RejectCreate(address machina.DeviceAddress, err error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for i := range b.parents {
+		if b.parents[i].address == address {
+			b.parents[i].createErr = err
+		}
+	}
+}
+
+// AddDevice adds a mediated device to the bus as though it was created
+// elsewhere. It returns an error if the device could not be created.
+func (b *Bus) AddDevice(address machina.DeviceAddress, typ string, id machina.DeviceID) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.create(address, typ, id)
+}
+
+// Devices returns the mediated devices that exist on the bus, in order of
+// creation.
+func (b *Bus) Devices() []Device {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return slices.Clone(b.devices)
+}
+
+// Device returns the mediated device with the given ID, if it exists.
+func (b *Bus) Device(id machina.DeviceID) (dev Device, ok bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if i := b.deviceIndex(id); i >= 0 {
+		return b.devices[i], true
+	}
+	return Device{}, false
+}
+
+// Open opens the named file from a snapshot of the bus.
+func (b *Bus) Open(name string) (fs.File, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.snapshot().Open(name)
+}
+
+// ReadLink returns the destination of the named symbolic link. The only
+// symbolic links on the bus are the device links within each type.
+func (b *Bus) ReadLink(name string) (string, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if _, _, rest, ok := b.cutType(name); ok {
+		if dir, id, ok := strings.Cut(rest, "/"); ok && dir == "devices" {
+			if b.deviceIndexString(id) >= 0 {
+				return "../../../../../../mdev/devices/" + id, nil
+			}
+		}
+	}
+
+	if _, err := fs.Stat(b.snapshot(), name); err != nil {
+		return "", err
+	}
+	return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+}
+
+// WriteFile writes data to the named file on the bus.
+func (b *Bus) WriteFile(name string, data []byte) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	fail := func(err error) error {
+		return &fs.PathError{Op: "write", Path: name, Err: err}
+	}
+
+	value := strings.TrimSpace(string(data))
+
+	// Creation of mediated devices.
+	if address, typ, rest, ok := b.cutType(name); ok && rest == "create" {
+		var id machina.DeviceID
+		if err := id.UnmarshalText([]byte(value)); err != nil {
+			return fail(err)
+		}
+		if err := b.create(address, typ, id); err != nil {
+			return fail(err)
+		}
+		return nil
+	}
+
+	// Mediated device attributes.
+	if rest, ok := strings.CutPrefix(name, "bus/mdev/devices/"); ok {
+		id, attr, _ := strings.Cut(rest, "/")
+		i := b.deviceIndexString(id)
+		if i < 0 {
+			return fail(fs.ErrNotExist)
+		}
+		switch attr {
+		case "remove":
+			if value != "1" {
+				return fail(fs.ErrInvalid)
+			}
+			b.devices = slices.Delete(b.devices, i, i+1)
+			return nil
+		case "nvidia/placement_id":
+			placement, err := strconv.Atoi(value)
+			if err != nil {
+				return fail(fs.ErrInvalid)
+			}
+			if err := b.place(i, machina.MediatedDevicePlacementID(placement)); err != nil {
+				return fail(err)
+			}
+			return nil
+		}
+	}
+
+	if _, err := fs.Stat(b.snapshot(), name); err != nil {
+		return err
+	}
+	return fail(fs.ErrPermission)
+}
+
+func (b *Bus) create(address machina.DeviceAddress, typ string, id machina.DeviceID) error {
+	p, t, ok := b.findType(address, typ)
+	if !ok {
+		return fmt.Errorf("mediated device type %s is not offered by %s", typ, address)
+	}
+	if b.deviceIndex(id) >= 0 {
+		return fmt.Errorf("mediated device %s already exists", id)
+	}
+	if b.available(p, t) <= 0 {
+		return errors.New("no instances of the mediated device type are available")
+	}
+	if p.createErr != nil {
+		return p.createErr
+	}
+	b.devices = append(b.devices, Device{
+		ID:        id,
+		Parent:    p.address,
+		Type:      t.ID,
+		Placement: -1,
+	})
+	return nil
+}
+
+func (b *Bus) place(i int, placement machina.MediatedDevicePlacementID) error {
+	dev := b.devices[i]
+	_, t, _ := b.findType(dev.Parent, dev.Type)
+	if !slices.Contains(t.Placements, placement) {
+		return fmt.Errorf("placement ID %d is not offered by mediated device type %s", placement, t.ID)
+	}
+	for j, other := range b.devices {
+		if j != i && other.Parent == dev.Parent && other.Placement == placement {
+			return fmt.Errorf("placement ID %d is already in use by mediated device %s", placement, other.ID)
+		}
+	}
+	b.devices[i].Placement = placement
+	return nil
+}
+
+func (b *Bus) available(p parent, t Type) int {
+	count := 0
+	for _, dev := range b.devices {
+		if dev.Parent == p.address && dev.Type == t.ID {
+			count++
+		}
+	}
+	return t.MaxInstances - count
+}
+
+func (b *Bus) findType(address machina.DeviceAddress, typ string) (parent, Type, bool) {
+	for _, p := range b.parents {
+		if p.address != address {
+			continue
+		}
+		for _, t := range p.types {
+			if t.ID == typ {
+				return p, t, true
+			}
+		}
+	}
+	return parent{}, Type{}, false
+}
+
+// cutType splits a name within the supported types of a physical device
+// into its address, type and remaining path.
+func (b *Bus) cutType(name string) (address machina.DeviceAddress, typ, rest string, ok bool) {
+	rest, ok = strings.CutPrefix(name, "bus/pci/devices/")
+	if !ok {
+		return "", "", "", false
+	}
+	parts := strings.SplitN(rest, "/", 4)
+	if len(parts) != 4 || parts[1] != "mdev_supported_types" {
+		return "", "", "", false
+	}
+	return machina.DeviceAddress(parts[0]), parts[2], parts[3], true
+}
+
+func (b *Bus) deviceIndex(id machina.DeviceID) int {
+	return slices.IndexFunc(b.devices, func(dev Device) bool {
+		return dev.ID == id
+	})
+}
+
+func (b *Bus) deviceIndexString(id string) int {
+	return slices.IndexFunc(b.devices, func(dev Device) bool {
+		return dev.ID.String() == id
+	})
+}
+
+// snapshot returns the current contents of the bus as a file system.
+func (b *Bus) snapshot() fstest.MapFS {
+	dir := &fstest.MapFile{Mode: fs.ModeDir | 0755}
+	file := func(value string) *fstest.MapFile {
+		return &fstest.MapFile{Data: []byte(value + "\n"), Mode: 0644}
+	}
+	attr := &fstest.MapFile{Mode: 0200}
+
+	fsys := fstest.MapFS{
+		"bus/pci/devices":  dir,
+		"bus/mdev/devices": dir,
+	}
+
+	for _, p := range b.parents {
+		if p.localCPUs != "" {
+			fsys[path.Join("bus/pci/devices", string(p.address), "local_cpulist")] = file(p.localCPUs)
+		}
+		for _, t := range p.types {
+			typeDir := path.Join("bus/pci/devices", string(p.address), "mdev_supported_types", t.ID)
+			fsys[typeDir+"/name"] = file(t.Name)
+			fsys[typeDir+"/description"] = file(t.Description)
+			fsys[typeDir+"/available_instances"] = file(strconv.Itoa(b.available(p, t)))
+			fsys[typeDir+"/create"] = attr
+			fsys[typeDir+"/devices"] = dir
+			if len(t.Placements) > 0 {
+				ids := make([]string, len(t.Placements))
+				for i, id := range t.Placements {
+					ids[i] = strconv.Itoa(int(id))
+				}
+				fsys[typeDir+"/nvidia/creatable_placements"] = file(strings.Join(ids, " "))
+			}
+		}
+	}
+
+	for _, dev := range b.devices {
+		typeDir := path.Join("bus/pci/devices", string(dev.Parent), "mdev_supported_types", dev.Type)
+		fsys[typeDir+"/devices/"+dev.ID.String()] = &fstest.MapFile{Mode: fs.ModeSymlink | 0777}
+
+		devDir := path.Join("bus/mdev/devices", dev.ID.String())
+		fsys[devDir+"/remove"] = attr
+		if _, t, _ := b.findType(dev.Parent, dev.Type); len(t.Placements) > 0 {
+			if dev.Placement < 0 {
+				fsys[devDir+"/nvidia/placement_id"] = file("Not Applicable")
+			} else {
+				fsys[devDir+"/nvidia/placement_id"] = file(strconv.Itoa(int(dev.Placement)))
+			}
+		}
+	}
+
+	return fsys
+}
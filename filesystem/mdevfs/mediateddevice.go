@@ -3,12 +3,13 @@ package mdevfs
 import (
 	"errors"
 	"fmt"
-	"os"
+	"io/fs"
 	"path"
 	"strconv"
 	"strings"
 
 	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
 )
 
 // ErrNotApplicable is returned when querying some properties of mediated
@@ -36,38 +37,40 @@ func (list MediatedDeviceList) Placements() (machina.MediatedDevicePlacementSet,
 	return placements, nil
 }
 
-// MediatedDevice provides access to a mediated device through the local
-// file system.
+// MediatedDevice provides access to a mediated device through a sysfs file
+// system.
 type MediatedDevice struct {
-	path string
-}
-
-// NewMediatedDevice prepares access to a mediated device through the local
-// file system. It expects the device to be present in /sys/bus/mdev/devices.
-func NewMediatedDevice(id machina.DeviceID) MediatedDevice {
-	return MediatedDevice{
-		path: path.Join("/sys/bus/mdev/devices/", id.String()),
-	}
+	fsys sysfs.FS
+	name string
 }
 
 // Exists returns true if the mediated device already exists.
 func (mdev MediatedDevice) Exists() (bool, error) {
-	fi, err := os.Stat(mdev.path)
+	fi, err := fs.Stat(mdev.fsys, mdev.name)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return false, nil
 		}
 		return false, err
 	}
 	if !fi.IsDir() {
-		return false, fmt.Errorf("the sysfs path \"%s\" is not a directory", mdev.path)
+		return false, fmt.Errorf("the sysfs path \"%s\" is not a directory", mdev.Path())
 	}
 	return true, nil
 }
 
+// ID returns the device ID of the mediated device.
+func (mdev MediatedDevice) ID() (machina.DeviceID, error) {
+	var id machina.DeviceID
+	if err := id.UnmarshalText([]byte(path.Base(mdev.name))); err != nil {
+		return machina.DeviceID{}, fmt.Errorf("the mediated device \"%s\" does not have a valid device ID: %w", mdev.Path(), err)
+	}
+	return id, nil
+}
+
 // Path returns the sysfs path for the mediated device.
 func (mdev MediatedDevice) Path() string {
-	return mdev.path
+	return string(sysfs.AbsPath(mdev.name))
 }
 
 // Placement returns the placement ID for the mediated device.
@@ -75,8 +78,7 @@ func (mdev MediatedDevice) Path() string {
 // It may return [ErrNotApplicable] if the device exists but is not currently
 // in use.
 func (mdev MediatedDevice) Placement() (machina.MediatedDevicePlacementID, error) {
-	sysfs := path.Join(mdev.path, "nvidia/placement_id")
-	data, err := readSystemFile(sysfs)
+	data, err := sysfs.ReadFile(mdev.fsys, path.Join(mdev.name, "nvidia/placement_id"))
 	if err != nil {
 		return 0, fmt.Errorf("failed to read mediated device placement ID: %w", err)
 	}
@@ -94,13 +96,10 @@ func (mdev MediatedDevice) Placement() (machina.MediatedDevicePlacementID, error
 //
 // If the mediated device is in use, an error will be returned.
 func (mdev MediatedDevice) ChangePlacement(id machina.MediatedDevicePlacementID) error {
-	sysfs := path.Join(mdev.path, "nvidia/placement_id")
-	data := strconv.Itoa(int(id))
-	return writeToSystemFile(sysfs, data)
+	return sysfs.WriteFile(mdev.fsys, path.Join(mdev.name, "nvidia/placement_id"), strconv.Itoa(int(id)))
 }
 
 // Remove attempts to remove the mediated device from the system.
 func (mdev MediatedDevice) Remove() error {
-	sysfs := path.Join(mdev.path, "remove")
-	return writeToSystemFile(sysfs, "1")
+	return sysfs.WriteFile(mdev.fsys, path.Join(mdev.name, "remove"), "1")
 }
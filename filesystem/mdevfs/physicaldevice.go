@@ -4,28 +4,19 @@ import (
 	"errors"
 	"fmt"
 	"io/fs"
-	"os"
 	"path"
 	"strings"
 
 	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
 )
 
-// PhysicalDevice provides access to a physical PCI device through the local
+// PhysicalDevice provides access to a physical PCI device through a sysfs
 // file system.
 type PhysicalDevice struct {
+	fsys    sysfs.FS
 	address machina.DeviceAddress
-	path    string
-}
-
-// NewPhysicalDevice returns a physical device accessor that will access a
-// PCI device with the given address through the local file system. It
-// expects the device to be present in /sys/bus/pci/devices.
-func NewPhysicalDevice(address machina.DeviceAddress) PhysicalDevice {
-	return PhysicalDevice{
-		address: address,
-		path:    path.Join("/sys/bus/pci/devices/", string(address)),
-	}
+	name    string
 }
 
 // Address returns the PCI address for the physical device.
@@ -35,7 +26,7 @@ func (pdev PhysicalDevice) Address() machina.DeviceAddress {
 
 // Path returns the sysfs path for the physical device.
 func (pdev PhysicalDevice) Path() string {
-	return pdev.path
+	return string(sysfs.AbsPath(pdev.name))
 }
 
 // Exists returns true if the sysfs path for the device exists.
@@ -44,15 +35,15 @@ func (pdev PhysicalDevice) Exists() (bool, error) {
 		return false, errors.New("the phyiscal device does not have an address")
 	}
 
-	fi, err := os.Stat(pdev.path)
+	fi, err := fs.Stat(pdev.fsys, pdev.name)
 	if err != nil {
-		if os.IsNotExist(err) {
+		if errors.Is(err, fs.ErrNotExist) {
 			return false, nil
 		}
 		return false, err
 	}
 	if !fi.IsDir() {
-		return false, fmt.Errorf("the sysfs path \"%s\" is not a directory", pdev.path)
+		return false, fmt.Errorf("the sysfs path \"%s\" is not a directory", pdev.Path())
 	}
 	return true, nil
 }
@@ -60,9 +51,7 @@ func (pdev PhysicalDevice) Exists() (bool, error) {
 // Types returns the set of mediated device types that are supported by the
 // physical device.
 func (pdev PhysicalDevice) Types() (types TypeList, err error) {
-	sysfs := os.DirFS(pdev.path)
-
-	dirents, err := fs.ReadDir(sysfs, "mdev_supported_types")
+	dirents, err := fs.ReadDir(pdev.fsys, path.Join(pdev.name, "mdev_supported_types"))
 	if err != nil {
 		return nil, err
 	}
@@ -73,25 +62,25 @@ func (pdev PhysicalDevice) Types() (types TypeList, err error) {
 		}
 
 		typ := Type{
-			path: path.Join(pdev.path, "mdev_supported_types", dirent.Name()),
+			fsys: pdev.fsys,
+			name: path.Join(pdev.name, "mdev_supported_types", dirent.Name()),
 			typ:  dirent.Name(),
 		}
 
 		// Friendly name
-		typefs := os.DirFS(typ.path)
-		name, err := fs.ReadFile(typefs, "name")
+		name, err := fs.ReadFile(pdev.fsys, path.Join(typ.name, "name"))
 		if err != nil {
-			if !os.IsNotExist(err) {
+			if !errors.Is(err, fs.ErrNotExist) {
 				return nil, err
 			}
 		} else {
-			typ.name = strings.TrimSpace(string(name))
+			typ.friendlyName = strings.TrimSpace(string(name))
 		}
 
 		// Friendly description
-		desc, err := fs.ReadFile(typefs, "description")
+		desc, err := fs.ReadFile(pdev.fsys, path.Join(typ.name, "description"))
 		if err != nil {
-			if !os.IsNotExist(err) {
+			if !errors.Is(err, fs.ErrNotExist) {
 				return nil, err
 			}
 		} else {
@@ -103,3 +92,17 @@ func (pdev PhysicalDevice) Types() (types TypeList, err error) {
 
 	return types, nil
 }
+
+// LocalCPUs returns the CPUs that are local to the NUMA node of the physical
+// device. It returns an empty list if the system does not report NUMA
+// locality for the device.
+func (pdev PhysicalDevice) LocalCPUs() (sysfs.CPUList, error) {
+	value, err := sysfs.ReadFile(pdev.fsys, path.Join(pdev.name, "local_cpulist"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	return sysfs.ParseCPUList(value)
+}
@@ -1,49 +1,73 @@
 package mdevfs
 
 import (
-	"fmt"
-	"os"
-	"strings"
+	"io/fs"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
 )
 
-// Supported returns true if the local system supports mediated devices.
+// System provides access to mediated devices through a sysfs file system.
+type System struct {
+	fsys sysfs.FS
+}
+
+// New returns a System that accesses mediated devices through fsys, which
+// must be rooted at the sysfs mount point.
+func New(fsys sysfs.FS) System {
+	return System{fsys: fsys}
+}
+
+// Local returns a System that accesses mediated devices through the sysfs
+// file system of the local system.
+func Local() System {
+	return New(sysfs.Local())
+}
+
+// Supported returns true if the system supports mediated devices.
 //
 // TODO: Do more than just checking for the presence of the PCI bus.
-func Supported() bool {
-	if fi, err := os.Stat("/sys/bus/pci/devices/"); err != nil || !fi.IsDir() {
+func (s System) Supported() bool {
+	if fi, err := fs.Stat(s.fsys, "bus/pci/devices"); err != nil || !fi.IsDir() {
 		return false
 	}
 	return true
 }
 
-func readSystemFile(sysfsPath string) (string, error) {
-	if !strings.HasPrefix(sysfsPath, "/sys/") {
-		return "", fmt.Errorf("invalid system file path: %s", sysfsPath)
+// MediatedDevice prepares access to a mediated device with the given ID. It
+// expects the device to be present in bus/mdev/devices.
+func (s System) MediatedDevice(id machina.DeviceID) MediatedDevice {
+	return MediatedDevice{
+		fsys: s.fsys,
+		name: "bus/mdev/devices/" + id.String(),
 	}
-
-	data, err := os.ReadFile(sysfsPath)
-	if err != nil {
-		return "", err
-	}
-
-	return strings.TrimSuffix(string(data), "\n"), nil
 }
 
-func writeToSystemFile(sysfsPath, value string) error {
-	if !strings.HasPrefix(sysfsPath, "/sys/") {
-		return fmt.Errorf("invalid system file path: %s", sysfsPath)
+// PhysicalDevice returns a physical device accessor that will access a
+// PCI device with the given address. It expects the device to be present in
+// bus/pci/devices.
+func (s System) PhysicalDevice(address machina.DeviceAddress) PhysicalDevice {
+	return PhysicalDevice{
+		fsys:    s.fsys,
+		address: address,
+		name:    "bus/pci/devices/" + string(address),
 	}
+}
 
-	file, err := os.OpenFile(sysfsPath, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
+// Supported returns true if the local system supports mediated devices.
+func Supported() bool {
+	return Local().Supported()
+}
 
-	data := []byte(value)
-	if _, err := file.Write(data); err != nil {
-		return err
-	}
+// NewMediatedDevice prepares access to a mediated device through the local
+// file system. It expects the device to be present in /sys/bus/mdev/devices.
+func NewMediatedDevice(id machina.DeviceID) MediatedDevice {
+	return Local().MediatedDevice(id)
+}
 
-	return nil
+// NewPhysicalDevice returns a physical device accessor that will access a
+// PCI device with the given address through the local file system. It
+// expects the device to be present in /sys/bus/pci/devices.
+func NewPhysicalDevice(address machina.DeviceAddress) PhysicalDevice {
+	return Local().PhysicalDevice(address)
 }
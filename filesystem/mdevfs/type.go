@@ -1,13 +1,15 @@
 package mdevfs
 
 import (
+	"errors"
 	"fmt"
 	"io/fs"
-	"os"
 	"path"
 	"strconv"
+	"strings"
 
 	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
 )
 
 // TypeList holds a set of mediated device types.
@@ -41,15 +43,16 @@ func (list TypeList) Devices() (MediatedDeviceList, error) {
 // Type describes a supported type offered by a mediated device
 // on the local system.
 type Type struct {
-	path        string
-	typ         string
-	name        string
-	description string
+	fsys         sysfs.FS
+	name         string
+	typ          string
+	friendlyName string
+	description  string
 }
 
 // Path returns the sysfs path for the supported type on the local system.
 func (t Type) Path() string {
-	return t.path
+	return string(sysfs.AbsPath(t.name))
 }
 
 // ID returns the supported type identifier.
@@ -59,7 +62,7 @@ func (t Type) ID() string {
 
 // Name returns the name of the supported type, which is optional.
 func (t Type) Name() string {
-	return t.name
+	return t.friendlyName
 }
 
 // Description returns the description of the supported type, which is
@@ -68,17 +71,29 @@ func (t Type) Description() string {
 	return t.description
 }
 
+// HasAttribute returns true if the supported type has a sysfs attribute
+// with the given name, which is relative to the type's directory. It is
+// typically used to discover vendor-specific features.
+func (t Type) HasAttribute(name string) (bool, error) {
+	if _, err := fs.Stat(t.fsys, path.Join(t.name, name)); err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
 // AvailableInstances returns the number of instances currently available for
 // the supported type.
 //
 // The value is queried at the of the function call, and is not cached.
 func (t Type) AvailableInstances() (int, error) {
-	typefs := os.DirFS(t.path)
-	avail, err := fs.ReadFile(typefs, "available_instances")
+	avail, err := fs.ReadFile(t.fsys, path.Join(t.name, "available_instances"))
 	if err != nil {
 		return 0, err
 	}
-	return strconv.Atoi(string(avail))
+	return strconv.Atoi(strings.TrimSpace(string(avail)))
 }
 
 // Devices returns the set of devices that have been created with the
@@ -86,36 +101,21 @@ func (t Type) AvailableInstances() (int, error) {
 //
 // The value is queried at the of the function call, and is not cached.
 func (t Type) Devices() ([]MediatedDevice, error) {
-	typefs := os.DirFS(t.path)
-	dirents, err := fs.ReadDir(typefs, "devices")
+	dirents, err := fs.ReadDir(t.fsys, path.Join(t.name, "devices"))
 	if err != nil {
 		return nil, err
 	}
 
 	devices := make([]MediatedDevice, 0, len(dirents))
 	for _, dirent := range dirents {
-		// Determine the path of the symbolic link to the device.
-		linkPath := path.Join(t.path, "devices", dirent.Name())
-
-		// Read the symoblic link.
-		devicePath, err := os.Readlink(linkPath)
+		// Resolve the symbolic link to the device.
+		linkName := path.Join(t.name, "devices", dirent.Name())
+		deviceName, err := sysfs.ResolveLink(t.fsys, linkName)
 		if err != nil {
-			return nil, fmt.Errorf("failed to read symbolic link \"%s\": %w", linkPath, err)
-		}
-		if devicePath == "" {
-			return nil, fmt.Errorf("the symbolic link \"%s\" has an empty path", linkPath)
-		}
-
-		// Resolve relative paths.
-		if !path.IsAbs(devicePath) {
-			resolved := path.Join(t.path, "devices", devicePath)
-			if resolved == "" {
-				return nil, fmt.Errorf("failed to resolve symbolic link \"%s\" with relavive path \"%s\": an empty path was returned from the path join", linkPath, devicePath)
-			}
-			devicePath = resolved
+			return nil, fmt.Errorf("failed to read symbolic link \"%s\": %w", sysfs.AbsPath(linkName), err)
 		}
 
-		devices = append(devices, MediatedDevice{path: devicePath})
+		devices = append(devices, MediatedDevice{fsys: t.fsys, name: deviceName})
 	}
 
 	return devices, nil
@@ -124,6 +124,5 @@ func (t Type) Devices() ([]MediatedDevice, error) {
 // Create requests the creation of a mediated device of type t with the given
 // device ID.
 func (t Type) Create(id machina.DeviceID) error {
-	create := path.Join(t.path, "create")
-	return writeToSystemFile(create, id.String())
+	return sysfs.WriteFile(t.fsys, path.Join(t.name, "create"), id.String())
 }
@@ -0,0 +1,281 @@
+package pcifs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+// VFIODriver is the name of the host driver that allows PCI devices to be
+// passed through to virtual machines.
+const VFIODriver = "vfio-pci"
+
+// System provides access to PCI devices through a sysfs file system.
+type System struct {
+	fsys sysfs.FS
+}
+
+// New returns a System that accesses PCI devices through fsys, which must be
+// rooted at the sysfs mount point.
+func New(fsys sysfs.FS) System {
+	return System{fsys: fsys}
+}
+
+// Local returns a System that accesses PCI devices through the sysfs file
+// system of the local system.
+func Local() System {
+	return New(sysfs.Local())
+}
+
+// Supported returns true if the system supports passthrough of PCI devices
+// via the vfio-pci driver.
+func (s System) Supported() bool {
+	if fi, err := fs.Stat(s.fsys, "bus/pci/drivers/"+VFIODriver); err != nil || !fi.IsDir() {
+		return false
+	}
+	return true
+}
+
+// Device returns a device accessor for the PCI device with the given
+// address. It expects the device to be present in bus/pci/devices.
+func (s System) Device(address machina.DeviceAddress) Device {
+	return Device{
+		fsys:    s.fsys,
+		address: address,
+		name:    "bus/pci/devices/" + string(address),
+	}
+}
+
+// Supported returns true if the local system supports passthrough of PCI
+// devices via the vfio-pci driver.
+func Supported() bool {
+	return Local().Supported()
+}
+
+// NewDevice returns a device accessor that will access a PCI device with the
+// given address through the local file system. It expects the device to be
+// present in /sys/bus/pci/devices.
+func NewDevice(address machina.DeviceAddress) Device {
+	return Local().Device(address)
+}
+
+// Locate returns a device accessor for the PCI device described by the
+// given passthrough device on the local system.
+func Locate(dev machina.PassthroughDevice) (Device, error) {
+	return Local().Locate(dev)
+}
+
+// FindVendorDevice returns all of the PCI devices on the local system with
+// the given vendor and device identifier.
+func FindVendorDevice(id machina.PCIVendorDevice) ([]Device, error) {
+	return Local().FindVendorDevice(id)
+}
+
+// Device provides access to a PCI device through a sysfs file system.
+type Device struct {
+	fsys    sysfs.FS
+	address machina.DeviceAddress
+	name    string
+}
+
+// Locate returns a device accessor for the PCI device described by the
+// given passthrough device.
+//
+// If the passthrough device has an address, it is used without consulting
+// the file system. Otherwise the PCI bus is searched for a device with a
+// matching vendor and device identifier, and exactly one device must be
+// found.
+func (s System) Locate(dev machina.PassthroughDevice) (Device, error) {
+	if err := dev.Validate(); err != nil {
+		return Device{}, err
+	}
+	if dev.Address != "" {
+		return s.Device(dev.Address), nil
+	}
+
+	devices, err := s.FindVendorDevice(dev.ID)
+	if err != nil {
+		return Device{}, err
+	}
+	switch len(devices) {
+	case 0:
+		return Device{}, fmt.Errorf("no PCI devices with the identifier %s are present", dev.ID)
+	case 1:
+		return devices[0], nil
+	default:
+		return Device{}, fmt.Errorf("%d PCI devices with the identifier %s are present: an address must be specified to select one of them", len(devices), dev.ID)
+	}
+}
+
+// FindVendorDevice returns all of the PCI devices with the given vendor and
+// device identifier.
+func (s System) FindVendorDevice(id machina.PCIVendorDevice) ([]Device, error) {
+	vendor, device, ok := id.Split()
+	if !ok {
+		return nil, fmt.Errorf("invalid PCI vendor and device identifier: \"%s\"", id)
+	}
+	if s.fsys == nil {
+		return nil, fmt.Errorf("unable to search for PCI device %s: a sysfs file system was not provided", id)
+	}
+
+	dirents, err := fs.ReadDir(s.fsys, "bus/pci/devices")
+	if err != nil {
+		return nil, err
+	}
+
+	var devices []Device
+	for _, dirent := range dirents {
+		candidate := s.Device(machina.DeviceAddress(dirent.Name()))
+		candidateID, err := candidate.VendorDevice()
+		if err != nil {
+			return nil, err
+		}
+		if v, d, _ := candidateID.Split(); v == vendor && d == device {
+			devices = append(devices, candidate)
+		}
+	}
+
+	return devices, nil
+}
+
+// Address returns the PCI address for the device.
+func (dev Device) Address() machina.DeviceAddress {
+	return dev.address
+}
+
+// Path returns the sysfs path for the device.
+func (dev Device) Path() sysfs.Path {
+	return sysfs.AbsPath(dev.name)
+}
+
+// Exists returns true if the sysfs path for the device exists.
+func (dev Device) Exists() (bool, error) {
+	if dev.address == "" {
+		return false, errors.New("the PCI device does not have an address")
+	}
+
+	fi, err := fs.Stat(dev.fsys, dev.name)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return false, nil
+		}
+		return false, err
+	}
+	if !fi.IsDir() {
+		return false, fmt.Errorf("the sysfs path \"%s\" is not a directory", dev.Path())
+	}
+	return true, nil
+}
+
+// VendorDevice returns the vendor and device identifier of the device.
+func (dev Device) VendorDevice() (machina.PCIVendorDevice, error) {
+	vendor, err := sysfs.ReadFile(dev.fsys, path.Join(dev.name, "vendor"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read vendor of PCI device %s: %w", dev.address, err)
+	}
+	device, err := sysfs.ReadFile(dev.fsys, path.Join(dev.name, "device"))
+	if err != nil {
+		return "", fmt.Errorf("failed to read device identifier of PCI device %s: %w", dev.address, err)
+	}
+	return machina.PCIVendorDevice(strings.TrimPrefix(vendor, "0x") + ":" + strings.TrimPrefix(device, "0x")), nil
+}
+
+// Driver returns the name of the host driver that is currently bound to
+// the device. It returns an empty string if no driver is bound.
+func (dev Device) Driver() (string, error) {
+	target, err := dev.fsys.ReadLink(path.Join(dev.name, "driver"))
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return "", nil
+		}
+		return "", fmt.Errorf("failed to read driver of PCI device %s: %w", dev.address, err)
+	}
+	return path.Base(target), nil
+}
+
+// BindVFIO binds the device to the vfio-pci driver, unbinding it from its
+// current host driver if necessary. It does nothing if the device is
+// already bound to vfio-pci.
+//
+// A driver override is put in place so that the kernel will not rebind the
+// device to its original driver while the override is present.
+func (dev Device) BindVFIO() error {
+	driver, err := dev.Driver()
+	if err != nil {
+		return err
+	}
+	if driver == VFIODriver {
+		return nil
+	}
+
+	if err := dev.override(VFIODriver); err != nil {
+		return err
+	}
+	if driver != "" {
+		if err := dev.unbind(); err != nil {
+			return err
+		}
+	}
+	if err := dev.probe(); err != nil {
+		return err
+	}
+
+	if driver, err = dev.Driver(); err != nil {
+		return err
+	} else if driver != VFIODriver {
+		return fmt.Errorf("PCI device %s could not be bound to the %s driver", dev.address, VFIODriver)
+	}
+
+	return nil
+}
+
+// ReleaseVFIO unbinds the device from the vfio-pci driver and asks the
+// kernel to probe it again, which restores its original host driver. It
+// does nothing if the device is not bound to vfio-pci.
+func (dev Device) ReleaseVFIO() error {
+	driver, err := dev.Driver()
+	if err != nil {
+		return err
+	}
+	if driver != VFIODriver {
+		return nil
+	}
+
+	// Writing a newline clears the driver override.
+	if err := dev.override("\n"); err != nil {
+		return err
+	}
+	if err := dev.unbind(); err != nil {
+		return err
+	}
+	return dev.probe()
+}
+
+// override sets the driver override for the device.
+func (dev Device) override(driver string) error {
+	if err := sysfs.WriteFile(dev.fsys, path.Join(dev.name, "driver_override"), driver); err != nil {
+		return fmt.Errorf("failed to set driver override for PCI device %s: %w", dev.address, err)
+	}
+	return nil
+}
+
+// unbind unbinds the device from its current driver.
+func (dev Device) unbind() error {
+	if err := sysfs.WriteFile(dev.fsys, path.Join(dev.name, "driver/unbind"), string(dev.address)); err != nil {
+		return fmt.Errorf("failed to unbind PCI device %s from its driver: %w", dev.address, err)
+	}
+	return nil
+}
+
+// probe asks the kernel to bind the device to a suitable driver.
+func (dev Device) probe() error {
+	if err := sysfs.WriteFile(dev.fsys, "bus/pci/drivers_probe", string(dev.address)); err != nil {
+		return fmt.Errorf("failed to probe drivers for PCI device %s: %w", dev.address, err)
+	}
+	return nil
+}
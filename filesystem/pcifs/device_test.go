@@ -0,0 +1,74 @@
+package pcifs_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/pcifs"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+func TestSystemLocate(t *testing.T) {
+	root := t.TempDir()
+	devices := map[string][2]string{
+		"0000:00:1f.0": {"0x8086", "0xa382"},
+		"0000:3b:00.0": {"0x10de", "0x1eb8"},
+		"0000:af:00.0": {"0x15b3", "0x1017"},
+		"0000:af:00.1": {"0x15b3", "0x1017"},
+	}
+	for address, id := range devices {
+		dir := filepath.Join(root, "bus/pci/devices", address)
+		if err := os.MkdirAll(dir, 0755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "vendor"), []byte(id[0]+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "device"), []byte(id[1]+"\n"), 0644); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	pci := pcifs.New(sysfs.Dir(root))
+
+	tests := []struct {
+		Name    string
+		Device  machina.PassthroughDevice
+		Address machina.DeviceAddress
+		OK      bool
+	}{
+		{"address", machina.PassthroughDevice{Address: "0000:65:00.0"}, "0000:65:00.0", true},
+		{"unique id", machina.PassthroughDevice{ID: "10de:1eb8"}, "0000:3b:00.0", true},
+		{"ambiguous id", machina.PassthroughDevice{ID: "15b3:1017"}, "", false},
+		{"missing id", machina.PassthroughDevice{ID: "1af4:1041"}, "", false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			dev, err := pci.Locate(test.Device)
+			if !test.OK {
+				if err == nil {
+					t.Fatalf("expected an error, got device %s", dev.Address())
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if dev.Address() != test.Address {
+				t.Errorf("unexpected address %s (want %s)", dev.Address(), test.Address)
+			}
+		})
+	}
+
+	// A System without a file system can still locate devices by address.
+	var none pcifs.System
+	if _, err := none.Locate(machina.PassthroughDevice{Address: "0000:3b:00.0"}); err != nil {
+		t.Errorf("unexpected error locating a device by address: %v", err)
+	}
+	if _, err := none.Locate(machina.PassthroughDevice{ID: "10de:1eb8"}); err == nil {
+		t.Errorf("expected an error locating a device by id without a file system")
+	}
+}
@@ -0,0 +1,4 @@
+// Package pcifs facilitates communication with PCI devices through the local
+// sysfs file system. It is used to hand PCI devices to the vfio-pci driver
+// so that they can be passed through to virtual machines.
+package pcifs
@@ -0,0 +1,77 @@
+package sysfs
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+// CPUList is an ordered list of logical CPU numbers.
+type CPUList []int
+
+// ParseCPUList parses a list of CPUs in the form used by the kernel, such as
+// "0-3,8,10-11". An empty string yields an empty list.
+func ParseCPUList(s string) (CPUList, error) {
+	s = strings.TrimSpace(s)
+	if s == "" {
+		return nil, nil
+	}
+
+	var list CPUList
+	for _, part := range strings.Split(s, ",") {
+		first, last, isRange := strings.Cut(part, "-")
+		start, err := strconv.Atoi(first)
+		if err != nil || start < 0 {
+			return nil, fmt.Errorf("invalid CPU list \"%s\": bad CPU \"%s\"", s, first)
+		}
+		end := start
+		if isRange {
+			end, err = strconv.Atoi(last)
+			if err != nil || end < start {
+				return nil, fmt.Errorf("invalid CPU list \"%s\": bad range \"%s\"", s, part)
+			}
+		}
+		for cpu := start; cpu <= end; cpu++ {
+			list = append(list, cpu)
+		}
+	}
+
+	slices.Sort(list)
+	return slices.Compact(list), nil
+}
+
+// Contains returns true if the list contains cpu.
+func (list CPUList) Contains(cpu int) bool {
+	_, found := slices.BinarySearch(list, cpu)
+	return found
+}
+
+// Intersects returns true if the list shares at least one CPU with other.
+func (list CPUList) Intersects(other CPUList) bool {
+	for _, cpu := range other {
+		if list.Contains(cpu) {
+			return true
+		}
+	}
+	return false
+}
+
+// String returns the list in the form used by the kernel, with consecutive
+// CPUs collapsed into ranges.
+func (list CPUList) String() string {
+	var parts []string
+	for i := 0; i < len(list); {
+		j := i
+		for j+1 < len(list) && list[j+1] == list[j]+1 {
+			j++
+		}
+		if i == j {
+			parts = append(parts, strconv.Itoa(list[i]))
+		} else {
+			parts = append(parts, fmt.Sprintf("%d-%d", list[i], list[j]))
+		}
+		i = j + 1
+	}
+	return strings.Join(parts, ",")
+}
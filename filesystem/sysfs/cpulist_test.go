@@ -0,0 +1,54 @@
+package sysfs_test
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+func TestParseCPUList(t *testing.T) {
+	tests := []struct {
+		Input  string
+		Want   sysfs.CPUList
+		String string
+	}{
+		{"", nil, ""},
+		{"0", sysfs.CPUList{0}, "0"},
+		{"0-3", sysfs.CPUList{0, 1, 2, 3}, "0-3"},
+		{"8,0-2,10-11\n", sysfs.CPUList{0, 1, 2, 8, 10, 11}, "0-2,8,10-11"},
+		{"1,1-2", sysfs.CPUList{1, 2}, "1-2"},
+	}
+	for _, test := range tests {
+		list, err := sysfs.ParseCPUList(test.Input)
+		if err != nil {
+			t.Errorf("%q: %v", test.Input, err)
+			continue
+		}
+		if !slices.Equal(list, test.Want) {
+			t.Errorf("%q: unexpected list %v (want %v)", test.Input, list, test.Want)
+		}
+		if s := list.String(); s != test.String {
+			t.Errorf("%q: unexpected string %q (want %q)", test.Input, s, test.String)
+		}
+	}
+
+	for _, input := range []string{"a", "3-1", "1,,2", "-1"} {
+		if _, err := sysfs.ParseCPUList(input); err == nil {
+			t.Errorf("%q: expected an error", input)
+		}
+	}
+}
+
+func TestCPUListIntersects(t *testing.T) {
+	node0 := sysfs.CPUList{0, 1, 2, 3}
+	if !node0.Intersects(sysfs.CPUList{3, 4}) {
+		t.Error("expected lists to intersect")
+	}
+	if node0.Intersects(sysfs.CPUList{4, 5}) {
+		t.Error("expected lists not to intersect")
+	}
+	if node0.Intersects(nil) {
+		t.Error("expected an empty list not to intersect")
+	}
+}
@@ -0,0 +1,7 @@
+// Package sysfs provides access to information exposed by the Linux kernel
+// through the sysfs file system.
+//
+// Functions that read from sysfs accept an fs.FS rooted at the sysfs mount
+// point, which allows them to be used with the local system via Local or
+// with a fake sysfs tree in tests.
+package sysfs
@@ -0,0 +1,121 @@
+package sysfs
+
+import (
+	"errors"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+)
+
+// MountPoint is the location of the sysfs file system on the local system.
+const MountPoint = "/sys"
+
+// FS is a sysfs file system that can be read from and written to.
+//
+// Names are slash-separated paths relative to the sysfs mount point, such as
+// "bus/mdev/devices", and must satisfy fs.ValidPath.
+type FS interface {
+	fs.FS
+
+	// ReadLink returns the destination of the named symbolic link.
+	ReadLink(name string) (string, error)
+
+	// WriteFile writes data to the named file, which must already exist.
+	WriteFile(name string, data []byte) error
+}
+
+// Local returns the sysfs file system of the local system.
+func Local() FS {
+	return Dir(MountPoint)
+}
+
+// Dir returns a sysfs file system rooted at the given directory. It can be
+// used to operate on a copy of a sysfs tree.
+func Dir(root string) FS {
+	return dirFS(root)
+}
+
+type dirFS string
+
+func (dir dirFS) Open(name string) (fs.File, error) {
+	return os.DirFS(string(dir)).Open(name)
+}
+
+func (dir dirFS) ReadLink(name string) (string, error) {
+	full, err := dir.join("readlink", name)
+	if err != nil {
+		return "", err
+	}
+	return os.Readlink(full)
+}
+
+func (dir dirFS) WriteFile(name string, data []byte) error {
+	full, err := dir.join("write", name)
+	if err != nil {
+		return err
+	}
+
+	file, err := os.OpenFile(full, os.O_WRONLY|os.O_TRUNC, 0)
+	if err != nil {
+		return err
+	}
+	defer file.Close()
+
+	if _, err := file.Write(data); err != nil {
+		return err
+	}
+
+	return nil
+}
+
+func (dir dirFS) join(op, name string) (string, error) {
+	if !fs.ValidPath(name) {
+		return "", &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+	return filepath.Join(string(dir), filepath.FromSlash(name)), nil
+}
+
+// ReadFile reads the named file from fsys and returns its contents without
+// a trailing newline.
+func ReadFile(fsys fs.FS, name string) (string, error) {
+	data, err := fs.ReadFile(fsys, name)
+	if err != nil {
+		return "", err
+	}
+	return strings.TrimSuffix(string(data), "\n"), nil
+}
+
+// WriteFile writes value to the named file in fsys.
+func WriteFile(fsys FS, name, value string) error {
+	return fsys.WriteFile(name, []byte(value))
+}
+
+// ResolveLink reads the named symbolic link from fsys and returns the name
+// of its destination within fsys.
+func ResolveLink(fsys FS, name string) (string, error) {
+	dest, err := fsys.ReadLink(name)
+	if err != nil {
+		return "", err
+	}
+	if dest == "" {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.New("the symbolic link has an empty destination")}
+	}
+
+	var resolved string
+	if path.IsAbs(dest) {
+		resolved, _ = strings.CutPrefix(path.Clean(dest), MountPoint+"/")
+	} else {
+		resolved = path.Join(path.Dir(name), dest)
+	}
+	if path.IsAbs(resolved) || !fs.ValidPath(resolved) {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: errors.New("the symbolic link refers to a location outside of sysfs: " + dest)}
+	}
+	return resolved, nil
+}
+
+// AbsPath returns the absolute path of the named file on the local system.
+func AbsPath(name string) Path {
+	return Path(path.Join(MountPoint, name))
+}
@@ -0,0 +1,31 @@
+package sysfs
+
+import (
+	"fmt"
+	"io/fs"
+	"strconv"
+)
+
+// FreeHugePages returns the number of free huge pages with the given size
+// in kibibytes, such as 2048 or 1048576.
+//
+// If node is negative, the number of free huge pages on the whole system
+// is returned. Otherwise the number of free huge pages on the given NUMA
+// node is returned.
+func FreeHugePages(fsys fs.FS, node, sizeKB int) (int, error) {
+	name := fmt.Sprintf("kernel/mm/hugepages/hugepages-%dkB/free_hugepages", sizeKB)
+	if node >= 0 {
+		name = fmt.Sprintf("devices/system/node/node%d/hugepages/hugepages-%dkB/free_hugepages", node, sizeKB)
+	}
+
+	value, err := ReadFile(fsys, name)
+	if err != nil {
+		return 0, err
+	}
+
+	free, err := strconv.Atoi(value)
+	if err != nil {
+		return 0, fmt.Errorf("failed to parse %s: %w", name, err)
+	}
+	return free, nil
+}
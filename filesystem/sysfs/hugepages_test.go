@@ -0,0 +1,40 @@
+package sysfs_test
+
+import (
+	"errors"
+	"io/fs"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+func TestFreeHugePages(t *testing.T) {
+	fsys := fstest.MapFS{
+		"kernel/mm/hugepages/hugepages-2048kB/free_hugepages":                    {Data: []byte("1024\n")},
+		"devices/system/node/node1/hugepages/hugepages-1048576kB/free_hugepages": {Data: []byte("4\n")},
+	}
+
+	tests := []struct {
+		Node   int
+		SizeKB int
+		Want   int
+	}{
+		{-1, 2048, 1024},
+		{1, 1048576, 4},
+	}
+	for _, test := range tests {
+		got, err := sysfs.FreeHugePages(fsys, test.Node, test.SizeKB)
+		if err != nil {
+			t.Errorf("node %d, %d kB: %v", test.Node, test.SizeKB, err)
+			continue
+		}
+		if got != test.Want {
+			t.Errorf("node %d, %d kB: unexpected free huge pages %d (want %d)", test.Node, test.SizeKB, got, test.Want)
+		}
+	}
+
+	if _, err := sysfs.FreeHugePages(fsys, 0, 2048); !errors.Is(err, fs.ErrNotExist) {
+		t.Errorf("expected a not exist error for a missing node, got %v", err)
+	}
+}
@@ -0,0 +1,99 @@
+package sysfs
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"slices"
+	"strconv"
+)
+
+// ErrNoIOMMUGroup is returned when a device does not belong to an IOMMU
+// group, which is typically because the IOMMU is disabled.
+var ErrNoIOMMUGroup = errors.New("the device does not belong to an IOMMU group")
+
+// iommuGroupsDir is the location of IOMMU groups within sysfs.
+const iommuGroupsDir = "kernel/iommu_groups"
+
+// IOMMUGroup is a set of devices that are isolated from the rest of the
+// system by the IOMMU, but not from each other. All of the devices in a
+// group must be handed to vfio before any of them can be passed through to
+// a virtual machine.
+type IOMMUGroup struct {
+	ID      int
+	Devices []string
+}
+
+// Contains returns true if the group contains a device with the given
+// address.
+func (group IOMMUGroup) Contains(address string) bool {
+	return slices.Contains(group.Devices, address)
+}
+
+// IOMMUGroups returns all of the IOMMU groups present in fsys, which must be
+// rooted at the sysfs mount point. The groups are sorted by ID, and the
+// devices within each group are sorted by address.
+//
+// If the IOMMU is disabled no groups will be returned.
+func IOMMUGroups(fsys fs.FS) ([]IOMMUGroup, error) {
+	dirents, err := fs.ReadDir(fsys, iommuGroupsDir)
+	if err != nil {
+		if errors.Is(err, fs.ErrNotExist) {
+			return nil, nil
+		}
+		return nil, fmt.Errorf("failed to read IOMMU groups: %w", err)
+	}
+
+	groups := make([]IOMMUGroup, 0, len(dirents))
+	for _, dirent := range dirents {
+		id, err := strconv.Atoi(dirent.Name())
+		if err != nil {
+			continue
+		}
+		group, err := ReadIOMMUGroup(fsys, id)
+		if err != nil {
+			return nil, err
+		}
+		groups = append(groups, group)
+	}
+
+	slices.SortFunc(groups, func(a, b IOMMUGroup) int {
+		return a.ID - b.ID
+	})
+
+	return groups, nil
+}
+
+// ReadIOMMUGroup returns the IOMMU group with the given ID from fsys, which
+// must be rooted at the sysfs mount point.
+func ReadIOMMUGroup(fsys fs.FS, id int) (IOMMUGroup, error) {
+	dirents, err := fs.ReadDir(fsys, path.Join(iommuGroupsDir, strconv.Itoa(id), "devices"))
+	if err != nil {
+		return IOMMUGroup{}, fmt.Errorf("failed to read IOMMU group %d: %w", id, err)
+	}
+
+	group := IOMMUGroup{ID: id}
+	for _, dirent := range dirents {
+		group.Devices = append(group.Devices, dirent.Name())
+	}
+	slices.Sort(group.Devices)
+
+	return group, nil
+}
+
+// FindIOMMUGroup returns the IOMMU group that the device with the given
+// address belongs to. If the device does not belong to a group,
+// ErrNoIOMMUGroup is returned.
+func FindIOMMUGroup(fsys fs.FS, address string) (IOMMUGroup, error) {
+	groups, err := IOMMUGroups(fsys)
+	if err != nil {
+		return IOMMUGroup{}, err
+	}
+	for _, group := range groups {
+		if group.Contains(address) {
+			return group, nil
+		}
+	}
+	return IOMMUGroup{}, ErrNoIOMMUGroup
+}
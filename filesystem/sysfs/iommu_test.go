@@ -0,0 +1,113 @@
+package sysfs_test
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+// fakeSysfs returns a fake sysfs tree with a GPU that shares an IOMMU group
+// with its audio function and a root port, and a network adapter that is
+// alone in its group.
+func fakeSysfs() fstest.MapFS {
+	return fstest.MapFS{
+		"kernel/iommu_groups/1/devices/0000:00:01.0":  {},
+		"kernel/iommu_groups/1/devices/0000:01:00.1":  {},
+		"kernel/iommu_groups/1/devices/0000:01:00.0":  {},
+		"kernel/iommu_groups/10/devices/0000:02:00.0": {},
+		"bus/pci/devices/0000:00:01.0/uevent": {Data: []byte(
+			"DRIVER=pcieport\nPCI_CLASS=60400\nPCI_ID=8086:1901\nPCI_SLOT_NAME=0000:00:01.0\n")},
+		"bus/pci/devices/0000:01:00.0/uevent": {Data: []byte(
+			"DRIVER=vfio-pci\nPCI_CLASS=30000\nPCI_ID=10DE:1EB8\nPCI_SLOT_NAME=0000:01:00.0\n")},
+		"bus/pci/devices/0000:01:00.1/uevent": {Data: []byte(
+			"DRIVER=snd_hda_intel\nPCI_CLASS=40300\nPCI_ID=10DE:10F8\nPCI_SLOT_NAME=0000:01:00.1\n")},
+		"bus/pci/devices/0000:02:00.0/uevent": {Data: []byte(
+			"PCI_CLASS=20000\nPCI_ID=8086:1533\nPCI_SLOT_NAME=0000:02:00.0\n")},
+	}
+}
+
+func TestIOMMUGroups(t *testing.T) {
+	groups, err := sysfs.IOMMUGroups(fakeSysfs())
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	want := []sysfs.IOMMUGroup{
+		{ID: 1, Devices: []string{"0000:00:01.0", "0000:01:00.0", "0000:01:00.1"}},
+		{ID: 10, Devices: []string{"0000:02:00.0"}},
+	}
+	if len(groups) != len(want) {
+		t.Fatalf("unexpected number of IOMMU groups: %d (want %d)", len(groups), len(want))
+	}
+	for i := range groups {
+		if groups[i].ID != want[i].ID || !slices.Equal(groups[i].Devices, want[i].Devices) {
+			t.Errorf("unexpected IOMMU group %d: %v (want %v)", i, groups[i], want[i])
+		}
+	}
+}
+
+func TestIOMMUGroupsDisabled(t *testing.T) {
+	groups, err := sysfs.IOMMUGroups(fstest.MapFS{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(groups) != 0 {
+		t.Errorf("unexpected IOMMU groups when the IOMMU is disabled: %v", groups)
+	}
+}
+
+func TestFindIOMMUGroup(t *testing.T) {
+	fsys := fakeSysfs()
+
+	group, err := sysfs.FindIOMMUGroup(fsys, "0000:01:00.1")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if group.ID != 1 {
+		t.Errorf("unexpected IOMMU group for 0000:01:00.1: %d (want 1)", group.ID)
+	}
+
+	if _, err := sysfs.FindIOMMUGroup(fsys, "0000:03:00.0"); !errors.Is(err, sysfs.ErrNoIOMMUGroup) {
+		t.Errorf("unexpected error for a device without an IOMMU group: %v (want %v)", err, sysfs.ErrNoIOMMUGroup)
+	}
+}
+
+func TestReadPCIDevice(t *testing.T) {
+	fsys := fakeSysfs()
+
+	tests := []struct {
+		Address string
+		ID      string
+		Driver  string
+		Bridge  bool
+		Viable  bool
+	}{
+		{"0000:00:01.0", "8086:1901", "pcieport", true, true},
+		{"0000:01:00.0", "10de:1eb8", "vfio-pci", false, true},
+		{"0000:01:00.1", "10de:10f8", "snd_hda_intel", false, false},
+		{"0000:02:00.0", "8086:1533", "", false, true},
+	}
+
+	for _, test := range tests {
+		dev, err := sysfs.ReadPCIDevice(fsys, test.Address)
+		if err != nil {
+			t.Errorf("%s: %v", test.Address, err)
+			continue
+		}
+		if dev.ID != test.ID {
+			t.Errorf("%s: unexpected ID \"%s\" (want \"%s\")", test.Address, dev.ID, test.ID)
+		}
+		if dev.Driver != test.Driver {
+			t.Errorf("%s: unexpected driver \"%s\" (want \"%s\")", test.Address, dev.Driver, test.Driver)
+		}
+		if dev.Bridge() != test.Bridge {
+			t.Errorf("%s: unexpected bridge status %t (want %t)", test.Address, dev.Bridge(), test.Bridge)
+		}
+		if dev.VFIOViable() != test.Viable {
+			t.Errorf("%s: unexpected vfio viability %t (want %t)", test.Address, dev.VFIOViable(), test.Viable)
+		}
+	}
+}
@@ -0,0 +1,35 @@
+package sysfs
+
+import (
+	"io/fs"
+	"path"
+)
+
+// ModuleLoaded returns true if the kernel module with the given name, such
+// as kvm_intel, is loaded.
+func ModuleLoaded(fsys fs.FS, module string) bool {
+	info, err := fs.Stat(fsys, path.Join("module", module))
+	return err == nil && info.IsDir()
+}
+
+// ModuleParameter returns the value of a parameter of a loaded kernel
+// module, such as the nested parameter of kvm_intel.
+func ModuleParameter(fsys fs.FS, module, param string) (string, error) {
+	return ReadFile(fsys, path.Join("module", module, "parameters", param))
+}
+
+// ModuleFeatureEnabled returns true if a boolean parameter of a loaded
+// kernel module is enabled. Kernel modules report boolean parameters as
+// Y or N, or as 1 or 0.
+func ModuleFeatureEnabled(fsys fs.FS, module, param string) (bool, error) {
+	value, err := ModuleParameter(fsys, module, param)
+	if err != nil {
+		return false, err
+	}
+	switch value {
+	case "Y", "y", "1":
+		return true, nil
+	default:
+		return false, nil
+	}
+}
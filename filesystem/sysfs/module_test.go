@@ -0,0 +1,44 @@
+package sysfs_test
+
+import (
+	"testing"
+	"testing/fstest"
+
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+)
+
+func TestModuleFeatureEnabled(t *testing.T) {
+	fsys := fstest.MapFS{
+		"module/kvm_amd/parameters/nested": {Data: []byte("1\n")},
+		"module/kvm_amd/parameters/sev":    {Data: []byte("Y\n")},
+		"module/kvm_amd/parameters/sev_es": {Data: []byte("N\n")},
+	}
+
+	if !sysfs.ModuleLoaded(fsys, "kvm_amd") {
+		t.Errorf("kvm_amd should be loaded")
+	}
+	if sysfs.ModuleLoaded(fsys, "kvm_intel") {
+		t.Errorf("kvm_intel should not be loaded")
+	}
+
+	tests := []struct {
+		Param string
+		Want  bool
+	}{
+		{"nested", true},
+		{"sev", true},
+		{"sev_es", false},
+	}
+	for _, test := range tests {
+		got, err := sysfs.ModuleFeatureEnabled(fsys, "kvm_amd", test.Param)
+		if err != nil {
+			t.Errorf("%s: %v", test.Param, err)
+		} else if got != test.Want {
+			t.Errorf("%s: got %t, want %t", test.Param, got, test.Want)
+		}
+	}
+
+	if _, err := sysfs.ModuleFeatureEnabled(fsys, "kvm_intel", "nested"); err == nil {
+		t.Errorf("expected an error for a module that isn't loaded")
+	}
+}
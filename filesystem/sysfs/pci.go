@@ -0,0 +1,94 @@
+package sysfs
+
+import (
+	"bufio"
+	"fmt"
+	"io/fs"
+	"path"
+	"strconv"
+	"strings"
+)
+
+// PCIDevice holds information about a PCI device on the host system.
+type PCIDevice struct {
+	// Address is the PCI address of the device, such as "0000:01:00.0".
+	Address string
+
+	// ID is the vendor and device identifier of the device in lowercase
+	// hexadecimal "vendor:device" form, such as "10de:1eb8".
+	ID string
+
+	// Class is the PCI class code of the device, including its subclass
+	// and programming interface.
+	Class uint32
+
+	// Driver is the name of the host driver bound to the device. It is
+	// empty if no driver is bound.
+	Driver string
+}
+
+// Bridge returns true if the device is a PCI bridge.
+//
+// PCI bridges, including PCI Express root ports, can share an IOMMU group
+// with a passthrough device without being handed to vfio.
+func (dev PCIDevice) Bridge() bool {
+	return dev.Class>>8 == 0x0604
+}
+
+// VFIOViable returns true if the device's current driver binding does not
+// prevent the IOMMU group it belongs to from being used by vfio.
+func (dev PCIDevice) VFIOViable() bool {
+	switch dev.Driver {
+	case "", "vfio-pci", "pci-stub":
+		return true
+	default:
+		return dev.Bridge()
+	}
+}
+
+// String returns a string representation of the device.
+func (dev PCIDevice) String() string {
+	driver := dev.Driver
+	if driver == "" {
+		driver = "no driver"
+	}
+	return fmt.Sprintf("%s [%s] (%s)", dev.Address, dev.ID, driver)
+}
+
+// ReadPCIDevice reads information about the PCI device with the given
+// address from fsys, which must be rooted at the sysfs mount point.
+func ReadPCIDevice(fsys fs.FS, address string) (PCIDevice, error) {
+	// The uevent file holds everything we need in a form that can be read
+	// without following symbolic links.
+	f, err := fsys.Open(path.Join("bus/pci/devices", address, "uevent"))
+	if err != nil {
+		return PCIDevice{}, fmt.Errorf("failed to read PCI device %s: %w", address, err)
+	}
+	defer f.Close()
+
+	dev := PCIDevice{Address: address}
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		key, value, ok := strings.Cut(scanner.Text(), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "DRIVER":
+			dev.Driver = value
+		case "PCI_ID":
+			dev.ID = strings.ToLower(value)
+		case "PCI_CLASS":
+			class, err := strconv.ParseUint(value, 16, 32)
+			if err != nil {
+				return PCIDevice{}, fmt.Errorf("failed to read PCI device %s: invalid class \"%s\": %w", address, value, err)
+			}
+			dev.Class = uint32(class)
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return PCIDevice{}, fmt.Errorf("failed to read PCI device %s: %w", address, err)
+	}
+
+	return dev, nil
+}
@@ -13,6 +13,7 @@ require (
 	github.com/vishvananda/netlink v1.3.0
 	github.com/willabides/kongplete v0.4.0
 	golang.org/x/crypto v0.36.0
+	golang.org/x/sys v0.31.0
 )
 
 require (
@@ -23,5 +24,4 @@ require (
 	github.com/mattn/go-isatty v0.0.20 // indirect
 	github.com/riywo/loginshell v0.0.0-20200815045211-7d26008be1ab // indirect
 	github.com/vishvananda/netns v0.0.5 // indirect
-	golang.org/x/sys v0.31.0 // indirect
 )
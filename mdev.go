@@ -59,13 +59,74 @@ func (set MediatedDevicePlacementSet) List() MediatedDevicePlacementList {
 	return list
 }
 
+// MediatedDevicePolicy determines which physical device is selected when
+// more than one physical device can supply a mediated device.
+type MediatedDevicePolicy string
+
+// Mediated device policies.
+const (
+	// PreferAddressMediatedDevicePolicy selects physical devices in
+	// descending order of priority and then in order of PCI address. It
+	// is the default policy.
+	PreferAddressMediatedDevicePolicy = MediatedDevicePolicy("prefer-address")
+
+	// SpreadMediatedDevicePolicy selects the physical device with the most
+	// available instances of the mediated device type, which balances
+	// mediated devices across physical devices.
+	SpreadMediatedDevicePolicy = MediatedDevicePolicy("spread")
+
+	// PackMediatedDevicePolicy selects the physical device with the fewest
+	// available instances of the mediated device type, which fills each
+	// physical device before moving on to the next one.
+	PackMediatedDevicePolicy = MediatedDevicePolicy("pack")
+
+	// NUMALocalMediatedDevicePolicy selects a physical device that is local
+	// to the NUMA node of the CPUs that the virtual machine runs on. Among
+	// local devices, or when no local device has capacity, it behaves like
+	// SpreadMediatedDevicePolicy.
+	NUMALocalMediatedDevicePolicy = MediatedDevicePolicy("numa-local")
+)
+
+// Valid returns true if p is a recognized mediated device policy.
+func (p MediatedDevicePolicy) Valid() bool {
+	switch p {
+	case PreferAddressMediatedDevicePolicy, SpreadMediatedDevicePolicy, PackMediatedDevicePolicy, NUMALocalMediatedDevicePolicy:
+		return true
+	default:
+		return false
+	}
+}
+
 // MediatedDeviceType stores information about an available mediated device
 // type and its desired configuration.
+//
+// If a policy is specified it overrides the policy of the mediated device
+// for this class.
 type MediatedDeviceType struct {
 	Name       MediatedDeviceTypeName      `json:"name"`
 	Placements MediatedDevicePlacementList `json:"placements,omitempty"`
+	Policy     MediatedDevicePolicy        `json:"policy,omitempty"`
 }
 
+// MediatedDeviceDriver identifies the vendor driver that manages the
+// vendor-specific behavior of a mediated device, such as mode setup and
+// placement handling.
+type MediatedDeviceDriver string
+
+// Mediated device drivers.
+const (
+	// NVIDIAMediatedDeviceDriver manages NVIDIA vGPU devices. It supports
+	// heterogeneous mode and placement IDs.
+	NVIDIAMediatedDeviceDriver = MediatedDeviceDriver("nvidia")
+
+	// GVTgMediatedDeviceDriver manages Intel GVT-g devices.
+	GVTgMediatedDeviceDriver = MediatedDeviceDriver("gvt-g")
+
+	// GenericMediatedDeviceDriver manages mediated devices that don't have
+	// any vendor-specific behavior.
+	GenericMediatedDeviceDriver = MediatedDeviceDriver("generic")
+)
+
 // MediatedDeviceName is the name of a mediated device on the host system.
 type MediatedDeviceName string
 
@@ -74,12 +135,47 @@ type MediatedDeviceName string
 type MediatedDeviceClassMap map[DeviceClass]MediatedDeviceType
 
 // MediatedDevice describes a mediated device available on the host system.
+//
+// The policy and priority determine which physical device is selected when
+// more than one of them supplies a device class.
 type MediatedDevice struct {
 	Address       DeviceAddress          `json:"address"`
+	Driver        MediatedDeviceDriver   `json:"driver,omitempty"`
 	Heterogeneous bool                   `json:"heterogeneous"`
+	Policy        MediatedDevicePolicy   `json:"policy,omitempty"`
+	Priority      int                    `json:"priority,omitempty"`
 	Classes       MediatedDeviceClassMap `json:"classes,omitempty"`
 }
 
+// EffectiveDriver returns the vendor driver for the mediated device. If a
+// driver has not been specified it returns NVIDIAMediatedDeviceDriver when
+// heterogeneous mode or placements are configured, which are NVIDIA
+// features, and GenericMediatedDeviceDriver otherwise.
+func (dev MediatedDevice) EffectiveDriver() MediatedDeviceDriver {
+	if dev.Driver != "" {
+		return dev.Driver
+	}
+	if dev.Heterogeneous {
+		return NVIDIAMediatedDeviceDriver
+	}
+	for _, typ := range dev.Classes {
+		if len(typ.Placements) > 0 {
+			return NVIDIAMediatedDeviceDriver
+		}
+	}
+	return GenericMediatedDeviceDriver
+}
+
+// PolicyFor returns the policy configured for the given device class, if
+// any. A policy configured for the class takes precedence over the policy
+// of the mediated device.
+func (dev MediatedDevice) PolicyFor(class DeviceClass) MediatedDevicePolicy {
+	if typ, found := dev.Classes[class]; found && typ.Policy != "" {
+		return typ.Policy
+	}
+	return dev.Policy
+}
+
 // MediatedDeviceList holds a sortable list of mediated devices on the host
 // system.
 type MediatedDeviceList []MediatedDevice
@@ -90,6 +186,20 @@ func (a MediatedDeviceList) Less(i, j int) bool {
 	return strings.Compare(string(a[i].Address), string(a[j].Address)) < 0
 }
 
+// Policy returns the policy for selecting among the mediated devices in the
+// list that supply the given device class. If the devices are configured
+// with different policies, the policy of the first device in the list that
+// configures one is used. If none of them configure a policy it returns
+// PreferAddressMediatedDevicePolicy.
+func (a MediatedDeviceList) Policy(class DeviceClass) MediatedDevicePolicy {
+	for _, dev := range a {
+		if policy := dev.PolicyFor(class); policy != "" {
+			return policy
+		}
+	}
+	return PreferAddressMediatedDevicePolicy
+}
+
 // MediatedDeviceMap describes a set of mediated devices on the host system.
 type MediatedDeviceMap map[MediatedDeviceName]MediatedDevice
 
@@ -107,6 +217,16 @@ func (m MediatedDeviceMap) WithClass(class DeviceClass) (devices MediatedDeviceL
 
 // Config adds the mediated device configuration to the summary.
 func (dev MediatedDevice) Config(out summary.Interface) {
+	out.Add("Driver: %s", dev.EffectiveDriver())
+	if dev.Heterogeneous {
+		out.Add("Heterogeneous: %t", dev.Heterogeneous)
+	}
+	if dev.Policy != "" {
+		out.Add("Policy: %s", dev.Policy)
+	}
+	if dev.Priority != 0 {
+		out.Add("Priority: %d", dev.Priority)
+	}
 	out.Add("Supplied Device Classes:")
 	out.Descend()
 	for class, typ := range dev.Classes {
@@ -116,6 +236,9 @@ func (dev MediatedDevice) Config(out summary.Interface) {
 		if len(typ.Placements) > 0 {
 			out.Add("Placement IDs: %s", typ.Placements)
 		}
+		if typ.Policy != "" {
+			out.Add("Policy: %s", typ.Policy)
+		}
 		out.Ascend()
 	}
 	out.Ascend()
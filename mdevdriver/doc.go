@@ -0,0 +1,7 @@
+// Package mdevdriver provides vendor drivers for mediated devices.
+//
+// A vendor driver is responsible for the vendor-specific behavior of the
+// physical devices that host mediated devices, such as mode setup,
+// placement handling and capability discovery. Drivers are selected for
+// each mediated device in the system configuration by name.
+package mdevdriver
@@ -0,0 +1,86 @@
+package mdevdriver
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+)
+
+// ErrPlacementUnsupported is returned by drivers that do not support
+// placement IDs.
+var ErrPlacementUnsupported = errors.New("mediated device placements are not supported by the vendor driver")
+
+// Capabilities describes the vendor-specific features supported by a
+// physical device.
+type Capabilities struct {
+	// Heterogeneous is true if the physical device can host mediated
+	// devices of different types at the same time.
+	Heterogeneous bool
+
+	// Placement is true if the placement of mediated devices within the
+	// physical device can be selected.
+	Placement bool
+}
+
+// Driver is a vendor driver for mediated devices.
+type Driver interface {
+	// Capabilities reports the vendor-specific features supported by the
+	// physical device.
+	Capabilities(pdev mdevfs.PhysicalDevice) (Capabilities, error)
+
+	// PrepareMode prepares the physical device for the creation of a
+	// mediated device, according to its configuration. The existing
+	// mediated devices on the physical device are provided.
+	PrepareMode(ctx context.Context, pdev mdevfs.PhysicalDevice, config machina.MediatedDevice, existing mdevfs.MediatedDeviceList) error
+
+	// Placements returns the set of placement IDs in use by the given
+	// mediated devices.
+	Placements(devices mdevfs.MediatedDeviceList) (machina.MediatedDevicePlacementSet, error)
+
+	// Placement returns the placement ID of a mediated device. It returns
+	// mdevfs.ErrNotApplicable if the device does not have a placement yet.
+	Placement(mdev mdevfs.MediatedDevice) (machina.MediatedDevicePlacementID, error)
+
+	// ChangePlacement changes the placement ID of a mediated device.
+	ChangePlacement(mdev mdevfs.MediatedDevice, id machina.MediatedDevicePlacementID) error
+}
+
+var (
+	registryMutex sync.RWMutex
+	registry      = map[machina.MediatedDeviceDriver]Driver{
+		machina.NVIDIAMediatedDeviceDriver:  NVIDIA{},
+		machina.GVTgMediatedDeviceDriver:    GVTg{},
+		machina.GenericMediatedDeviceDriver: Generic{},
+	}
+)
+
+// Register makes a vendor driver available by name. If a driver is already
+// registered with the name, it is replaced.
+func Register(name machina.MediatedDeviceDriver, driver Driver) {
+	registryMutex.Lock()
+	defer registryMutex.Unlock()
+	registry[name] = driver
+}
+
+// Lookup returns the vendor driver registered with the given name.
+func Lookup(name machina.MediatedDeviceDriver) (driver Driver, ok bool) {
+	registryMutex.RLock()
+	defer registryMutex.RUnlock()
+	driver, ok = registry[name]
+	return
+}
+
+// For returns the vendor driver for the given mediated device
+// configuration.
+func For(config machina.MediatedDevice) (Driver, error) {
+	name := config.EffectiveDriver()
+	driver, ok := Lookup(name)
+	if !ok {
+		return nil, fmt.Errorf("the mediated device %s uses an unrecognized vendor driver: \"%s\"", config.Address, name)
+	}
+	return driver, nil
+}
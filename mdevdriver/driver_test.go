@@ -0,0 +1,140 @@
+package mdevdriver_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs/mdevtest"
+	"github.com/gentlemanautomaton/machina/mdevdriver"
+)
+
+func TestFor(t *testing.T) {
+	tests := []struct {
+		Config machina.MediatedDevice
+		Want   any
+	}{
+		{machina.MediatedDevice{}, mdevdriver.Generic{}},
+		{machina.MediatedDevice{Heterogeneous: true}, mdevdriver.NVIDIA{}},
+		{machina.MediatedDevice{Classes: machina.MediatedDeviceClassMap{
+			"vgpu": {Name: "GRID T4-8Q", Placements: machina.MediatedDevicePlacementList{0}},
+		}}, mdevdriver.NVIDIA{}},
+		{machina.MediatedDevice{Driver: machina.GVTgMediatedDeviceDriver}, mdevdriver.GVTg{}},
+	}
+
+	for i, test := range tests {
+		driver, err := mdevdriver.For(test.Config)
+		if err != nil {
+			t.Errorf("%d: %v", i, err)
+			continue
+		}
+		if got, want := fmt.Sprintf("%T", driver), fmt.Sprintf("%T", test.Want); got != want {
+			t.Errorf("%d: unexpected driver %s (want %s)", i, got, want)
+		}
+	}
+
+	if _, err := mdevdriver.For(machina.MediatedDevice{Driver: "unknown"}); err == nil {
+		t.Errorf("an unrecognized vendor driver was accepted")
+	}
+}
+
+func TestNVIDIAPrepareMode(t *testing.T) {
+	const address = machina.DeviceAddress("0000:3b:00.0")
+
+	bus := mdevtest.NewBus()
+	bus.AddPhysicalDevice(address, mdevtest.Type{ID: "nvidia-256", Name: "GRID T4-8Q", MaxInstances: 2})
+	pdev := mdevfs.New(bus).PhysicalDevice(address)
+
+	var calls [][]string
+	driver := mdevdriver.NVIDIA{
+		SMI: func(ctx context.Context, args ...string) error {
+			calls = append(calls, args)
+			return nil
+		},
+	}
+
+	config := machina.MediatedDevice{Address: address, Heterogeneous: true}
+
+	// Heterogeneous mode should be set when no devices exist.
+	if err := driver.PrepareMode(context.Background(), pdev, config, nil); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"vgpu", "--id", "0000:3b:00.0", "-shm", "1"}
+	if len(calls) != 1 || !slices.Equal(calls[0], want) {
+		t.Fatalf("unexpected nvidia-smi invocations: %v (want [%v])", calls, want)
+	}
+
+	// Heterogeneous mode can't be changed once devices exist.
+	var id machina.DeviceID
+	if err := id.UnmarshalText([]byte("79db70f1-92a5-4879-95f7-6325b66c1ff9")); err != nil {
+		t.Fatal(err)
+	}
+	if err := bus.AddDevice(address, "nvidia-256", id); err != nil {
+		t.Fatal(err)
+	}
+	types, err := pdev.Types()
+	if err != nil {
+		t.Fatal(err)
+	}
+	existing, err := types.Devices()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := driver.PrepareMode(context.Background(), pdev, config, existing); err != nil {
+		t.Fatal(err)
+	}
+	if len(calls) != 1 {
+		t.Errorf("nvidia-smi was invoked while mediated devices exist: %v", calls)
+	}
+}
+
+func TestNVIDIACapabilities(t *testing.T) {
+	bus := mdevtest.NewBus()
+	bus.AddPhysicalDevice("0000:3b:00.0", mdevtest.Type{ID: "nvidia-256", Name: "GRID T4-8Q", MaxInstances: 2})
+	bus.AddPhysicalDevice("0000:5e:00.0",
+		mdevtest.Type{ID: "nvidia-1149", Name: "NVIDIA L4-6Q", MaxInstances: 4, Placements: []machina.MediatedDevicePlacementID{0, 6, 12, 18}},
+		mdevtest.Type{ID: "nvidia-1151", Name: "NVIDIA L4-12Q", MaxInstances: 2, Placements: []machina.MediatedDevicePlacementID{0, 12}})
+	sys := mdevfs.New(bus)
+
+	tests := []struct {
+		Address machina.DeviceAddress
+		Want    mdevdriver.Capabilities
+	}{
+		{"0000:3b:00.0", mdevdriver.Capabilities{}},
+		{"0000:5e:00.0", mdevdriver.Capabilities{Heterogeneous: true, Placement: true}},
+	}
+
+	var driver mdevdriver.NVIDIA
+	for _, test := range tests {
+		caps, err := driver.Capabilities(sys.PhysicalDevice(test.Address))
+		if err != nil {
+			t.Errorf("%s: %v", test.Address, err)
+			continue
+		}
+		if caps != test.Want {
+			t.Errorf("%s: unexpected capabilities: %+v (want %+v)", test.Address, caps, test.Want)
+		}
+	}
+
+	if _, err := driver.Capabilities(sys.PhysicalDevice("0000:af:00.0")); err == nil {
+		t.Errorf("capabilities were reported for a missing physical device")
+	}
+}
+
+func TestGenericPlacements(t *testing.T) {
+	var driver mdevdriver.Generic
+
+	if caps, err := driver.Capabilities(mdevfs.PhysicalDevice{}); err != nil || caps.Placement || caps.Heterogeneous {
+		t.Errorf("unexpected capabilities: %+v: %v", caps, err)
+	}
+	if err := driver.ChangePlacement(mdevfs.MediatedDevice{}, 0); !errors.Is(err, mdevdriver.ErrPlacementUnsupported) {
+		t.Errorf("unexpected error: %v (want %v)", err, mdevdriver.ErrPlacementUnsupported)
+	}
+	if err := driver.PrepareMode(context.Background(), mdevfs.PhysicalDevice{}, machina.MediatedDevice{Heterogeneous: true}, nil); err == nil {
+		t.Errorf("heterogeneous mode was accepted by the generic driver")
+	}
+}
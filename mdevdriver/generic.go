@@ -0,0 +1,45 @@
+package mdevdriver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+)
+
+// Generic is a vendor driver for mediated devices that don't have any
+// vendor-specific behavior. It relies entirely on the standard mdev sysfs
+// interface.
+type Generic struct{}
+
+// Capabilities reports that the physical device doesn't have any
+// vendor-specific features. There is nothing to probe, because the
+// standard mdev sysfs interface doesn't describe any.
+func (Generic) Capabilities(pdev mdevfs.PhysicalDevice) (Capabilities, error) {
+	return Capabilities{}, nil
+}
+
+// PrepareMode returns an error if heterogeneous mode is requested, because
+// generic devices don't have a mode that can be changed.
+func (Generic) PrepareMode(ctx context.Context, pdev mdevfs.PhysicalDevice, config machina.MediatedDevice, existing mdevfs.MediatedDeviceList) error {
+	if config.Heterogeneous {
+		return fmt.Errorf("heterogeneous mode is not supported by the %s vendor driver", machina.GenericMediatedDeviceDriver)
+	}
+	return nil
+}
+
+// Placements returns an empty set.
+func (Generic) Placements(devices mdevfs.MediatedDeviceList) (machina.MediatedDevicePlacementSet, error) {
+	return make(machina.MediatedDevicePlacementSet), nil
+}
+
+// Placement returns ErrPlacementUnsupported.
+func (Generic) Placement(mdev mdevfs.MediatedDevice) (machina.MediatedDevicePlacementID, error) {
+	return 0, ErrPlacementUnsupported
+}
+
+// ChangePlacement returns ErrPlacementUnsupported.
+func (Generic) ChangePlacement(mdev mdevfs.MediatedDevice, id machina.MediatedDevicePlacementID) error {
+	return ErrPlacementUnsupported
+}
@@ -0,0 +1,35 @@
+package mdevdriver
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+)
+
+// https://wiki.archlinux.org/title/Intel_GVT-g
+
+// GVTg is a vendor driver for Intel GVT-g devices.
+//
+// GVT-g devices can host virtual GPUs of different types at the same time
+// without any mode setup, and they don't have placement IDs.
+type GVTg struct {
+	Generic
+}
+
+// Capabilities reports that the physical device can host mediated devices
+// of different types at the same time if it offers more than one type.
+func (GVTg) Capabilities(pdev mdevfs.PhysicalDevice) (Capabilities, error) {
+	types, err := pdev.Types()
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to enumerate mediated device types of physical device %s: %v", pdev.Address(), err)
+	}
+	return Capabilities{Heterogeneous: len(types) > 1}, nil
+}
+
+// PrepareMode does nothing, because GVT-g devices are always able to host
+// mediated devices of different types.
+func (GVTg) PrepareMode(ctx context.Context, pdev mdevfs.PhysicalDevice, config machina.MediatedDevice, existing mdevfs.MediatedDeviceList) error {
+	return nil
+}
@@ -0,0 +1,105 @@
+package mdevdriver
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/exec"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/mdevfs"
+)
+
+// NVIDIA is a vendor driver for NVIDIA vGPU devices.
+//
+// NVIDIA devices that support placement IDs must be switched into
+// heterogeneous mode with nvidia-smi before they can host virtual GPUs of
+// different types, which is only possible while no virtual GPUs exist. The
+// placement of each virtual GPU within the physical device's memory can be
+// selected.
+type NVIDIA struct {
+	// SMI runs nvidia-smi with the given arguments. If it is nil, the
+	// nvidia-smi executable in the system path is invoked.
+	SMI func(ctx context.Context, args ...string) error
+}
+
+// nvidiaPlacementAttribute is the vendor-specific attribute of a mediated
+// device type that lists the placement IDs that can be created. It is only
+// present on physical devices that support placement IDs.
+const nvidiaPlacementAttribute = "nvidia/creatable_placements"
+
+// Capabilities probes the mediated device types of the physical device for
+// NVIDIA's placement attributes. Physical devices that support placement
+// IDs also support heterogeneous mode. Older devices support neither.
+func (NVIDIA) Capabilities(pdev mdevfs.PhysicalDevice) (Capabilities, error) {
+	types, err := pdev.Types()
+	if err != nil {
+		return Capabilities{}, fmt.Errorf("failed to enumerate mediated device types of physical device %s: %v", pdev.Address(), err)
+	}
+	for _, typ := range types {
+		found, err := typ.HasAttribute(nvidiaPlacementAttribute)
+		if err != nil {
+			return Capabilities{}, err
+		}
+		if found {
+			return Capabilities{Heterogeneous: true, Placement: true}, nil
+		}
+	}
+	return Capabilities{}, nil
+}
+
+// PrepareMode switches the physical device into heterogeneous mode if the
+// configuration calls for it and no mediated devices exist yet.
+func (driver NVIDIA) PrepareMode(ctx context.Context, pdev mdevfs.PhysicalDevice, config machina.MediatedDevice, existing mdevfs.MediatedDeviceList) error {
+	if !config.Heterogeneous || len(existing) > 0 {
+		return nil
+	}
+
+	smi := driver.SMI
+	if smi == nil {
+		smi = nvidiasmi
+	}
+
+	if err := smi(ctx, "vgpu", "--id", string(pdev.Address()), "-shm", "1"); err != nil {
+		return fmt.Errorf("failed to set heterogeneous mode for physcial device %s: %v", pdev.Address(), err)
+	}
+
+	return nil
+}
+
+// Placements returns the set of placement IDs in use by the given mediated
+// devices.
+func (NVIDIA) Placements(devices mdevfs.MediatedDeviceList) (machina.MediatedDevicePlacementSet, error) {
+	return devices.Placements()
+}
+
+// Placement returns the placement ID of a mediated device.
+func (NVIDIA) Placement(mdev mdevfs.MediatedDevice) (machina.MediatedDevicePlacementID, error) {
+	return mdev.Placement()
+}
+
+// ChangePlacement changes the placement ID of a mediated device.
+func (NVIDIA) ChangePlacement(mdev mdevfs.MediatedDevice, id machina.MediatedDevicePlacementID) error {
+	return mdev.ChangePlacement(id)
+}
+
+// nvidiasmi executes an nvidia-smi command with the given arguments. The
+// commands will be executed with stdin, stdout and stderr connected to
+// os.Stdin, os.Stdout and os.Stderr respectively.
+func nvidiasmi(ctx context.Context, args ...string) error {
+	path, err := exec.LookPath("nvidia-smi")
+	if err != nil {
+		return err
+	}
+
+	cmd := exec.CommandContext(ctx, path, args...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("failed to invoke nvidia-smi: %v", err)
+	}
+
+	return cmd.Wait()
+}
@@ -7,17 +7,95 @@ package machina
 // Spaces in particular could lead to argument parsing badness.
 type NetworkName string
 
+// NetworkType identifies the type of a network on the local system.
+type NetworkType string
+
+// Network types.
+const (
+	// TapNetwork connects machines to a bridge on the host through a tap
+	// device. It requires privileges to manage network interfaces on the
+	// host. It is the default network type.
+	TapNetwork = NetworkType("tap")
+
+	// UserNetwork connects machines to the host through QEMU's built-in
+	// user-mode network stack (slirp). It does not require any
+	// privileges on the host.
+	UserNetwork = NetworkType("user")
+
+	// PasstNetwork connects machines to the host through a user-mode
+	// network stack provided by passt. It does not require any privileges
+	// on the host.
+	PasstNetwork = NetworkType("passt")
+)
+
+// UserMode returns true if the network type is implemented entirely
+// by unprivileged user-mode processes, without host network interfaces.
+func (t NetworkType) UserMode() bool {
+	return t == UserNetwork || t == PasstNetwork
+}
+
+// TapMode identifies how tap devices are managed for a tap network.
+type TapMode string
+
+// Tap modes.
+const (
+	// ScriptTapMode causes QEMU to create a tap device for each connection
+	// when it starts, and to invoke the network's up and down scripts to
+	// manage it. It requires QEMU to run with CAP_NET_ADMIN. It is the
+	// default tap mode.
+	ScriptTapMode = TapMode("script")
+
+	// PersistentTapMode causes machina to create a persistent tap device for
+	// each connection during preparation and to attach it to the network.
+	// QEMU opens the prepared tap device without running any scripts, which
+	// allows it to run without CAP_NET_ADMIN. The tap device is removed
+	// during teardown.
+	PersistentTapMode = TapMode("persistent")
+)
+
 // NetworkMap maps network names to networks on the local system.
 type NetworkMap map[NetworkName]Network
 
 // Network defines a network that a machine can be connected to.
+//
+// Device, TapMode, Up and Down are only used by tap networks. Up and Down
+// are ignored when persistent taps are used.
 type Network struct {
-	Device string `json:"device"`
-	Up     string `json:"up"`
-	Down   string `json:"down"`
+	Type    NetworkType `json:"type,omitempty"`
+	Device  string      `json:"device"`
+	TapMode TapMode     `json:"tap-mode,omitempty"`
+	Up      string      `json:"up"`
+	Down    string      `json:"down"`
+}
+
+// EffectiveType returns the network's type. If a type has not been
+// specified it returns TapNetwork.
+func (n Network) EffectiveType() NetworkType {
+	if n.Type == "" {
+		return TapNetwork
+	}
+	return n.Type
+}
+
+// EffectiveTapMode returns the network's tap mode. If a tap mode has not
+// been specified it returns ScriptTapMode.
+func (n Network) EffectiveTapMode() TapMode {
+	if n.TapMode == "" {
+		return ScriptTapMode
+	}
+	return n.TapMode
+}
+
+// PersistentTaps returns true if machina is responsible for creating
+// persistent tap devices for the network.
+func (n Network) PersistentTaps() bool {
+	return n.EffectiveType() == TapNetwork && n.EffectiveTapMode() == PersistentTapMode
 }
 
 // String returns a string representation of the network configuration.
 func (n Network) String() string {
+	if typ := n.EffectiveType(); typ.UserMode() {
+		return string(typ)
+	}
 	return n.Device
 }
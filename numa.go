@@ -0,0 +1,187 @@
+package machina
+
+import (
+	"fmt"
+	"slices"
+
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
+	"github.com/gentlemanautomaton/machina/summary"
+)
+
+// MemoryPolicy determines how the memory of a guest NUMA node is allocated
+// from the host NUMA nodes it is bound to.
+type MemoryPolicy string
+
+// Memory policies.
+const (
+	// DefaultMemoryPolicy allocates memory according to the default policy
+	// of the QEMU process.
+	DefaultMemoryPolicy = MemoryPolicy("default")
+
+	// PreferredMemoryPolicy allocates memory from the host NUMA nodes when
+	// possible, and falls back to other nodes when they are exhausted.
+	PreferredMemoryPolicy = MemoryPolicy("preferred")
+
+	// BindMemoryPolicy only allocates memory from the host NUMA nodes. It
+	// is the policy used when host nodes are specified without a policy.
+	BindMemoryPolicy = MemoryPolicy("bind")
+
+	// InterleaveMemoryPolicy interleaves memory allocations across the
+	// host NUMA nodes.
+	InterleaveMemoryPolicy = MemoryPolicy("interleave")
+)
+
+// Valid returns true if p is a recognized memory policy.
+func (p MemoryPolicy) Valid() bool {
+	switch p {
+	case DefaultMemoryPolicy, PreferredMemoryPolicy, BindMemoryPolicy, InterleaveMemoryPolicy:
+		return true
+	default:
+		return false
+	}
+}
+
+// NUMA describes the non-uniform memory access topology of a machine.
+type NUMA struct {
+	Nodes []NUMANode `json:"nodes,omitempty"`
+}
+
+// NUMANode describes a guest NUMA node and the host NUMA nodes that its
+// memory is bound to.
+//
+// CPUs lists the guest vCPU indices that belong to the node, such as "0-3".
+// Memory is the amount of memory in the node, in mebibytes. HostNodes
+// lists the host NUMA nodes that supply the node's memory, such as "0".
+type NUMANode struct {
+	CPUs      string       `json:"cpus,omitempty"`
+	Memory    int          `json:"memory,omitempty"`
+	HostNodes string       `json:"host-nodes,omitempty"`
+	Policy    MemoryPolicy `json:"policy,omitempty"`
+}
+
+// EffectivePolicy returns the memory policy of the node. If the node is
+// bound to host nodes without a policy, BindMemoryPolicy is returned.
+func (node NUMANode) EffectivePolicy() MemoryPolicy {
+	if node.Policy == "" && node.HostNodes != "" {
+		return BindMemoryPolicy
+	}
+	return node.Policy
+}
+
+// Validate returns an error if the NUMA topology is invalid.
+func (n NUMA) Validate() error {
+	var assigned sysfs.CPUList
+	for i, node := range n.Nodes {
+		cpus, err := sysfs.ParseCPUList(node.CPUs)
+		if err != nil {
+			return fmt.Errorf("numa node %d: %w", i, err)
+		}
+		if len(cpus) == 0 {
+			return fmt.Errorf("numa node %d does not specify any vCPUs", i)
+		}
+		if assigned.Intersects(cpus) {
+			return fmt.Errorf("numa node %d includes vCPUs that belong to another node: %s", i, cpus)
+		}
+		assigned = append(assigned, cpus...)
+		slices.Sort(assigned)
+
+		if node.Memory <= 0 {
+			return fmt.Errorf("numa node %d does not specify an amount of memory", i)
+		}
+		if _, err := sysfs.ParseCPUList(node.HostNodes); err != nil {
+			return fmt.Errorf("numa node %d: invalid host nodes: %w", i, err)
+		}
+		if policy := node.Policy; policy != "" && !policy.Valid() {
+			return fmt.Errorf("numa node %d has an unrecognized memory policy: \"%s\"", i, policy)
+		}
+	}
+	return nil
+}
+
+// Memory returns the total amount of memory in all of the nodes, in
+// mebibytes.
+func (n NUMA) Memory() int {
+	var total int
+	for _, node := range n.Nodes {
+		total += node.Memory
+	}
+	return total
+}
+
+// Config adds the NUMA configuration to the summary.
+func (n *NUMA) Config(out summary.Interface) {
+	for i, node := range n.Nodes {
+		line := fmt.Sprintf("NUMA Node %d: vCPUs %s, %s", i, node.CPUs, qguest.MB(node.Memory).Size())
+		if node.HostNodes != "" {
+			line += fmt.Sprintf(", host nodes %s (%s)", node.HostNodes, node.EffectivePolicy())
+		}
+		out.Add("%s", line)
+	}
+}
+
+func overlayNUMA(merged, overlay *NUMA) {
+	if len(overlay.Nodes) > 0 {
+		merged.Nodes = overlay.Nodes
+	}
+}
+
+// Pinning describes the host CPUs that the threads of a machine are pinned
+// to. Each field holds a list of host CPUs, such as "2-9".
+//
+// If VCPUs lists at least as many host CPUs as the machine has vCPUs, each
+// vCPU is pinned to its own host CPU in order. Otherwise each vCPU may run
+// on any of the listed CPUs.
+//
+// IOThreads lists the host CPUs that the machine's I/O threads may run on.
+// Emulator lists the host CPUs that the rest of the QEMU process may run
+// on.
+type Pinning struct {
+	VCPUs     string `json:"vcpus,omitempty"`
+	IOThreads string `json:"iothreads,omitempty"`
+	Emulator  string `json:"emulator,omitempty"`
+}
+
+// IsEmpty returns true if no pinning has been specified.
+func (p Pinning) IsEmpty() bool {
+	return p.VCPUs == "" && p.IOThreads == "" && p.Emulator == ""
+}
+
+// Validate returns an error if the pinning contains an invalid CPU list.
+func (p Pinning) Validate() error {
+	if _, err := sysfs.ParseCPUList(p.VCPUs); err != nil {
+		return fmt.Errorf("vcpu pinning: %w", err)
+	}
+	if _, err := sysfs.ParseCPUList(p.IOThreads); err != nil {
+		return fmt.Errorf("iothread pinning: %w", err)
+	}
+	if _, err := sysfs.ParseCPUList(p.Emulator); err != nil {
+		return fmt.Errorf("emulator pinning: %w", err)
+	}
+	return nil
+}
+
+// Config adds the pinning configuration to the summary.
+func (p *Pinning) Config(out summary.Interface) {
+	if p.VCPUs != "" {
+		out.Add("vCPU Pinning: %s", p.VCPUs)
+	}
+	if p.IOThreads != "" {
+		out.Add("I/O Thread Pinning: %s", p.IOThreads)
+	}
+	if p.Emulator != "" {
+		out.Add("Emulator Pinning: %s", p.Emulator)
+	}
+}
+
+func overlayPinning(merged, overlay *Pinning) {
+	if overlay.VCPUs != "" {
+		merged.VCPUs = overlay.VCPUs
+	}
+	if overlay.IOThreads != "" {
+		merged.IOThreads = overlay.IOThreads
+	}
+	if overlay.Emulator != "" {
+		merged.Emulator = overlay.Emulator
+	}
+}
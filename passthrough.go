@@ -0,0 +1,127 @@
+package machina
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina/summary"
+)
+
+// PCIVendorDevice identifies a model of PCI device by its vendor and device
+// identifiers. It is expressed in the hexadecimal "vendor:device" form
+// reported by "lspci -n", such as "10de:1eb8".
+type PCIVendorDevice string
+
+// Split returns the vendor and device identifiers.
+func (id PCIVendorDevice) Split() (vendor, device string, ok bool) {
+	vendor, device, ok = strings.Cut(strings.ToLower(string(id)), ":")
+	if !ok || !isHex16(vendor) || !isHex16(device) {
+		return "", "", false
+	}
+	return vendor, device, true
+}
+
+// Valid returns true if the identifier is in the "vendor:device" form.
+func (id PCIVendorDevice) Valid() bool {
+	_, _, ok := id.Split()
+	return ok
+}
+
+// isHex16 returns true if s holds a 16-bit hexadecimal value.
+func isHex16(s string) bool {
+	if len(s) != 4 {
+		return false
+	}
+	for _, c := range s {
+		if !strings.ContainsRune("0123456789abcdef", c) {
+			return false
+		}
+	}
+	return true
+}
+
+// PassthroughDeviceName is the name of a passthrough device on the host
+// system.
+type PassthroughDeviceName string
+
+// PassthroughDevice describes a PCI device on the host system that can be
+// passed through in its entirety to a virtual machine via vfio-pci.
+//
+// The device is identified by its PCI address or by its vendor and device
+// identifiers. When only the vendor and device identifiers are provided,
+// exactly one matching device must be present on the host system.
+//
+// Machines claim passthrough devices by class. A machine device with the
+// class claims every passthrough device that supplies it, which allows
+// related functions of a physical device, such as the graphics and audio
+// functions of a GPU, to be claimed together.
+type PassthroughDevice struct {
+	Address DeviceAddress   `json:"address,omitempty"`
+	ID      PCIVendorDevice `json:"id,omitempty"`
+	Class   DeviceClass     `json:"class"`
+}
+
+// Validate returns an error if the passthrough device is not identified
+// correctly.
+func (dev PassthroughDevice) Validate() error {
+	if dev.Address == "" && dev.ID == "" {
+		return fmt.Errorf("passthrough device of class %s lacks both an address and a vendor:device identifier", dev.Class)
+	}
+	if dev.ID != "" && !dev.ID.Valid() {
+		return fmt.Errorf("passthrough device of class %s has an invalid vendor:device identifier: \"%s\"", dev.Class, dev.ID)
+	}
+	return nil
+}
+
+// String returns a string representation of the passthrough device.
+func (dev PassthroughDevice) String() string {
+	switch {
+	case dev.Address != "" && dev.ID != "":
+		return fmt.Sprintf("%s (%s)", dev.Address, dev.ID)
+	case dev.Address != "":
+		return string(dev.Address)
+	default:
+		return string(dev.ID)
+	}
+}
+
+// Config adds the passthrough device configuration to the summary.
+func (dev PassthroughDevice) Config(out summary.Interface) {
+	if dev.Address != "" {
+		out.Add("Address: %s", dev.Address)
+	}
+	if dev.ID != "" {
+		out.Add("Vendor and Device ID: %s", dev.ID)
+	}
+	out.Add("Supplied Device Class: %s", dev.Class)
+}
+
+// PassthroughDeviceList holds a sortable list of passthrough devices on the
+// host system.
+type PassthroughDeviceList []PassthroughDevice
+
+func (a PassthroughDeviceList) Len() int      { return len(a) }
+func (a PassthroughDeviceList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a PassthroughDeviceList) Less(i, j int) bool {
+	if c := strings.Compare(string(a[i].Address), string(a[j].Address)); c != 0 {
+		return c < 0
+	}
+	return strings.Compare(string(a[i].ID), string(a[j].ID)) < 0
+}
+
+// PassthroughDeviceMap describes a set of passthrough devices on the host
+// system.
+type PassthroughDeviceMap map[PassthroughDeviceName]PassthroughDevice
+
+// WithClass returns zero or more passthrough devices that supply the given
+// device class.
+func (m PassthroughDeviceMap) WithClass(class DeviceClass) (devices PassthroughDeviceList) {
+	for _, dev := range m {
+		if dev.Class == class {
+			devices = append(devices, dev)
+		}
+	}
+	sort.Stable(devices)
+	return devices
+}
@@ -12,6 +12,7 @@ const (
 	LinuxMachineDir        = "/etc/machina/machine.conf.d"
 	LinuxUnitDir           = "/etc/systemd/system"
 	LinuxRunDir            = "/run/machina"
+	LinuxStateDir          = "/var/lib/machina"
 	LinuxBashCompletionDir = "/usr/share/bash-completion/completions"
 )
 
@@ -43,3 +44,16 @@ func MakeTPMSocketPath(info MachineInfo) string {
 	}
 	return path.Join(LinuxRunDir, string(info.Name), "swtpm", "control.socket")
 }
+
+// MakePCIAddressMapPath returns the path of the file that records the PCI
+// addresses assigned to the devices of the given machine.
+//
+// If info lacks necessary details to build the path, it returns an empty
+// string.
+func MakePCIAddressMapPath(info MachineInfo) string {
+	// If we don't have a machine name we can't generate the built-in paths.
+	if info.Name == "" {
+		return ""
+	}
+	return path.Join(LinuxStateDir, string(info.Name), "pci-addresses.json")
+}
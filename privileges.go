@@ -9,6 +9,7 @@ import (
 // Privileges describe various privileges of a machine.
 type Privileges struct {
 	FileSystem FileSystemPrivileges `json:"filesystem,omitempty"`
+	Network    NetworkPrivileges    `json:"network,omitempty"`
 }
 
 // Populate returns a copy of the privileges with a file system access group
@@ -38,6 +39,7 @@ func (p Privileges) Populate(seed Seed) Privileges {
 // Config adds the privileges configuration to the summary.
 func (p *Privileges) Config(info MachineInfo, vars Vars, out summary.Interface) {
 	p.FileSystem.Config(out)
+	p.Network.Config(out)
 }
 
 // MergePrivileges merges a set of privileges in order. If a privilege value
@@ -46,6 +48,7 @@ func MergePrivileges(privs ...Privileges) Privileges {
 	var merged Privileges
 	for i := len(privs) - 1; i >= 0; i-- {
 		overlayFileSystemPrivileges(&merged.FileSystem, &privs[i].FileSystem)
+		overlayNetworkPrivileges(&merged.Network, &privs[i].Network)
 	}
 	return merged
 }
@@ -71,6 +74,29 @@ func overlayFileSystemPrivileges(merged, overlay *FileSystemPrivileges) {
 	}
 }
 
+// NetworkPrivileges describes the network privileges of a machine.
+//
+// When machina creates persistent tap devices for a machine, the tap devices
+// will be owned by the owner and by the machine's file system access group.
+// This allows QEMU to open the tap devices without CAP_NET_ADMIN when it
+// runs as the owner or as a member of the group.
+type NetworkPrivileges struct {
+	Owner UserID `json:"owner,omitempty"`
+}
+
+// Config adds the network privileges configuration to the summary.
+func (np *NetworkPrivileges) Config(out summary.Interface) {
+	if np.Owner != 0 {
+		out.Add("Network Device Owner: %d", np.Owner)
+	}
+}
+
+func overlayNetworkPrivileges(merged, overlay *NetworkPrivileges) {
+	if overlay.Owner != 0 {
+		merged.Owner = overlay.Owner
+	}
+}
+
 // UserID is the ID of a POSIX user.
 type UserID uint32
 
@@ -1,6 +1,10 @@
 package machina
 
-import "slices"
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
 
 // ProcessorName identifies a processor on the local system by a well-known name.
 type ProcessorName string
@@ -48,9 +52,40 @@ func (m ProcessorMap) Default() ProcessorName {
 }
 
 // Processor describes a processor that a machine can run on.
+//
+// If CPUModel is specified, machines running on the processor are presented
+// with the named QEMU CPU model, such as EPYC-v4 or Skylake-Server, instead
+// of the host's processor. This allows machines to migrate between hosts
+// with different processors. Flags enable or disable individual CPU
+// features.
 type Processor struct {
-	Brand          string `json:"brand"`
-	Model          string `json:"model"`
-	ThreadsPerCore int    `json:"threads"`
-	Default        bool   `json:"default"`
+	Brand          string    `json:"brand"`
+	Model          string    `json:"model"`
+	CPUModel       string    `json:"cpu-model,omitempty"`
+	Flags          []CPUFlag `json:"flags,omitempty"`
+	ThreadsPerCore int       `json:"threads"`
+	Default        bool      `json:"default"`
+}
+
+// CPUFlag enables or disables a CPU feature. Flags that start with a plus
+// sign enable the feature and flags that start with a minus sign disable
+// it, such as "+avx2" or "-hle".
+type CPUFlag string
+
+// Feature returns the name of the CPU feature.
+func (flag CPUFlag) Feature() string {
+	return string(flag)[1:]
+}
+
+// Enabled returns true if the flag enables its CPU feature.
+func (flag CPUFlag) Enabled() bool {
+	return strings.HasPrefix(string(flag), "+")
+}
+
+// Validate returns an error if the flag is invalid.
+func (flag CPUFlag) Validate() error {
+	if len(flag) < 2 || (flag[0] != '+' && flag[0] != '-') {
+		return fmt.Errorf("invalid CPU flag \"%s\": flags must start with + or -", flag)
+	}
+	return nil
 }
@@ -0,0 +1,82 @@
+package qdev
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// Key identifies a device that occupies a slot in the PCI Express Root
+// Complex, such as "vol.os" or "nic.lan".
+//
+// Devices that are added with a key can be pinned to a particular address,
+// which allows them to keep the same address when other devices are added
+// to or removed from the topology.
+type Key string
+
+// applyRoot applies the key to a PCI Express Root Port device.
+func (key Key) applyRoot(opts *rootOptions) {
+	opts.key = key
+}
+
+// AddrMap holds the PCI Express addresses assigned to keyed devices.
+type AddrMap map[Key]Addr
+
+// ParseAddr parses a PCI Express address in slot.function form, such as
+// "1.3".
+func ParseAddr(s string) (Addr, error) {
+	slot, function, ok := strings.Cut(s, ".")
+	if !ok {
+		return Addr{}, fmt.Errorf("invalid PCI address \"%s\": the address must be in slot.function form", s)
+	}
+	var (
+		addr Addr
+		err  error
+	)
+	if addr.Slot, err = strconv.Atoi(slot); err != nil {
+		return Addr{}, fmt.Errorf("invalid PCI address \"%s\": the slot is not a number", s)
+	}
+	if addr.Function, err = strconv.Atoi(function); err != nil {
+		return Addr{}, fmt.Errorf("invalid PCI address \"%s\": the function is not a number", s)
+	}
+	return addr, nil
+}
+
+// MarshalText returns the address in slot.function form.
+func (addr Addr) MarshalText() ([]byte, error) {
+	return []byte(addr.String()), nil
+}
+
+// UnmarshalText parses an address in slot.function form.
+func (addr *Addr) UnmarshalText(text []byte) error {
+	parsed, err := ParseAddr(string(text))
+	if err != nil {
+		return err
+	}
+	*addr = parsed
+	return nil
+}
+
+// rootComplexSlot is the first slot used by devices within the PCI Express
+// Root Complex.
+const rootComplexSlot = 1
+
+// rootComplexAddr returns the address of the device at the given index
+// within the PCI Express Root Complex.
+func rootComplexAddr(index int) Addr {
+	return Addr{Slot: index/MaxMultifunctionDevices + rootComplexSlot, Function: index % MaxMultifunctionDevices}
+}
+
+// rootComplexIndex returns the index of the device at the given address
+// within the PCI Express Root Complex. It returns false if the address is
+// not within the range used by the root complex.
+func rootComplexIndex(addr Addr) (index int, ok bool) {
+	if addr.Function < 0 || addr.Function >= MaxMultifunctionDevices || addr.Slot < rootComplexSlot {
+		return -1, false
+	}
+	index = (addr.Slot-rootComplexSlot)*MaxMultifunctionDevices + addr.Function
+	if index >= MaxRoots {
+		return -1, false
+	}
+	return index, true
+}
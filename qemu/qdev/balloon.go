@@ -0,0 +1,61 @@
+package qdev
+
+import "strconv"
+
+// BalloonOption is an option for a Virtio Memory Balloon device.
+type BalloonOption interface {
+	applyBalloon(*Balloon)
+}
+
+// FreePageReporting enables free page reporting for a Virtio Memory Balloon
+// device. When enabled, the guest reports pages it has freed to the host,
+// which allows the host to reclaim them.
+type FreePageReporting bool
+
+func (enabled FreePageReporting) applyBalloon(b *Balloon) {
+	b.freePageReporting = bool(enabled)
+}
+
+// StatsInterval is the interval in seconds at which the guest's memory
+// statistics are polled by a Virtio Memory Balloon device.
+type StatsInterval int
+
+func (interval StatsInterval) applyBalloon(b *Balloon) {
+	b.statsInterval = interval
+}
+
+// Balloon is a PCI Express Virtio Memory Balloon device. It allows the
+// memory available to a running guest to be adjusted.
+type Balloon struct {
+	id                ID
+	bus               ID
+	freePageReporting bool
+	statsInterval     StatsInterval
+}
+
+// ID returns the identifier of the Virtio Memory Balloon device.
+func (b Balloon) ID() ID {
+	return b.id
+}
+
+// Driver returns the driver for the Virtio Memory Balloon device,
+// virtio-balloon-pci.
+func (b Balloon) Driver() Driver {
+	return "virtio-balloon-pci"
+}
+
+// Properties returns the properties of the Virtio Memory Balloon device.
+func (b Balloon) Properties() Properties {
+	props := Properties{
+		{Name: string(b.Driver())},
+		{Name: "id", Value: string(b.id)},
+		{Name: "bus", Value: string(b.bus)},
+	}
+	if b.freePageReporting {
+		props.Add("free-page-reporting", "on")
+	}
+	if b.statsInterval > 0 {
+		props.Add("guest-stats-polling-interval", strconv.Itoa(int(b.statsInterval)))
+	}
+	return props
+}
@@ -0,0 +1,32 @@
+package qdev_test
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+)
+
+func ExampleBalloon() {
+	var topo qdev.Topology
+
+	// Add a PCI Express Root Port that we'll connect the balloon to
+	root, err := topo.AddRoot()
+	if err != nil {
+		panic(err)
+	}
+
+	// Add the balloon with free page reporting and statistics polling
+	if _, err := root.AddBalloon(qdev.FreePageReporting(true), qdev.StatsInterval(10)); err != nil {
+		panic(err)
+	}
+
+	// Print the configuration
+	options := topo.Options()
+	for _, option := range options {
+		fmt.Printf("%s\n", option)
+	}
+
+	// Output:
+	// -device ioh3420,id=pcie.1.0,chassis=0,bus=pcie.0,addr=1.0,multifunction=on
+	// -device virtio-balloon-pci,id=balloon.0,bus=pcie.1.0,free-page-reporting=on,guest-stats-polling-interval=10
+}
@@ -24,6 +24,25 @@ func (granularity DiscardGranularity) applyBlock(block *Block) {
 	block.discardGranularity = granularity
 }
 
+// DiskID is a device identifier for a QEMU disk device.
+//
+// Assigning a well-known identifier to a disk device allows it to be
+// targeted by QMP commands, such as device_del, while the virtual machine is
+// running.
+type DiskID ID
+
+func (id DiskID) applySCSIHD(disk *SCSIHD) {
+	disk.id = ID(id)
+}
+
+func (id DiskID) applySCSICD(cd *SCSICD) {
+	cd.id = ID(id)
+}
+
+func (id DiskID) applyBlock(block *Block) {
+	block.id = ID(id)
+}
+
 // BootOrder keeps track of the preferred order of boot devices.
 type BootOrder struct {
 	index int
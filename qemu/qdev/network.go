@@ -4,13 +4,36 @@ import (
 	"github.com/gentlemanautomaton/machina/qemu/qhost"
 )
 
+// NetworkOption is an option for a Virtio Network Controller device.
+type NetworkOption interface {
+	applyNetwork(*Network)
+}
+
+// NetworkID is a device identifier for a Virtio Network Controller device.
+//
+// Assigning a well-known identifier to a Network Controller allows it to be
+// targeted by QMP commands, such as set_link, while the virtual machine is
+// running.
+type NetworkID ID
+
+func (id NetworkID) applyNetwork(n *Network) {
+	n.id = ID(id)
+}
+
 // Network is a PCI Express Virtio Network Controller device.
 type Network struct {
+	id     ID
 	bus    ID
 	netdev qhost.ID
 	mac    string
 }
 
+// ID returns the identifier of the Network Controller device. It returns an
+// empty string if an identifier was not assigned.
+func (n Network) ID() ID {
+	return n.id
+}
+
 // Driver returns the driver used for the Network Controller device,
 // virtio-net-pci.
 func (n Network) Driver() Driver {
@@ -19,10 +42,13 @@ func (n Network) Driver() Driver {
 
 // Properties returns the properties of the Network Controller device.
 func (n Network) Properties() Properties {
-	return Properties{
-		{Name: string(n.Driver())},
-		{Name: "bus", Value: string(n.bus)},
-		{Name: "mac", Value: n.mac},
-		{Name: "netdev", Value: string(n.netdev)},
+	var props Properties
+	props.AddValue(string(n.Driver()))
+	if n.id != "" {
+		props.Add("id", string(n.id))
 	}
+	props.Add("bus", string(n.bus))
+	props.Add("mac", n.mac)
+	props.Add("netdev", string(n.netdev))
+	return props
 }
@@ -30,6 +30,16 @@ func ExampleNetwork() {
 		panic(err)
 	}
 
+	// Add a second Network Controller with a well-known device identifier,
+	// which allows its link state to be controlled via QMP
+	root, err = topo.AddRoot()
+	if err != nil {
+		panic(err)
+	}
+	if _, err := root.AddVirtioNetwork("00:00:00:00:00:01", tap, qdev.NetworkID("nic.lan")); err != nil {
+		panic(err)
+	}
+
 	// Print the configuration
 	options := topo.Options()
 	for _, option := range options {
@@ -39,4 +49,6 @@ func ExampleNetwork() {
 	// Output:
 	// -device ioh3420,id=pcie.1.0,chassis=0,bus=pcie.0,addr=1.0,multifunction=on
 	// -device virtio-net-pci,bus=pcie.1.0,mac=00:00:00:00:00:00,netdev=net.0
+	// -device ioh3420,id=pcie.1.1,chassis=1,bus=pcie.0,addr=1.1
+	// -device virtio-net-pci,id=nic.lan,bus=pcie.1.1,mac=00:00:00:00:00:01,netdev=net.0
 }
@@ -145,7 +145,7 @@ func (r *Root) AddVirtioBlock(thread qhost.IOThread, bdev blockdev.Node, options
 // PCI Express Root Port.
 //
 // TODO: Consider naming this AddNetwork.
-func (r *Root) AddVirtioNetwork(mac string, netdev qhost.NetDev) (Network, error) {
+func (r *Root) AddVirtioNetwork(mac string, netdev qhost.NetDev, options ...NetworkOption) (Network, error) {
 	if r.downstream != nil {
 		return Network{}, ErrDownstreamOccupied
 	}
@@ -154,6 +154,9 @@ func (r *Root) AddVirtioNetwork(mac string, netdev qhost.NetDev) (Network, error
 		mac:    mac,
 		netdev: netdev.ID(),
 	}
+	for _, opt := range options {
+		opt.applyNetwork(&network)
+	}
 	r.downstream = network
 	return network, nil
 }
@@ -176,6 +179,23 @@ func (r *Root) AddVFIO(device sysfs.Path) (VFIO, error) {
 	return vfio, nil
 }
 
+// AddBalloon connects a PCI Express Virtio Memory Balloon device to the
+// PCI Express Root Port.
+func (r *Root) AddBalloon(options ...BalloonOption) (Balloon, error) {
+	if r.downstream != nil {
+		return Balloon{}, ErrDownstreamOccupied
+	}
+	balloon := Balloon{
+		id:  r.buses.Allocate("balloon"),
+		bus: r.id,
+	}
+	for _, opt := range options {
+		opt.applyBalloon(&balloon)
+	}
+	r.downstream = balloon
+	return balloon, nil
+}
+
 // Connect connects a device to the PCI Express Root Port.
 //
 // This function should only be used for custom devices not already supplied
@@ -83,7 +83,7 @@ func (controller *SCSI) AddDisk(bdev blockdev.Node, options ...SCSIHDOption) (SC
 }
 
 // AddCD connects a SCSI CD-ROM device to the Virtio SCSI Controller.
-func (controller *SCSI) AddCD(bdev blockdev.Node) (SCSICD, error) {
+func (controller *SCSI) AddCD(bdev blockdev.Node, options ...SCSICDOption) (SCSICD, error) {
 	index, err := controller.allocate()
 	if err != nil {
 		return SCSICD{}, err
@@ -97,6 +97,9 @@ func (controller *SCSI) AddCD(bdev blockdev.Node) (SCSICD, error) {
 		lun:      index,
 		blockdev: bdev.Name(),
 	}
+	for _, opt := range options {
+		opt.applySCSICD(&cd)
+	}
 	controller.devices = append(controller.devices, cd)
 
 	return cd, nil
@@ -160,6 +163,11 @@ func (disk SCSIHD) Properties() Properties {
 	return props
 }
 
+// SCSICDOption is an option for a SCSI CD-ROM device.
+type SCSICDOption interface {
+	applySCSICD(*SCSICD)
+}
+
 // SCSICD is a SCSI CD-ROM device.
 type SCSICD struct {
 	id       ID
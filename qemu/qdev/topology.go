@@ -27,26 +27,79 @@ var (
 // TODO: Consider representing the PCI Express Root Complex with its own
 // struct.
 type Topology struct {
-	devices []Device
-	sata    int
-	buses   BusMap
+	devices  []Device
+	sata     int
+	buses    BusMap
+	pinned   AddrMap
+	reserved map[int]Key
+	used     map[int]bool
+	assigned AddrMap
+}
+
+// RootOption is an option for a PCI Express Root Port device.
+type RootOption interface {
+	applyRoot(*rootOptions)
+}
+
+type rootOptions struct {
+	key Key
+}
+
+// Pin reserves the given addresses for keyed devices. When a device with
+// a pinned key is added to the topology it is assigned its pinned address.
+// Devices without a pinned address are assigned the next address that is
+// neither in use nor reserved.
+//
+// Pin must be called before devices are added to the topology.
+func (t *Topology) Pin(addrs AddrMap) error {
+	if len(t.devices) > 0 {
+		return errors.New("addresses must be pinned before devices are added to the topology")
+	}
+	t.init()
+	for key, addr := range addrs {
+		index, ok := rootComplexIndex(addr)
+		if !ok {
+			return fmt.Errorf("the pinned address %s of \"%s\" is outside of the PCI Express root complex", addr, key)
+		}
+		if other, exists := t.reserved[index]; exists {
+			return fmt.Errorf("the pinned address %s is claimed by both \"%s\" and \"%s\"", addr, other, key)
+		}
+		t.pinned[key] = addr
+		t.reserved[index] = key
+	}
+	return nil
+}
+
+// Addrs returns the addresses that have been assigned to keyed devices
+// within the topology.
+func (t *Topology) Addrs() AddrMap {
+	addrs := make(AddrMap, len(t.assigned))
+	for key, addr := range t.assigned {
+		addrs[key] = addr
+	}
+	return addrs
 }
 
 // AddRoot adds a new PCI Express Root Port device to the PCI Express Root
 // Complex.
 //
+// If a Key is provided as an option and an address has been pinned for it,
+// the root port is assigned the pinned address.
+//
 // An error is returned if the addition would cause the root complex to exceed
 // MaxRoots.
-//
-// TODO: Consider allowing the caller to supply a preferred bus address.
-func (t *Topology) AddRoot() (*Root, error) {
-	index, err := t.allocate()
+func (t *Topology) AddRoot(options ...RootOption) (*Root, error) {
+	var opts rootOptions
+	for _, option := range options {
+		option.applyRoot(&opts)
+	}
+
+	index, err := t.allocate(opts.key)
 	if err != nil {
 		return nil, err
 	}
 
-	const startingSlot = 1
-	addr := Addr{Slot: index/MaxMultifunctionDevices + startingSlot, Function: index % MaxMultifunctionDevices}
+	addr := rootComplexAddr(index)
 	root := &Root{
 		id:      ID(fmt.Sprintf("pcie.%d.%d", addr.Slot, addr.Function)),
 		chassis: index,
@@ -58,20 +111,53 @@ func (t *Topology) AddRoot() (*Root, error) {
 	return root, nil
 }
 
+// AddSpareRoots adds count PCI Express Root Port devices to the PCI Express
+// Root Complex without connecting any devices to them. The spare root ports
+// are reserved for devices that are hotplugged while the virtual machine is
+// running.
+//
+// Spare root ports should be added after all other devices so that the
+// addresses of the other devices are not affected by the number of spare
+// root ports.
+func (t *Topology) AddSpareRoots(count int) ([]*Root, error) {
+	roots := make([]*Root, 0, count)
+	for i := 0; i < count; i++ {
+		root, err := t.AddRoot(Key(fmt.Sprintf("spare.%d", i)))
+		if err != nil {
+			return roots, err
+		}
+		roots = append(roots, root)
+	}
+	return roots, nil
+}
+
+// SpareRoots returns the PCI Express Root Port devices within the PCI
+// Express Root Complex that don't have a downstream device connected to
+// them.
+func (t *Topology) SpareRoots() []*Root {
+	var roots []*Root
+	for _, device := range t.devices {
+		if root, ok := device.(*Root); ok && root.Downstream() == nil {
+			roots = append(roots, root)
+		}
+	}
+	return roots
+}
+
 // AddQXL connects a PCI Express QXL display device to the PCI Express Root
 // Complex.
 func (t *Topology) AddQXL() (*QXL, error) {
-	index, err := t.allocate()
+	t.init()
+	key := Key(fmt.Sprintf("qxl.%d", t.buses.Count("qxl")))
+	index, err := t.allocate(key)
 	if err != nil {
 		return nil, err
 	}
 
-	const startingSlot = 1
-	addr := Addr{Slot: index/MaxMultifunctionDevices + startingSlot, Function: index % MaxMultifunctionDevices}
 	secondary := t.buses.Count("qxl") > 0
 	qxl := &QXL{
 		id:        t.buses.Allocate("qxl"),
-		addr:      addr,
+		addr:      rootComplexAddr(index),
 		secondary: secondary,
 	}
 	t.devices = append(t.devices, qxl)
@@ -81,16 +167,16 @@ func (t *Topology) AddQXL() (*QXL, error) {
 // AddPanic connects a paravirtualized panic device to the PCI Express Root
 // Complex as an integrated PCI device.
 func (t *Topology) AddPanic() (PVPanic, error) {
-	index, err := t.allocate()
+	t.init()
+	key := Key(fmt.Sprintf("panic.%d", t.buses.Count("panic")))
+	index, err := t.allocate(key)
 	if err != nil {
 		return PVPanic{}, err
 	}
 
-	const startingSlot = 1
-	addr := Addr{Slot: index/MaxMultifunctionDevices + startingSlot, Function: index % MaxMultifunctionDevices}
 	p := PVPanic{
 		id:   t.buses.Allocate("panic"),
-		addr: addr,
+		addr: rootComplexAddr(index),
 	}
 	t.devices = append(t.devices, p)
 	return p, nil
@@ -139,10 +225,24 @@ func (t *Topology) AddCDROM(bdev blockdev.Node) (SATACD, error) {
 
 // Devices returns all of the PCI Express Roots within the PCI Express Root
 // Complex.
+//
+// Guests don't probe the other functions of a slot when function 0 is
+// empty, which can happen when the device pinned to function 0 has been
+// removed. An empty PCI Express Root Port is included at function 0 of
+// such slots so that the other devices in the slot remain visible.
 func (t *Topology) Devices() []Device {
 	// Return a copy of the slice.
 	devices := make([]Device, 0, len(t.devices))
 	devices = append(devices, t.devices...)
+	for _, index := range vacantFunctionZero(t.used, t.used) {
+		addr := rootComplexAddr(index)
+		devices = append(devices, &Root{
+			id:      ID(fmt.Sprintf("pcie.%d.%d", addr.Slot, addr.Function)),
+			chassis: index,
+			addr:    addr,
+			buses:   t.buses,
+		})
+	}
 	return devices
 }
 
@@ -166,17 +266,103 @@ func (t *Topology) Options() qemu.Options {
 	return opts
 }
 
-func (t *Topology) allocate() (index int, err error) {
+func (t *Topology) init() {
 	if t.devices == nil {
 		t.devices = make([]Device, 0, MaxRoots)
 	}
 	if t.buses == nil {
 		t.buses = make(BusMap)
 	}
+	if t.pinned == nil {
+		t.pinned = make(AddrMap)
+	}
+	if t.reserved == nil {
+		t.reserved = make(map[int]Key)
+	}
+	if t.used == nil {
+		t.used = make(map[int]bool)
+	}
+	if t.assigned == nil {
+		t.assigned = make(AddrMap)
+	}
+}
+
+// allocate returns the index of the next device in the PCI Express Root
+// Complex. If key has a pinned address, the index of that address is
+// returned.
+func (t *Topology) allocate(key Key) (index int, err error) {
+	t.init()
 
 	if len(t.devices)+1 > MaxRoots {
 		return -1, ErrRootComplexFull
 	}
 
-	return len(t.devices), nil
+	if key != "" {
+		if _, exists := t.assigned[key]; exists {
+			return -1, fmt.Errorf("a device with the key \"%s\" has already been added to the topology", key)
+		}
+	}
+
+	if addr, pinned := t.pinned[key]; key != "" && pinned {
+		index, _ = rootComplexIndex(addr)
+		if t.used[index] {
+			return -1, fmt.Errorf("the pinned address %s of \"%s\" is already in use", addr, key)
+		}
+	} else {
+		// Fill function 0 of slots that hold other devices first, because
+		// guests don't probe the other functions of a slot without it.
+		// Otherwise skip addresses that are in use or reserved for other
+		// devices.
+		occupied := t.occupied()
+		if vacant := vacantFunctionZero(occupied, occupied); len(vacant) > 0 {
+			index = vacant[0]
+		} else {
+			index = len(t.devices)
+			for index < MaxRoots && occupied[index] {
+				index++
+			}
+			if index >= MaxRoots {
+				return -1, ErrRootComplexFull
+			}
+		}
+	}
+
+	t.used[index] = true
+	if key != "" {
+		t.assigned[key] = rootComplexAddr(index)
+	}
+
+	return index, nil
+}
+
+// occupied returns the indices in the PCI Express Root Complex that are
+// either in use or reserved for pinned devices.
+func (t *Topology) occupied() map[int]bool {
+	occupied := make(map[int]bool, len(t.used)+len(t.reserved))
+	for index := range t.used {
+		occupied[index] = true
+	}
+	for index := range t.reserved {
+		occupied[index] = true
+	}
+	return occupied
+}
+
+// vacantFunctionZero returns the indices in the PCI Express Root Complex
+// of function 0 of each slot in which function 0 is absent from taken while
+// another function of the slot is present, in ascending order.
+func vacantFunctionZero(taken, present map[int]bool) []int {
+	var vacant []int
+	for first := 0; first < MaxRoots; first += MaxMultifunctionDevices {
+		if taken[first] {
+			continue
+		}
+		for index := first + 1; index < first+MaxMultifunctionDevices; index++ {
+			if present[index] {
+				vacant = append(vacant, first)
+				break
+			}
+		}
+	}
+	return vacant
 }
@@ -0,0 +1,133 @@
+package qdev_test
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+)
+
+func ExampleTopology_AddSpareRoots() {
+	var topo qdev.Topology
+
+	// Add a PCI Express Root Port with a USB Controller
+	root, err := topo.AddRoot()
+	if err != nil {
+		panic(err)
+	}
+	if _, err := root.AddUSB(); err != nil {
+		panic(err)
+	}
+
+	// Reserve two PCI Express Root Ports for hotplugged devices
+	if _, err := topo.AddSpareRoots(2); err != nil {
+		panic(err)
+	}
+
+	// Print the configuration
+	for _, option := range topo.Options() {
+		fmt.Printf("%s\n", option)
+	}
+
+	// Print the spare root ports
+	for _, spare := range topo.SpareRoots() {
+		fmt.Printf("spare: %s\n", spare.ID())
+	}
+
+	// Output:
+	// -device ioh3420,id=pcie.1.0,chassis=0,bus=pcie.0,addr=1.0,multifunction=on
+	// -device qemu-xhci,id=usb,bus=pcie.1.0,p2=4,p3=4
+	// -device ioh3420,id=pcie.1.1,chassis=1,bus=pcie.0,addr=1.1
+	// -device ioh3420,id=pcie.1.2,chassis=2,bus=pcie.0,addr=1.2
+	// spare: pcie.1.1
+	// spare: pcie.1.2
+}
+
+func ExampleTopology_Pin() {
+	var topo qdev.Topology
+
+	// Pin the address of a network controller that was assigned when the
+	// machine only had a network connection.
+	if err := topo.Pin(qdev.AddrMap{"nic.lan": {Slot: 1, Function: 0}}); err != nil {
+		panic(err)
+	}
+
+	// Add a Virtio Block device for a new volume before the network
+	// controller. It is assigned the next free address.
+	vol, err := topo.AddRoot(qdev.Key("vol.data"))
+	if err != nil {
+		panic(err)
+	}
+
+	// Add the network controller, which keeps its pinned address.
+	nic, err := topo.AddRoot(qdev.Key("nic.lan"))
+	if err != nil {
+		panic(err)
+	}
+
+	fmt.Printf("vol.data: %s\n", vol.ID())
+	fmt.Printf("nic.lan: %s\n", nic.ID())
+
+	// Print the addresses to be pinned for future builds
+	data, err := json.Marshal(topo.Addrs())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("%s\n", data)
+
+	// Output:
+	// vol.data: pcie.1.1
+	// nic.lan: pcie.1.0
+	// {"nic.lan":"1.0","vol.data":"1.1"}
+}
+
+func ExampleTopology_Pin_removed() {
+	// Build a topology with the given pinned addresses and keyed devices.
+	build := func(addrs qdev.AddrMap, keys ...qdev.Key) *qdev.Topology {
+		var topo qdev.Topology
+		if err := topo.Pin(addrs); err != nil {
+			panic(err)
+		}
+		for _, key := range keys {
+			if _, err := topo.AddRoot(key); err != nil {
+				panic(err)
+			}
+		}
+		return &topo
+	}
+
+	// Pin the addresses that were assigned when the machine had more
+	// devices. The devices at 1.0 and 2.0 have since been removed, so
+	// function 0 of both slots is filled with an empty root port to keep
+	// the other devices in those slots visible to the guest.
+	first := build(qdev.AddrMap{
+		"vol.old":  {Slot: 1, Function: 0},
+		"vol.data": {Slot: 1, Function: 1},
+		"nic.old":  {Slot: 2, Function: 0},
+		"nic.lan":  {Slot: 2, Function: 1},
+	}, "vol.data", "nic.lan", "vol.new")
+
+	fmt.Println("first build:")
+	for _, option := range first.Options() {
+		fmt.Printf("%s\n", option)
+	}
+
+	// In the next build the removed devices are no longer pinned, so a new
+	// device fills function 0 of the first slot.
+	second := build(first.Addrs(), "vol.data", "nic.lan", "vol.new", "vol.extra")
+
+	data, err := json.Marshal(second.Addrs())
+	if err != nil {
+		panic(err)
+	}
+	fmt.Printf("second build: %s\n", data)
+
+	// Output:
+	// first build:
+	// -device ioh3420,id=pcie.1.1,chassis=1,bus=pcie.0,addr=1.1
+	// -device ioh3420,id=pcie.2.1,chassis=9,bus=pcie.0,addr=2.1
+	// -device ioh3420,id=pcie.1.2,chassis=2,bus=pcie.0,addr=1.2
+	// -device ioh3420,id=pcie.1.0,chassis=0,bus=pcie.0,addr=1.0,multifunction=on
+	// -device ioh3420,id=pcie.2.0,chassis=8,bus=pcie.0,addr=2.0,multifunction=on
+	// second build: {"nic.lan":"2.1","vol.data":"1.1","vol.extra":"1.0","vol.new":"1.2"}
+}
@@ -95,3 +95,41 @@ func ExampleUSBAttachedSCSI() {
 	// -device usb-uas,id=uas,bus=usb.0,port=1
 	// -device scsi-hd,id=uas.0.0,bus=uas.0,channel=0,scsi-id=0,lun=0,drive=uas-disk
 }
+
+func ExampleUSBHost() {
+	var topo qdev.Topology
+
+	// Add a PCI Express Root Port that we'll connect the USB Controller to
+	root, err := topo.AddRoot()
+	if err != nil {
+		panic(err)
+	}
+
+	// Add a USB Controller
+	usb, err := root.AddUSB()
+	if err != nil {
+		panic(err)
+	}
+
+	// Pass through a host device by its vendor and product identifiers
+	if _, err := usb.AddHost(qdev.USBHostSelector{VendorID: 0x046d, ProductID: 0xc52b}, qdev.USBHostID("usbhost.dongle.0")); err != nil {
+		panic(err)
+	}
+
+	// Pass through whatever host device is plugged into bus 3, port 1.2
+	if _, err := usb.AddHost(qdev.USBHostSelector{HostBus: 3, HostPort: "1.2"}); err != nil {
+		panic(err)
+	}
+
+	// Print the configuration
+	options := topo.Options()
+	for _, option := range options {
+		fmt.Printf("%s\n", option)
+	}
+
+	// Output:
+	// -device ioh3420,id=pcie.1.0,chassis=0,bus=pcie.0,addr=1.0,multifunction=on
+	// -device qemu-xhci,id=usb,bus=pcie.1.0,p2=4,p3=4
+	// -device usb-host,id=usbhost.dongle.0,bus=usb.0,port=1,vendorid=0x046d,productid=0xc52b
+	// -device usb-host,id=usb.0.2,bus=usb.0,port=2,hostbus=3,hostport=1.2
+}
@@ -0,0 +1,91 @@
+package qdev
+
+import (
+	"fmt"
+	"strconv"
+)
+
+// USBHostSelector identifies a USB device on the host system that is
+// passed through to the guest. A device is selected either by its vendor
+// and product identifiers, or by the bus number and port path that it is
+// plugged into.
+type USBHostSelector struct {
+	VendorID  uint16
+	ProductID uint16
+	HostBus   int
+	HostPort  string
+}
+
+// USBHostOption is an option for a USB Host device.
+type USBHostOption interface {
+	applyUSBHost(*USBHost)
+}
+
+// USBHostID is a device identifier for a USB Host device.
+//
+// Assigning a well-known identifier to a USB Host device allows it to be
+// removed and added again with QMP commands while the virtual machine is
+// running.
+type USBHostID ID
+
+func (id USBHostID) applyUSBHost(host *USBHost) {
+	host.id = ID(id)
+}
+
+// AddHost connects a USB device on the host system to the xHCI Controller.
+func (controller *USB) AddHost(selector USBHostSelector, options ...USBHostOption) (USBHost, error) {
+	index, err := controller.allocate()
+	if err != nil {
+		return USBHost{}, err
+	}
+
+	host := USBHost{
+		id:       controller.id.Downstream(strconv.Itoa(index)),
+		bus:      controller.id,
+		port:     index,
+		selector: selector,
+	}
+	for _, option := range options {
+		option.applyUSBHost(&host)
+	}
+	controller.devices = append(controller.devices, host)
+
+	return host, nil
+}
+
+// USBHost is a USB device on the host system that is passed through to the
+// guest.
+type USBHost struct {
+	id       ID
+	bus      ID
+	port     int
+	selector USBHostSelector
+}
+
+// ID returns the identifier of the USB Host device.
+func (host USBHost) ID() ID {
+	return host.id
+}
+
+// Driver returns the driver for the USB Host device, usb-host.
+func (host USBHost) Driver() Driver {
+	return "usb-host"
+}
+
+// Properties returns the properties of the USB Host device.
+func (host USBHost) Properties() Properties {
+	props := Properties{
+		{Name: string(host.Driver())},
+		{Name: "id", Value: string(host.id)},
+		{Name: "bus", Value: string(host.bus)},
+		{Name: "port", Value: strconv.Itoa(host.port)},
+	}
+	if host.selector.HostBus != 0 || host.selector.HostPort != "" {
+		props.Add("hostbus", strconv.Itoa(host.selector.HostBus))
+		props.Add("hostport", host.selector.HostPort)
+	} else {
+		props.Add("vendorid", fmt.Sprintf("0x%04x", host.selector.VendorID))
+		props.Add("productid", fmt.Sprintf("0x%04x", host.selector.ProductID))
+	}
+	return props
+}
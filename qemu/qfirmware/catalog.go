@@ -0,0 +1,143 @@
+package qfirmware
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path"
+	"slices"
+	"strings"
+)
+
+// DefaultDirs lists the directories that firmware descriptors are read
+// from, in order of precedence.
+var DefaultDirs = []string{
+	"/etc/qemu/firmware",
+	"/usr/share/qemu/firmware",
+}
+
+// ErrNotFound is returned when none of the firmware descriptors satisfy
+// a set of requirements.
+var ErrNotFound = errors.New("no firmware descriptor satisfies the requirements")
+
+// Catalog is a list of firmware descriptors in order of priority.
+type Catalog []Descriptor
+
+// Local returns a catalog of the firmware descriptors in DefaultDirs.
+func Local() (Catalog, error) {
+	dirs := make([]fs.FS, len(DefaultDirs))
+	for i, dir := range DefaultDirs {
+		dirs[i] = os.DirFS(dir)
+	}
+	return Load(dirs...)
+}
+
+// Load reads the firmware descriptors in the given directories and returns
+// them in order of priority.
+//
+// Following QEMU's conventions, descriptors are ordered by file name. When
+// more than one directory holds a file with the same name, the file in the
+// earliest directory is used. An empty file masks files with the same name
+// in later directories. Directories that don't exist are ignored.
+func Load(dirs ...fs.FS) (Catalog, error) {
+	files := make(map[string]fs.FS)
+	for _, dir := range dirs {
+		entries, err := fs.ReadDir(dir, ".")
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				continue
+			}
+			return nil, err
+		}
+		for _, entry := range entries {
+			name := entry.Name()
+			if entry.IsDir() || path.Ext(name) != ".json" {
+				continue
+			}
+			if _, exists := files[name]; !exists {
+				files[name] = dir
+			}
+		}
+	}
+
+	names := make([]string, 0, len(files))
+	for name := range files {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+
+	var catalog Catalog
+	for _, name := range names {
+		data, err := fs.ReadFile(files[name], name)
+		if err != nil {
+			return nil, err
+		}
+		if len(strings.TrimSpace(string(data))) == 0 {
+			continue
+		}
+		var desc Descriptor
+		if err := json.Unmarshal(data, &desc); err != nil {
+			return nil, fmt.Errorf("failed to parse firmware descriptor \"%s\": %w", name, err)
+		}
+		catalog = append(catalog, desc)
+	}
+
+	return catalog, nil
+}
+
+// Requirements describe the firmware that is needed by a machine.
+//
+// Machine is matched against the machine patterns of each descriptor. It
+// can include wildcards, such as pc-q35-*, which only match descriptors
+// that support every version of a machine type.
+type Requirements struct {
+	Interface    Interface
+	Architecture string
+	Machine      string
+
+	// Features lists the features that the firmware must have.
+	Features []Feature
+
+	// Exclude lists the features that the firmware must not have.
+	Exclude []Feature
+}
+
+// Select returns the first descriptor in the catalog that satisfies the
+// requirements and can be loaded through split flash devices with raw
+// images. It returns ErrNotFound if none of the descriptors are suitable.
+func (c Catalog) Select(req Requirements) (Descriptor, error) {
+	for _, desc := range c {
+		if !desc.HasInterface(req.Interface) || !desc.SupportsMachine(req.Architecture, req.Machine) {
+			continue
+		}
+		mapping := desc.Mapping
+		if mapping.Device != "flash" || mapping.EffectiveMode() != "split" {
+			continue
+		}
+		if mapping.Executable.Format != "raw" || mapping.NVRAMTemplate.Format != "raw" {
+			continue
+		}
+		if req.satisfiedBy(desc) {
+			return desc, nil
+		}
+	}
+	return Descriptor{}, ErrNotFound
+}
+
+// satisfiedBy returns true if desc has all of the required features and
+// none of the excluded features.
+func (req Requirements) satisfiedBy(desc Descriptor) bool {
+	for _, feature := range req.Features {
+		if !desc.HasFeature(feature) {
+			return false
+		}
+	}
+	for _, feature := range req.Exclude {
+		if desc.HasFeature(feature) {
+			return false
+		}
+	}
+	return true
+}
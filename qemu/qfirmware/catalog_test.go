@@ -0,0 +1,99 @@
+package qfirmware_test
+
+import (
+	"errors"
+	"testing"
+	"testing/fstest"
+
+	"github.com/gentlemanautomaton/machina/qemu/qfirmware"
+)
+
+const (
+	secureBootDescriptor = `{
+		"description": "OVMF with Secure Boot and enrolled keys",
+		"interface-types": ["uefi"],
+		"mapping": {
+			"device": "flash",
+			"executable": {"filename": "/usr/share/OVMF/OVMF_CODE_4M.ms.fd", "format": "raw"},
+			"nvram-template": {"filename": "/usr/share/OVMF/OVMF_VARS_4M.ms.fd", "format": "raw"}
+		},
+		"targets": [{"architecture": "x86_64", "machines": ["pc-q35-*"]}],
+		"features": ["acpi-s3", "enrolled-keys", "requires-smm", "secure-boot"]
+	}`
+	plainDescriptor = `{
+		"description": "OVMF without Secure Boot",
+		"interface-types": ["uefi"],
+		"mapping": {
+			"device": "flash",
+			"mode": "split",
+			"executable": {"filename": "/usr/share/OVMF/OVMF_CODE_4M.fd", "format": "raw"},
+			"nvram-template": {"filename": "/usr/share/OVMF/OVMF_VARS_4M.fd", "format": "raw"}
+		},
+		"targets": [{"architecture": "x86_64", "machines": ["pc-i440fx-*", "pc-q35-*"]}],
+		"features": ["acpi-s3"]
+	}`
+	qcow2Descriptor = `{
+		"description": "OVMF in qcow2 format",
+		"interface-types": ["uefi"],
+		"mapping": {
+			"device": "flash",
+			"executable": {"filename": "/usr/share/OVMF/OVMF_CODE.qcow2", "format": "qcow2"},
+			"nvram-template": {"filename": "/usr/share/OVMF/OVMF_VARS.qcow2", "format": "qcow2"}
+		},
+		"targets": [{"architecture": "x86_64", "machines": ["pc-q35-*"]}],
+		"features": []
+	}`
+)
+
+func TestCatalogSelect(t *testing.T) {
+	system := fstest.MapFS{
+		"10-qcow2.json":      {Data: []byte(qcow2Descriptor)},
+		"30-secureboot.json": {Data: []byte(secureBootDescriptor)},
+		"40-plain.json":      {Data: []byte(plainDescriptor)},
+		"50-masked.json":     {Data: []byte(plainDescriptor)},
+		"README":             {Data: []byte("not a descriptor")},
+	}
+	admin := fstest.MapFS{
+		"50-masked.json": {Data: []byte{}},
+	}
+
+	catalog, err := qfirmware.Load(admin, system)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(catalog) != 3 {
+		t.Fatalf("unexpected number of descriptors: %d", len(catalog))
+	}
+
+	base := qfirmware.Requirements{
+		Interface:    qfirmware.UEFI,
+		Architecture: "x86_64",
+		Machine:      "pc-q35-*",
+	}
+
+	secure := base
+	secure.Features = []qfirmware.Feature{qfirmware.FeatureSecureBoot, qfirmware.FeatureEnrolledKeys}
+	desc, err := catalog.Select(secure)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := desc.Mapping.NVRAMTemplate.Filename, "/usr/share/OVMF/OVMF_VARS_4M.ms.fd"; got != want {
+		t.Errorf("unexpected secure boot vars template: got %s, want %s", got, want)
+	}
+
+	plain := base
+	plain.Exclude = []qfirmware.Feature{qfirmware.FeatureEnrolledKeys}
+	desc, err = catalog.Select(plain)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := desc.Mapping.Executable.Filename, "/usr/share/OVMF/OVMF_CODE_4M.fd"; got != want {
+		t.Errorf("unexpected firmware code: got %s, want %s", got, want)
+	}
+
+	sev := base
+	sev.Features = []qfirmware.Feature{qfirmware.FeatureAMDSEV}
+	if _, err := catalog.Select(sev); !errors.Is(err, qfirmware.ErrNotFound) {
+		t.Errorf("expected ErrNotFound for SEV firmware, got %v", err)
+	}
+}
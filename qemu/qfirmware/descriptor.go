@@ -0,0 +1,102 @@
+package qfirmware
+
+import (
+	"path"
+	"slices"
+)
+
+// Interface is a firmware interface type, such as uefi.
+type Interface string
+
+// Firmware interface types.
+const (
+	BIOS = Interface("bios")
+	UEFI = Interface("uefi")
+)
+
+// Feature is a firmware feature, such as secure-boot.
+type Feature string
+
+// Firmware features.
+const (
+	FeatureAMDSEV       = Feature("amd-sev")
+	FeatureAMDSEVES     = Feature("amd-sev-es")
+	FeatureEnrolledKeys = Feature("enrolled-keys")
+	FeatureRequiresSMM  = Feature("requires-smm")
+	FeatureSecureBoot   = Feature("secure-boot")
+)
+
+// Descriptor describes a firmware build.
+type Descriptor struct {
+	Description    string      `json:"description"`
+	InterfaceTypes []Interface `json:"interface-types"`
+	Mapping        Mapping     `json:"mapping"`
+	Targets        []Target    `json:"targets"`
+	Features       []Feature   `json:"features"`
+	Tags           []string    `json:"tags,omitempty"`
+}
+
+// HasInterface returns true if the firmware supports the given interface.
+func (d Descriptor) HasInterface(iface Interface) bool {
+	return slices.Contains(d.InterfaceTypes, iface)
+}
+
+// HasFeature returns true if the firmware has the given feature.
+func (d Descriptor) HasFeature(feature Feature) bool {
+	return slices.Contains(d.Features, feature)
+}
+
+// SupportsMachine returns true if the firmware can be used by the given
+// architecture and machine type, such as x86_64 and pc-q35-8.2.
+func (d Descriptor) SupportsMachine(arch, machine string) bool {
+	for _, target := range d.Targets {
+		if target.Architecture != arch {
+			continue
+		}
+		for _, pattern := range target.Machines {
+			if matched, _ := path.Match(pattern, machine); matched {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// Mapping describes how the firmware is mapped into guest memory.
+type Mapping struct {
+	// Device is the kind of device the firmware is loaded by, such as
+	// flash or memory.
+	Device string `json:"device"`
+
+	// Mode is the flash mode, such as split, combined or stateless. It is
+	// split when not specified.
+	Mode string `json:"mode,omitempty"`
+
+	// Executable is the firmware code.
+	Executable File `json:"executable"`
+
+	// NVRAMTemplate is the template for the firmware variables of each
+	// machine. It is only present in split mode.
+	NVRAMTemplate File `json:"nvram-template"`
+}
+
+// EffectiveMode returns the flash mode of the mapping.
+func (m Mapping) EffectiveMode() string {
+	if m.Mode == "" {
+		return "split"
+	}
+	return m.Mode
+}
+
+// File describes a firmware file.
+type File struct {
+	Filename string `json:"filename"`
+	Format   string `json:"format"`
+}
+
+// Target describes an architecture and set of machine types that can run
+// the firmware.
+type Target struct {
+	Architecture string   `json:"architecture"`
+	Machines     []string `json:"machines"`
+}
@@ -0,0 +1,7 @@
+// Package qfirmware reads QEMU firmware descriptors and selects firmware
+// that matches a set of requirements.
+//
+// Firmware descriptors are JSON files installed by firmware packages, such
+// as OVMF, that describe the interfaces and features of each firmware
+// build. The format is documented in QEMU's docs/interop/firmware.json.
+package qfirmware
@@ -37,11 +37,36 @@ const (
 	ClockDriftFixSlew = ClockDriftFix("slew")
 )
 
+// TickPolicy specifies what the in-kernel programmable interval timer does
+// with timer interrupts that a VM missed. It is applied as the
+// lost_tick_policy global property of the kvm-pit driver.
+type TickPolicy string
+
+// Possible TickPolicy values.
+const (
+	TickPolicyDelay   = TickPolicy("delay")
+	TickPolicyDiscard = TickPolicy("discard")
+)
+
 // Clock configures the real time clock for a virtual machine.
+//
+// If DisableHPET is true, the high precision event timer is removed from
+// the machine.
 type Clock struct {
-	Base      ClockBase
-	Isolation ClockIsolation
-	DriftFix  ClockDriftFix
+	Base        ClockBase
+	Isolation   ClockIsolation
+	DriftFix    ClockDriftFix
+	DisableHPET bool
+}
+
+// MachineParameters returns the machine parameters for the clock
+// configuration.
+func (c Clock) MachineParameters() qemu.Parameters {
+	var params qemu.Parameters
+	if c.DisableHPET {
+		params.Add("hpet", "off")
+	}
+	return params
 }
 
 // Parameters returns the parameters used for configuring the real time clock.
@@ -0,0 +1,25 @@
+package qguest_test
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
+)
+
+func ExampleClock() {
+	clock := qguest.Clock{
+		Base:        qguest.ClockBaseLocal,
+		Isolation:   qguest.ClockIsolationHost,
+		DriftFix:    qguest.ClockDriftFixSlew,
+		DisableHPET: true,
+	}
+
+	fmt.Println(clock.MachineParameters().String())
+	for _, option := range clock.Options() {
+		fmt.Println(option)
+	}
+
+	// Output:
+	// hpet=off
+	// -rtc base=localtime,clock=host,driftfix=slew
+}
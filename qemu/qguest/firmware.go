@@ -11,9 +11,14 @@ import (
 // https://github.com/qemu/qemu/commit/ebc29e1beab02646702c8cb9a1d29b68f72ad503
 
 // Firmware holds firmware configuration for a QEMU virtual machine.
+//
+// If SMM is true, System Management Mode is enabled, which is required by
+// firmware that protects its variables with it, such as Secure Boot builds
+// of OVMF.
 type Firmware struct {
 	Code blockdev.NodeName
 	Vars blockdev.NodeName
+	SMM  bool
 }
 
 // MachineParameters returns a set of machine parameters for the firmware.
@@ -25,5 +30,8 @@ func (f Firmware) MachineParameters() qemu.Parameters {
 			params.Add("pflash1", string(f.Vars))
 		}
 	}
+	if f.SMM {
+		params.Add("smm", "on")
+	}
 	return params
 }
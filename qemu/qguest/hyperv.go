@@ -0,0 +1,126 @@
+package qguest
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina/qemu"
+)
+
+// Enlightenment is a Hyper-V enlightenment that is presented to the guest.
+// Its name is the name of the CPU feature without the "hv-" prefix, such
+// as "relaxed" or "spinlocks".
+type Enlightenment struct {
+	Name  string
+	Value string
+}
+
+// Parameter returns the CPU parameter for the enlightenment.
+func (e Enlightenment) Parameter() qemu.Parameter {
+	return qemu.Parameter{Name: "hv-" + e.Name, Value: e.Value}
+}
+
+// StandardEnlightenments returns the set of Hyper-V enlightenments that
+// are recommended for modern Windows guests running on a processor with
+// the given brand.
+func StandardEnlightenments(brand string) []Enlightenment {
+	set := []Enlightenment{
+		{Name: "relaxed"},
+		{Name: "vapic"},
+		{Name: "spinlocks", Value: "0x1fff"},
+		{Name: "vpindex"},
+		{Name: "runtime"},
+		{Name: "time"},
+		{Name: "synic"},
+		{Name: "stimer"},
+		{Name: "tlbflush"},
+		{Name: "ipi"},
+		{Name: "frequencies"},
+		{Name: "reenlightenment"},
+		{Name: "stimer-direct"},
+		{Name: "emsr-bitmap"},
+		{Name: "xmm-input"},
+		{Name: "tlbflush-ext"},
+		{Name: "tlbflush-direct"},
+	}
+
+	// Special Hyper-V enablements for AMD processors.
+	if strings.EqualFold(brand, "AMD") {
+		set = append(set, Enlightenment{Name: "avic", Value: "on"})
+	}
+
+	// Special Hyper-V enablements for Intel processors.
+	if strings.EqualFold(brand, "Intel") {
+		set = append(set, Enlightenment{Name: "evmcs"})
+	}
+
+	// hv-no-nonarch-coresharing is left out even when vCPUs are pinned.
+	// It promises the guest that its vCPUs never share a physical core
+	// with other workloads, which pinning alone doesn't guarantee, and
+	// Windows skips some of its side-channel mitigations when it's set.
+	// It can be enabled explicitly when the host cores are dedicated.
+
+	return set
+}
+
+// MinimalEnlightenments returns a small set of Hyper-V enlightenments that
+// is understood by older Windows guests.
+func MinimalEnlightenments() []Enlightenment {
+	return []Enlightenment{
+		{Name: "relaxed"},
+		{Name: "vapic"},
+		{Name: "spinlocks", Value: "0x1fff"},
+		{Name: "time"},
+	}
+}
+
+// HyperV describes the Hyper-V hypervisor that is simulated for a guest.
+//
+// If Enlightenments is nil, the standard enlightenments for the processor
+// are used. Enlightenments named in Disabled are never enabled. If
+// Passthrough is true, every enlightenment supported by the host is enabled
+// and those named in Disabled are explicitly turned off. If VendorID is
+// specified, it replaces the hypervisor vendor ID reported to the guest.
+type HyperV struct {
+	Enabled        bool
+	Passthrough    bool
+	Enlightenments []Enlightenment
+	Disabled       []string
+	VendorID       string
+}
+
+// Parameters returns the CPU parameters for the Hyper-V configuration
+// on a processor with the given brand.
+func (h HyperV) Parameters(brand string) qemu.Parameters {
+	if !h.Enabled {
+		return nil
+	}
+
+	var params qemu.Parameters
+	if h.Passthrough {
+		params.Add("hv-passthrough", "on")
+	}
+
+	enlightenments := h.Enlightenments
+	if enlightenments == nil && !h.Passthrough {
+		enlightenments = StandardEnlightenments(brand)
+	}
+	for _, e := range enlightenments {
+		if slices.Contains(h.Disabled, e.Name) {
+			continue
+		}
+		params = append(params, e.Parameter())
+	}
+
+	if h.Passthrough {
+		for _, name := range h.Disabled {
+			params.Add("hv-"+name, "off")
+		}
+	}
+
+	if h.VendorID != "" {
+		params.Add("hv-vendor-id", h.VendorID)
+	}
+
+	return params
+}
@@ -0,0 +1,31 @@
+package qguest_test
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
+)
+
+func ExampleHyperV() {
+	// Use the minimal enlightenments without synthetic timers and spoof
+	// the vendor ID.
+	minimal := qguest.HyperV{
+		Enabled:        true,
+		Enlightenments: qguest.MinimalEnlightenments(),
+		Disabled:       []string{"time"},
+		VendorID:       "GenuineIntel",
+	}
+	fmt.Println(minimal.Parameters("Intel").String())
+
+	// Pass through the host's enlightenments, except for evmcs.
+	passthrough := qguest.HyperV{
+		Enabled:     true,
+		Passthrough: true,
+		Disabled:    []string{"evmcs"},
+	}
+	fmt.Println(passthrough.Parameters("Intel").String())
+
+	// Output:
+	// hv-relaxed,hv-vapic,hv-spinlocks=0x1fff,hv-vendor-id=GenuineIntel
+	// hv-passthrough=on,hv-evmcs=off
+}
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/gentlemanautomaton/machina/qemu"
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
 )
 
 // Allocation is a memory allocation.
@@ -30,8 +31,37 @@ func (gb GB) Size() string {
 }
 
 // Memory describes the memory allocation for a QEMU guest.
+//
+// If Backend is specified, the guest's memory is supplied by the host
+// memory backend with that identifier. If Lock is true, the guest's memory
+// is locked into host memory so that it cannot be swapped out.
+//
+// If MaxAllocation and Slots are specified, the guest reserves that many
+// memory slots that DIMMs can be hotplugged into, up to a total of
+// MaxAllocation.
+//
+// If Encryption is specified, the guest's memory is encrypted by the host
+// object with that identifier, such as a sev-guest object.
 type Memory struct {
-	Allocation Allocation
+	Allocation    Allocation
+	MaxAllocation Allocation
+	Slots         int
+	Backend       qhost.ID
+	Encryption    qhost.ID
+	Lock          bool
+}
+
+// MachineParameters returns the machine parameters for the memory
+// configuration.
+func (m Memory) MachineParameters() qemu.Parameters {
+	var params qemu.Parameters
+	if m.Backend != "" {
+		params.Add("memory-backend", string(m.Backend))
+	}
+	if m.Encryption != "" {
+		params.Add("memory-encryption", string(m.Encryption))
+	}
+	return params
 }
 
 // Options returns a set of QEMU virtual machine options for specifying
@@ -39,7 +69,15 @@ type Memory struct {
 func (m Memory) Options() qemu.Options {
 	var opts qemu.Options
 	if size := m.Allocation.Size(); size != "" {
-		opts.Add("m", qemu.Parameter{Name: "size", Value: size})
+		params := qemu.Parameters{{Name: "size", Value: size}}
+		if m.MaxAllocation != nil && m.Slots > 0 {
+			params.Add("slots", strconv.Itoa(m.Slots))
+			params.Add("maxmem", m.MaxAllocation.Size())
+		}
+		opts.Add("m", params...)
+	}
+	if m.Lock {
+		opts.Add("overcommit", qemu.Parameter{Name: "mem-lock", Value: "on"})
 	}
 	return opts
 }
@@ -0,0 +1,48 @@
+package qguest
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina/qemu"
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
+)
+
+// NUMANode describes a guest NUMA node.
+type NUMANode struct {
+	// CPUs lists the vCPU indices that belong to the node, such as "0-3".
+	CPUs string
+
+	// MemDev identifies the host memory backend that supplies the node's
+	// memory.
+	MemDev qhost.ID
+}
+
+// NUMA describes the non-uniform memory access topology of a QEMU guest.
+type NUMA struct {
+	Nodes []NUMANode
+}
+
+// Options returns a set of QEMU virtual machine options for specifying
+// its NUMA topology.
+func (n NUMA) Options() qemu.Options {
+	var opts qemu.Options
+	for i, node := range n.Nodes {
+		params := qemu.Parameters{
+			{Name: "node"},
+			{Name: "nodeid", Value: strconv.Itoa(i)},
+		}
+		// QEMU accepts a single range per cpus parameter, so add a
+		// parameter for each range in the list.
+		if node.CPUs != "" {
+			for _, cpus := range strings.Split(node.CPUs, ",") {
+				params.Add("cpus", cpus)
+			}
+		}
+		if node.MemDev != "" {
+			params.Add("memdev", string(node.MemDev))
+		}
+		opts.Add("numa", params...)
+	}
+	return opts
+}
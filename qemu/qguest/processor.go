@@ -8,18 +8,48 @@ import (
 )
 
 // Processor describes the processor configuration of a QEMU guest.
+//
+// Model is the QEMU CPU model presented to the guest. If it is empty, the
+// host's processor is passed through. Features enable or disable
+// individual CPU features of the model.
+//
+// If MaxCPUs is specified, the guest starts with CPUs virtual CPUs and
+// additional virtual CPUs can be hotplugged up to MaxCPUs. The product of
+// Sockets, Cores and ThreadsPerCore must then equal MaxCPUs.
 type Processor struct {
 	Brand          string
+	Model          string
+	Features       []CPUFeature
+	CPUs           int
+	MaxCPUs        int
 	Sockets        int
 	Cores          int
 	ThreadsPerCore int
-	HyperV         bool
+	HyperV         HyperV
+}
+
+// CPUFeature enables or disables a CPU feature, such as avx2.
+type CPUFeature struct {
+	Name    string
+	Enabled bool
+}
+
+// Parameter returns the CPU parameter for the feature.
+func (f CPUFeature) Parameter() qemu.Parameter {
+	if f.Enabled {
+		return qemu.Parameter{Name: f.Name, Value: "on"}
+	}
+	return qemu.Parameter{Name: f.Name, Value: "off"}
 }
 
 // CPU returns the parameters of the CPU configuration. It includes any
 // processor enlightenments.
 func (p Processor) CPU() qemu.Parameters {
-	params := qemu.Parameters{{Name: "host"}}
+	model := p.Model
+	if model == "" {
+		model = "host"
+	}
+	params := qemu.Parameters{{Name: model}}
 
 	// Simultaneous multi-threading on AMD processors must be enabled
 	// explicitly.
@@ -28,51 +58,36 @@ func (p Processor) CPU() qemu.Parameters {
 	}
 
 	// Simulate a Hyper-V hypervisor if requested.
-	if p.HyperV {
-		params = append(params, qemu.Parameters{
-			{Name: "hv-relaxed"},
-			{Name: "hv-vapic"},
-			{Name: "hv-spinlocks", Value: "0x1fff"},
-			{Name: "hv-vpindex"},
-			{Name: "hv-runtime"},
-			{Name: "hv-time"},
-			{Name: "hv-synic"},
-			{Name: "hv-stimer"},
-			{Name: "hv-tlbflush"},
-			{Name: "hv-ipi"},
-			{Name: "hv-frequencies"},
-			{Name: "hv-reenlightenment"},
-			{Name: "hv-stimer-direct"},
-			{Name: "hv-emsr-bitmap"},
-			{Name: "hv-xmm-input"},
-			{Name: "hv-tlbflush-ext"},
-			{Name: "hv-tlbflush-direct"},
-		}...)
-
-		// Special Hyper-V enablements for AMD processors.
-		if strings.EqualFold(p.Brand, "AMD") {
-			params = append(params, qemu.Parameters{
-				{Name: "hv-avic", Value: "on"},
-			}...)
-		}
-
-		// Special Hyper-V enablements for Intel processors.
-		if strings.EqualFold(p.Brand, "Intel") {
-			params = append(params, qemu.Parameters{
-				{Name: "hv-evmcs"},
-			}...)
-		}
-
-		// TODO: Support hv-no-nonarch-coresharing once CPU pinning works.
+	params = append(params, p.HyperV.Parameters(p.Brand)...)
+
+	// Apply CPU features last so that they take precedence.
+	for _, feature := range p.Features {
+		params = append(params, feature.Parameter())
 	}
 
 	return params
 }
 
+// TotalCPUs returns the number of virtual CPUs that the guest can have,
+// including those that can be hotplugged later. Virtual CPU indices range
+// from zero up to, but not including, the total.
+func (p Processor) TotalCPUs() int {
+	if p.MaxCPUs > 0 {
+		return p.MaxCPUs
+	}
+	return max(p.Sockets, 1) * max(p.Cores, 1) * max(p.ThreadsPerCore, 1)
+}
+
 // SMP returns the parameters for the desired level of simultaneous
 // multithreading.
 func (p Processor) SMP() qemu.Parameters {
 	var params qemu.Parameters
+	if p.CPUs > 0 {
+		params.Add("cpus", strconv.Itoa(p.CPUs))
+	}
+	if p.MaxCPUs > 0 {
+		params.Add("maxcpus", strconv.Itoa(p.MaxCPUs))
+	}
 	if p.Sockets > 0 {
 		params.Add("sockets", strconv.Itoa(p.Sockets))
 	}
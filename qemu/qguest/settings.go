@@ -9,6 +9,7 @@ type Settings struct {
 	Clock     Clock
 	Processor Processor
 	Memory    Memory
+	NUMA      NUMA
 	QMP       QMP
 	Spice     Spice
 	Globals   qemu.Globals
@@ -30,10 +31,13 @@ func (s Settings) Options() qemu.Options {
 		}
 		params = append(params, s.Spice.MachineParameters()...)
 		params = append(params, s.Firmware.MachineParameters()...)
+		params = append(params, s.Memory.MachineParameters()...)
+		params = append(params, s.Clock.MachineParameters()...)
 		opts.Add("machine", params...)
 	}
 	opts = append(opts, s.Processor.Options()...)
 	opts = append(opts, s.Memory.Options()...)
+	opts = append(opts, s.NUMA.Options()...)
 	opts = append(opts, s.Clock.Options()...)
 	opts = append(opts, s.QMP.Options()...)
 	opts = append(opts, s.Spice.Options()...)
@@ -0,0 +1,62 @@
+package blockdev
+
+import (
+	"strconv"
+	"strings"
+)
+
+// booleanProperties are the block device properties that QMP expects as
+// JSON booleans.
+var booleanProperties = map[string]bool{
+	"read-only":      true,
+	"auto-read-only": true,
+	"force-share":    true,
+	"cache.direct":   true,
+	"cache.no-flush": true,
+	"floppy":         true,
+	"rw":             true,
+}
+
+// integerProperties are the block device properties that QMP expects as
+// JSON numbers.
+var integerProperties = map[string]bool{
+	"fat-type": true,
+}
+
+// Arguments returns the properties of node in the structured form that is
+// expected by the QMP blockdev-add command.
+//
+// Unlike the -blockdev command line option, blockdev-add expects typed
+// values and nested objects in place of dotted property names.
+func Arguments(node Node) map[string]any {
+	args := make(map[string]any)
+	for _, prop := range node.Properties() {
+		if prop.Name == "" {
+			continue
+		}
+
+		var value any = prop.Value
+		switch {
+		case booleanProperties[prop.Name]:
+			value = prop.Value == "on"
+		case integerProperties[prop.Name]:
+			if n, err := strconv.Atoi(prop.Value); err == nil {
+				value = n
+			}
+		}
+
+		// Expand dotted property names into nested objects.
+		parent := args
+		parts := strings.Split(prop.Name, ".")
+		for _, part := range parts[:len(parts)-1] {
+			child, ok := parent[part].(map[string]any)
+			if !ok {
+				child = make(map[string]any)
+				parent[part] = child
+			}
+			parent = child
+		}
+		parent[parts[len(parts)-1]] = value
+	}
+	return args
+}
@@ -0,0 +1,49 @@
+package blockdev_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina/qemu/qhost/blockdev"
+)
+
+func TestArguments(t *testing.T) {
+	var graph blockdev.Graph
+
+	file, err := blockdev.File{
+		Name:     "disk-file",
+		Path:     "/var/lib/disk.raw",
+		ReadOnly: true,
+		Cache:    blockdev.Cache{Direct: true, NoFlush: true},
+		Discard:  true,
+	}.Connect(&graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	dir, err := blockdev.Dir{
+		Name:    "share",
+		Path:    "/srv/share",
+		FatType: "32",
+	}.Connect(&graph)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		Node blockdev.Node
+		Want string
+	}{
+		{file, `{"cache":{"direct":true,"no-flush":true},"discard":"unmap","driver":"file","filename":"/var/lib/disk.raw","node-name":"disk-file","read-only":true}`},
+		{dir, `{"dir":"/srv/share","driver":"vvfat","fat-type":32,"node-name":"share"}`},
+	}
+	for _, test := range tests {
+		data, err := json.Marshal(blockdev.Arguments(test.Node))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); got != test.Want {
+			t.Errorf("%s: unexpected arguments:\n got: %s\nwant: %s", test.Node.Name(), got, test.Want)
+		}
+	}
+}
@@ -0,0 +1,169 @@
+package qhost
+
+import (
+	"strconv"
+	"strings"
+)
+
+// MemoryPolicy is the policy that a memory backend uses to allocate memory
+// from the host NUMA nodes it is bound to.
+type MemoryPolicy string
+
+// Memory policies supported by memory backends.
+const (
+	MemoryPolicyDefault    = MemoryPolicy("default")
+	MemoryPolicyPreferred  = MemoryPolicy("preferred")
+	MemoryPolicyBind       = MemoryPolicy("bind")
+	MemoryPolicyInterleave = MemoryPolicy("interleave")
+)
+
+// HostNodes describes the host NUMA nodes that a memory backend allocates
+// its memory from.
+type HostNodes struct {
+	// Nodes lists the host NUMA nodes, such as "0" or "0-1,3".
+	Nodes string
+
+	// Policy is the allocation policy for the nodes.
+	Policy MemoryPolicy
+}
+
+// properties returns the memory backend properties for the host nodes.
+//
+// QEMU accepts a single range per host-nodes property, so a property is
+// returned for each range in the list.
+func (h HostNodes) properties() Properties {
+	var props Properties
+	if h.Nodes != "" {
+		for _, nodes := range strings.Split(h.Nodes, ",") {
+			props.Add("host-nodes", nodes)
+		}
+	}
+	if h.Policy != "" {
+		props.Add("policy", string(h.Policy))
+	}
+	return props
+}
+
+// MemorySettings hold the settings that are common to all memory
+// backends.
+type MemorySettings struct {
+	// Size is the amount of memory in mebibytes.
+	Size int
+
+	// Nodes are the host NUMA nodes that memory is allocated from.
+	Nodes HostNodes
+
+	// Share makes the memory shareable with other processes, such as
+	// vhost-user device backends.
+	Share bool
+
+	// Prealloc allocates all of the memory when QEMU starts.
+	Prealloc bool
+}
+
+// properties returns the memory backend properties for the settings.
+func (s MemorySettings) properties() Properties {
+	props := Properties{
+		{Name: "size", Value: strconv.Itoa(s.Size) + "M"},
+	}
+	if s.Share {
+		props.Add("share", "on")
+	}
+	if s.Prealloc {
+		props.Add("prealloc", "on")
+	}
+	return append(props, s.Nodes.properties()...)
+}
+
+// MemoryBackend is a host memory backend object that supplies guest
+// memory.
+type MemoryBackend interface {
+	ID() ID
+	Driver() Driver
+	Properties() Properties
+}
+
+// MemoryBackendRAM is a memory backend that allocates anonymous memory on
+// the host.
+type MemoryBackendRAM struct {
+	id       ID
+	settings MemorySettings
+}
+
+// ID returns the identifier of the memory backend.
+func (m MemoryBackendRAM) ID() ID {
+	return m.id
+}
+
+// Driver returns the object driver, memory-backend-ram.
+func (m MemoryBackendRAM) Driver() Driver {
+	return "memory-backend-ram"
+}
+
+// Properties returns the properties of the memory backend.
+func (m MemoryBackendRAM) Properties() Properties {
+	props := Properties{
+		{Name: string(m.Driver())},
+		{Name: "id", Value: string(m.id)},
+	}
+	return append(props, m.settings.properties()...)
+}
+
+// MemoryBackendFile is a memory backend that allocates memory from a file
+// system, such as a hugetlbfs file system for huge pages.
+type MemoryBackendFile struct {
+	id       ID
+	path     string
+	settings MemorySettings
+}
+
+// ID returns the identifier of the memory backend.
+func (m MemoryBackendFile) ID() ID {
+	return m.id
+}
+
+// Driver returns the object driver, memory-backend-file.
+func (m MemoryBackendFile) Driver() Driver {
+	return "memory-backend-file"
+}
+
+// Properties returns the properties of the memory backend.
+func (m MemoryBackendFile) Properties() Properties {
+	props := Properties{
+		{Name: string(m.Driver())},
+		{Name: "id", Value: string(m.id)},
+		{Name: "mem-path", Value: m.path},
+	}
+	return append(props, m.settings.properties()...)
+}
+
+// MemoryBackendMemFD is a memory backend that allocates memory through
+// memfd. Its memory can be shared with vhost-user processes.
+type MemoryBackendMemFD struct {
+	id          ID
+	hugetlbSize string
+	settings    MemorySettings
+}
+
+// ID returns the identifier of the memory backend.
+func (m MemoryBackendMemFD) ID() ID {
+	return m.id
+}
+
+// Driver returns the object driver, memory-backend-memfd.
+func (m MemoryBackendMemFD) Driver() Driver {
+	return "memory-backend-memfd"
+}
+
+// Properties returns the properties of the memory backend.
+func (m MemoryBackendMemFD) Properties() Properties {
+	props := Properties{
+		{Name: string(m.Driver())},
+		{Name: "id", Value: string(m.id)},
+	}
+	if m.hugetlbSize != "" {
+		props.Add("hugetlb", "on")
+		props.Add("hugetlbsize", m.hugetlbSize)
+	}
+	return append(props, m.settings.properties()...)
+}
@@ -0,0 +1,96 @@
+package qhost_test
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
+)
+
+func TestMemoryBackends(t *testing.T) {
+	var host qhost.Resources
+
+	mem0, err := host.AddMemoryRAM(qhost.MemorySettings{Size: 4096, Nodes: qhost.HostNodes{Nodes: "0", Policy: qhost.MemoryPolicyBind}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := mem0.ID(), qhost.ID("mem.0"); got != want {
+		t.Errorf("unexpected memory backend ID \"%s\" (want \"%s\")", got, want)
+	}
+
+	if _, err := host.AddMemoryRAM(qhost.MemorySettings{Size: 2048, Nodes: qhost.HostNodes{Nodes: "1-2,4", Policy: qhost.MemoryPolicyInterleave}}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.AddMemoryRAM(qhost.MemorySettings{Size: 1024}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.AddMemoryFile("/dev/hugepages1G", qhost.MemorySettings{Size: 8192, Share: true, Prealloc: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.AddMemoryFD("2M", qhost.MemorySettings{Size: 4096, Share: true}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := host.AddMemoryFile("", qhost.MemorySettings{Size: 4096}); err == nil {
+		t.Error("expected an error for a file memory backend without a path")
+	}
+
+	expected := []string{
+		"-object memory-backend-ram,id=mem.0,size=4096M,host-nodes=0,policy=bind",
+		"-object memory-backend-ram,id=mem.1,size=2048M,host-nodes=1-2,host-nodes=4,policy=interleave",
+		"-object memory-backend-ram,id=mem.2,size=1024M",
+		"-object memory-backend-file,id=mem.3,mem-path=/dev/hugepages1G,size=8192M,share=on,prealloc=on",
+		"-object memory-backend-memfd,id=mem.4,hugetlb=on,hugetlbsize=2M,size=4096M,share=on",
+	}
+
+	options := host.Options()
+	if len(options) != len(expected) {
+		t.Fatalf("unexpected number of options: %d (want %d)", len(options), len(expected))
+	}
+	for i := range options {
+		if got, want := options[i].String(), expected[i]; got != want {
+			t.Errorf("unexpected memory option %d: \"%s\" (want \"%s\")", i, got, want)
+		}
+	}
+}
+
+func TestMemoryBackendArguments(t *testing.T) {
+	var host qhost.Resources
+
+	ram, err := host.AddMemoryRAM(qhost.MemorySettings{
+		Size:  1024,
+		Nodes: qhost.HostNodes{Nodes: "0-1,3", Policy: qhost.MemoryPolicyBind},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+	file, err := host.AddMemoryFile("/dev/hugepages", qhost.MemorySettings{Size: 2048, Prealloc: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	memfd, err := host.AddMemoryFD("2M", qhost.MemorySettings{Size: 512, Share: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := []struct {
+		Backend  qhost.MemoryBackend
+		Expected string
+	}{
+		{ram, `{"host-nodes":[0,1,3],"id":"mem.0","policy":"bind","qom-type":"memory-backend-ram","size":1073741824}`},
+		{file, `{"id":"mem.1","mem-path":"/dev/hugepages","prealloc":true,"qom-type":"memory-backend-file","size":2147483648}`},
+		{memfd, `{"hugetlb":true,"hugetlbsize":2097152,"id":"mem.2","qom-type":"memory-backend-memfd","share":true,"size":536870912}`},
+	}
+	for _, f := range fixtures {
+		args, err := qhost.MemoryBackendArguments(f.Backend)
+		if err != nil {
+			t.Fatal(err)
+		}
+		data, err := json.Marshal(args)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); got != f.Expected {
+			t.Errorf("unexpected arguments for %s:\n got: %s\nwant: %s", f.Backend.ID(), got, f.Expected)
+		}
+	}
+}
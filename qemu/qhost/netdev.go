@@ -1,5 +1,7 @@
 package qhost
 
+import "fmt"
+
 // Script is a path to an executable script on the QEMU host.
 type Script string
 
@@ -46,3 +48,101 @@ func (tap NetworkTap) Properties() Properties {
 	}
 	return props
 }
+
+// PortProtocol identifies the transport protocol of a forwarded port.
+type PortProtocol string
+
+// Port protocols supported by user-mode networks.
+const (
+	TCP = PortProtocol("tcp")
+	UDP = PortProtocol("udp")
+)
+
+// PortForward describes a host port that is forwarded to a guest port by
+// a user-mode network.
+type PortForward struct {
+	Protocol  PortProtocol
+	HostAddr  string
+	HostPort  int
+	GuestAddr string
+	GuestPort int
+}
+
+// NetworkUser is a user-mode network on the QEMU host, implemented by
+// QEMU's built-in slirp network stack.
+//
+// User-mode networks do not require any privileges on the host.
+type NetworkUser struct {
+	id       ID
+	forwards []PortForward
+}
+
+// ID returns the identifier of the host user-mode network.
+func (user NetworkUser) ID() ID {
+	return user.id
+}
+
+// Driver returns the driver used for the host network, user.
+func (user NetworkUser) Driver() Driver {
+	return "user"
+}
+
+// Properties returns the properties of the host user-mode network.
+func (user NetworkUser) Properties() Properties {
+	props := Properties{
+		{Name: string(user.Driver())},
+		{Name: "id", Value: string(user.id)},
+	}
+	for _, forward := range user.forwards {
+		protocol := forward.Protocol
+		if protocol == "" {
+			protocol = TCP
+		}
+		rule := fmt.Sprintf("%s:%s:%d-%s:%d", protocol, forward.HostAddr, forward.HostPort, forward.GuestAddr, forward.GuestPort)
+		props.Add("hostfwd", rule)
+	}
+	return props
+}
+
+// NetworkPasst is a user-mode network on the QEMU host, implemented by
+// a passt process that is managed by QEMU.
+//
+// User-mode networks do not require any privileges on the host.
+type NetworkPasst struct {
+	id       ID
+	forwards []PortForward
+}
+
+// ID returns the identifier of the host passt network.
+func (passt NetworkPasst) ID() ID {
+	return passt.id
+}
+
+// Driver returns the driver used for the host network, passt.
+func (passt NetworkPasst) Driver() Driver {
+	return "passt"
+}
+
+// Properties returns the properties of the host passt network.
+//
+// The guest address of port forwards is ignored, because passt always
+// forwards to the guest's address.
+func (passt NetworkPasst) Properties() Properties {
+	props := Properties{
+		{Name: string(passt.Driver())},
+		{Name: "id", Value: string(passt.id)},
+	}
+	for _, forward := range passt.forwards {
+		spec := fmt.Sprintf("%d:%d", forward.HostPort, forward.GuestPort)
+		if forward.HostAddr != "" {
+			spec = forward.HostAddr + "/" + spec
+		}
+		switch forward.Protocol {
+		case UDP:
+			props.Add("udp-ports", spec)
+		default:
+			props.Add("tcp-ports", spec)
+		}
+	}
+	return props
+}
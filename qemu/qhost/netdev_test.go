@@ -1,6 +1,7 @@
 package qhost_test
 
 import (
+	"encoding/json"
 	"strconv"
 	"testing"
 
@@ -52,3 +53,77 @@ func TestNetworkTap(t *testing.T) {
 		}
 	}
 }
+
+func TestNetworkUser(t *testing.T) {
+	var host qhost.Resources
+
+	user, err := host.AddNetworkUser(
+		qhost.PortForward{HostAddr: "127.0.0.1", HostPort: 2222, GuestPort: 22},
+		qhost.PortForward{Protocol: qhost.UDP, HostPort: 5353, GuestAddr: "10.0.2.15", GuestPort: 53},
+	)
+	if err != nil {
+		t.Fatalf("failed to add user network: %v", err)
+	}
+	if got, want := user.ID(), qhost.ID("net.0"); got != want {
+		t.Errorf("unexpected user network ID: \"%s\" (want \"%s\")", got, want)
+	}
+
+	passt, err := host.AddNetworkPasst(
+		qhost.PortForward{HostAddr: "127.0.0.1", HostPort: 2222, GuestPort: 22},
+		qhost.PortForward{Protocol: qhost.UDP, HostPort: 5353, GuestPort: 53},
+	)
+	if err != nil {
+		t.Fatalf("failed to add passt network: %v", err)
+	}
+	if got, want := passt.ID(), qhost.ID("net.1"); got != want {
+		t.Errorf("unexpected passt network ID: \"%s\" (want \"%s\")", got, want)
+	}
+
+	expected := []string{
+		"-netdev user,id=net.0,hostfwd=tcp:127.0.0.1:2222-:22,hostfwd=udp::5353-10.0.2.15:53",
+		"-netdev passt,id=net.1,tcp-ports=127.0.0.1/2222:22,udp-ports=5353:53",
+	}
+
+	options := host.Options()
+	if len(options) != len(expected) {
+		t.Fatalf("unexpected number of netdev options: %d (want %d)", len(options), len(expected))
+	}
+	for i := range options {
+		if got, want := options[i].String(), expected[i]; got != want {
+			t.Errorf("unexpected netdev option %d: \"%s\" (want \"%s\")", i, got, want)
+		}
+	}
+}
+
+func TestNetDevArguments(t *testing.T) {
+	var host qhost.Resources
+
+	tap, err := host.AddNetworkTap("vm.0", qhost.NoScript, qhost.NoScript)
+	if err != nil {
+		t.Fatal(err)
+	}
+	user, err := host.AddNetworkUser(
+		qhost.PortForward{HostPort: 2222, GuestPort: 22},
+		qhost.PortForward{Protocol: qhost.UDP, HostPort: 5353, GuestPort: 53},
+	)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	fixtures := []struct {
+		NetDev   qhost.NetDev
+		Expected string
+	}{
+		{tap, `{"downscript":"no","id":"net.0","ifname":"vm.0","script":"no","type":"tap"}`},
+		{user, `{"hostfwd":[{"str":"tcp::2222-:22"},{"str":"udp::5353-:53"}],"id":"net.1","type":"user"}`},
+	}
+	for _, f := range fixtures {
+		data, err := json.Marshal(qhost.NetDevArguments(f.NetDev))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := string(data); got != f.Expected {
+			t.Errorf("unexpected arguments for %s:\n got: %s\nwant: %s", f.NetDev.ID(), got, f.Expected)
+		}
+	}
+}
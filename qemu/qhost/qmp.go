@@ -0,0 +1,109 @@
+package qhost
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// NetDevArguments returns the properties of netdev in the structured form
+// that is expected by the QMP netdev_add command.
+//
+// Properties that can be repeated on the command line, such as hostfwd,
+// are provided as lists of string objects.
+func NetDevArguments(netdev NetDev) map[string]any {
+	args := map[string]any{
+		"type": string(netdev.Driver()),
+	}
+	for _, prop := range netdev.Properties() {
+		if prop.Name == "" || prop.Value == "" {
+			// The driver is provided as a parameter without a name.
+			continue
+		}
+		switch prop.Name {
+		case "id", "ifname", "script", "downscript":
+			args[prop.Name] = prop.Value
+		default:
+			list, _ := args[prop.Name].([]map[string]string)
+			args[prop.Name] = append(list, map[string]string{"str": prop.Value})
+		}
+	}
+	return args
+}
+
+// MemoryBackendArguments returns the properties of backend in the
+// structured form that is expected by the QMP object-add command.
+//
+// Sizes are converted to bytes, switches are converted to booleans and
+// host node ranges are expanded to lists of node numbers.
+func MemoryBackendArguments(backend MemoryBackend) (map[string]any, error) {
+	args := map[string]any{
+		"qom-type": string(backend.Driver()),
+	}
+	var nodes []int
+	for _, prop := range backend.Properties() {
+		if prop.Value == "" {
+			// The driver is provided as a parameter without a name.
+			continue
+		}
+		switch prop.Name {
+		case "size", "hugetlbsize":
+			size, err := parseSize(prop.Value)
+			if err != nil {
+				return nil, err
+			}
+			args[prop.Name] = size
+		case "share", "prealloc", "hugetlb":
+			args[prop.Name] = prop.Value == "on"
+		case "host-nodes":
+			first, last, found := strings.Cut(prop.Value, "-")
+			if !found {
+				last = first
+			}
+			start, err := strconv.Atoi(first)
+			if err != nil {
+				return nil, fmt.Errorf("invalid host node range \"%s\": %w", prop.Value, err)
+			}
+			end, err := strconv.Atoi(last)
+			if err != nil {
+				return nil, fmt.Errorf("invalid host node range \"%s\": %w", prop.Value, err)
+			}
+			for node := start; node <= end; node++ {
+				nodes = append(nodes, node)
+			}
+		default:
+			args[prop.Name] = prop.Value
+		}
+	}
+	if len(nodes) > 0 {
+		args["host-nodes"] = nodes
+	}
+	return args, nil
+}
+
+// parseSize parses a size with an optional K, M, G or T suffix and returns
+// the number of bytes.
+func parseSize(value string) (int64, error) {
+	multiplier := int64(1)
+	number := value
+	if n := len(value); n > 0 {
+		switch value[n-1] {
+		case 'K':
+			multiplier = 1 << 10
+		case 'M':
+			multiplier = 1 << 20
+		case 'G':
+			multiplier = 1 << 30
+		case 'T':
+			multiplier = 1 << 40
+		}
+		if multiplier > 1 {
+			number = value[:n-1]
+		}
+	}
+	size, err := strconv.ParseInt(number, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid size \"%s\": %w", value, err)
+	}
+	return size * multiplier, nil
+}
@@ -1,6 +1,8 @@
 package qhost
 
 import (
+	"errors"
+	"fmt"
 	"strconv"
 
 	"github.com/gentlemanautomaton/machina/qemu"
@@ -17,6 +19,8 @@ type Resources struct {
 	chardevs  chardev.Map
 	tpmdevs   tpmdev.Map
 	netdevs   []NetDev
+	memory    []MemoryBackend
+	sev       *SEVGuest
 }
 
 // IOThreads returns the set of IOThread resources that have been defined.
@@ -36,6 +40,85 @@ func (r *Resources) AddIOThread() (IOThread, error) {
 	return iothread, nil
 }
 
+// MemoryBackends returns the set of memory backends that have been
+// defined.
+func (r *Resources) MemoryBackends() []MemoryBackend {
+	return r.memory
+}
+
+// AddMemoryRAM adds a memory backend that allocates anonymous memory to
+// the host.
+func (r *Resources) AddMemoryRAM(settings MemorySettings) (MemoryBackendRAM, error) {
+	backend := MemoryBackendRAM{
+		id:       r.nextMemoryID(),
+		settings: settings,
+	}
+	r.memory = append(r.memory, backend)
+
+	return backend, nil
+}
+
+// AddMemoryFile adds a memory backend that allocates memory from the file
+// system at the given path to the host. This is typically a hugetlbfs
+// mount point, such as /dev/hugepages.
+func (r *Resources) AddMemoryFile(path string, settings MemorySettings) (MemoryBackendFile, error) {
+	if path == "" {
+		return MemoryBackendFile{}, errors.New("a file memory backend requires a path")
+	}
+	backend := MemoryBackendFile{
+		id:       r.nextMemoryID(),
+		path:     path,
+		settings: settings,
+	}
+	r.memory = append(r.memory, backend)
+
+	return backend, nil
+}
+
+// AddMemoryFD adds a memory backend that allocates memory through memfd to
+// the host. If hugetlbSize is not empty, memory is allocated from huge
+// pages of that size, such as "2M" or "1G".
+func (r *Resources) AddMemoryFD(hugetlbSize string, settings MemorySettings) (MemoryBackendMemFD, error) {
+	backend := MemoryBackendMemFD{
+		id:          r.nextMemoryID(),
+		hugetlbSize: hugetlbSize,
+		settings:    settings,
+	}
+	r.memory = append(r.memory, backend)
+
+	return backend, nil
+}
+
+// SEVGuest returns the SEV guest object that has been defined, if any.
+func (r *Resources) SEVGuest() (guest SEVGuest, ok bool) {
+	if r.sev == nil {
+		return SEVGuest{}, false
+	}
+	return *r.sev, true
+}
+
+// AddSEVGuest adds an AMD Secure Encrypted Virtualization guest object to
+// the host. A machine can have only one SEV guest object.
+func (r *Resources) AddSEVGuest(settings SEVSettings) (SEVGuest, error) {
+	if r.sev != nil {
+		return SEVGuest{}, errors.New("an SEV guest object has already been defined")
+	}
+	if settings.CBitPos < 1 || settings.ReducedPhysBits < 1 {
+		return SEVGuest{}, fmt.Errorf("an SEV guest requires a cbitpos and reduced-phys-bits of at least 1: %d and %d were provided", settings.CBitPos, settings.ReducedPhysBits)
+	}
+	guest := SEVGuest{
+		id:       ID("sev"),
+		settings: settings,
+	}
+	r.sev = &guest
+
+	return guest, nil
+}
+
+func (r *Resources) nextMemoryID() ID {
+	return ID("mem").Child(strconv.Itoa(len(r.memory)))
+}
+
 // BlockDevs returns the block device graph for the host block layer.
 func (r *Resources) BlockDevs() blockdev.NodeGraph {
 	return &r.blockdevs
@@ -76,6 +159,38 @@ func (r *Resources) AddNetworkTap(ifname string, up, down Script) (NetworkTap, e
 	return tap, nil
 }
 
+// AddNetworkUser adds a user-mode network implemented by QEMU's built-in
+// slirp network stack to the host configuration.
+//
+// Each of the provided port forwards will be forwarded from the host to
+// the guest.
+func (r *Resources) AddNetworkUser(forwards ...PortForward) (NetworkUser, error) {
+	index := len(r.netdevs)
+	user := NetworkUser{
+		id:       ID("net").Child(strconv.Itoa(index)),
+		forwards: forwards,
+	}
+	r.netdevs = append(r.netdevs, user)
+
+	return user, nil
+}
+
+// AddNetworkPasst adds a user-mode network implemented by passt to the host
+// configuration.
+//
+// Each of the provided port forwards will be forwarded from the host to
+// the guest.
+func (r *Resources) AddNetworkPasst(forwards ...PortForward) (NetworkPasst, error) {
+	index := len(r.netdevs)
+	passt := NetworkPasst{
+		id:       ID("net").Child(strconv.Itoa(index)),
+		forwards: forwards,
+	}
+	r.netdevs = append(r.netdevs, passt)
+
+	return passt, nil
+}
+
 // Options returns a set of QEMU virtual machine options for defining
 // host resources.
 func (r *Resources) Options() qemu.Options {
@@ -88,6 +203,18 @@ func (r *Resources) Options() qemu.Options {
 		}
 	}
 
+	// Memory Backends
+	for _, backend := range r.memory {
+		if props := backend.Properties(); len(props) > 0 {
+			opts.Add("object", props...)
+		}
+	}
+
+	// SEV Guest
+	if r.sev != nil {
+		opts.Add("object", r.sev.Properties()...)
+	}
+
 	// BlockDevs
 	opts = append(opts, r.blockdevs.Options()...)
 
@@ -0,0 +1,49 @@
+package qhost
+
+import "strconv"
+
+// SEVSettings hold the settings of an AMD Secure Encrypted Virtualization
+// guest.
+type SEVSettings struct {
+	// Policy is the guest policy that is enforced by the platform security
+	// processor. Bit 0 disables debugging and bit 2 enables SEV-ES.
+	Policy uint32
+
+	// CBitPos is the position of the encryption bit in page table entries.
+	// It must match the host processor.
+	CBitPos int
+
+	// ReducedPhysBits is the number of physical address bits that are lost
+	// when encryption is enabled. It must match the host processor and is
+	// at least 1.
+	ReducedPhysBits int
+}
+
+// SEVGuest is a QEMU object that launches a guest with AMD Secure
+// Encrypted Virtualization.
+type SEVGuest struct {
+	id       ID
+	settings SEVSettings
+}
+
+// ID returns the identifier of the SEV guest object.
+func (s SEVGuest) ID() ID {
+	return s.id
+}
+
+// Driver returns the object driver, sev-guest.
+func (s SEVGuest) Driver() Driver {
+	return "sev-guest"
+}
+
+// Properties returns the properties of the SEV guest object.
+func (s SEVGuest) Properties() Properties {
+	props := Properties{
+		{Name: string(s.Driver())},
+		{Name: "id", Value: string(s.id)},
+	}
+	props.Add("cbitpos", strconv.Itoa(s.settings.CBitPos))
+	props.Add("reduced-phys-bits", strconv.Itoa(s.settings.ReducedPhysBits))
+	props.Add("policy", strconv.FormatUint(uint64(s.settings.Policy), 10))
+	return props
+}
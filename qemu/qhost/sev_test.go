@@ -0,0 +1,31 @@
+package qhost_test
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
+)
+
+func TestSEVGuest(t *testing.T) {
+	var host qhost.Resources
+
+	guest, err := host.AddSEVGuest(qhost.SEVSettings{Policy: 0x5, CBitPos: 51, ReducedPhysBits: 1})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if got, want := guest.ID(), qhost.ID("sev"); got != want {
+		t.Errorf("unexpected sev-guest ID \"%s\" (want \"%s\")", got, want)
+	}
+
+	if _, err := host.AddSEVGuest(qhost.SEVSettings{Policy: 0x1}); err == nil {
+		t.Errorf("expected an error when adding a second sev-guest object")
+	}
+
+	options := host.Options()
+	if len(options) != 1 {
+		t.Fatalf("unexpected number of options: %d", len(options))
+	}
+	if got, want := options[0].String(), "-object sev-guest,id=sev,cbitpos=51,reduced-phys-bits=1,policy=5"; got != want {
+		t.Errorf("unexpected option:\n got: %s\nwant: %s", got, want)
+	}
+}
@@ -2,22 +2,42 @@ package qemugen
 
 import (
 	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/pcifs"
 	"github.com/gentlemanautomaton/machina/qemu/qdev"
 	"github.com/gentlemanautomaton/machina/qemu/qguest"
 	"github.com/gentlemanautomaton/machina/qemu/qvm"
 )
 
 // Build prepares a QEMU virtual machine definition for the given machina
-// machine and system configuration.
+// machine and system configuration. Passthrough devices without an address
+// are located on the local system.
 func Build(m machina.Machine, sys machina.System) (qvm.Definition, error) {
+	return BuildPinned(m, sys, nil, pcifs.Local())
+}
+
+// BuildPinned prepares a QEMU virtual machine definition for the given
+// machina machine and system configuration. Devices with an address in
+// addrs are assigned that address, which keeps their PCI addresses stable
+// when other devices are added to or removed from the machine.
+//
+// The addresses assigned to each device can be retrieved from the
+// topology of the returned definition and pinned for future builds.
+//
+// Passthrough devices that are identified by their vendor and device
+// identifier are located through pci. It is not consulted for passthrough
+// devices with an address.
+func BuildPinned(m machina.Machine, sys machina.System, addrs qdev.AddrMap, pci pcifs.System) (qvm.Definition, error) {
 	def, err := machina.Build(m, sys)
 	if err != nil {
 		return qvm.Definition{}, err
 	}
 
 	vm := qvm.Definition{}
+	if err := vm.Topology.Pin(addrs); err != nil {
+		return qvm.Definition{}, err
+	}
 	controllers := qdev.NewControllerMap(&vm.Topology)
-	target := Target{VM: &vm, Controllers: controllers, BootOrder: new(qdev.BootOrder)}
+	target := Target{VM: &vm, Controllers: controllers, BootOrder: new(qdev.BootOrder), PCI: pci}
 
 	if err := applyDefaults(&vm); err != nil {
 		return qvm.Definition{}, err
@@ -37,7 +57,10 @@ func Build(m machina.Machine, sys machina.System) (qvm.Definition, error) {
 	if err := applyConnections(m.Name, def.Connections, sys.Network, target); err != nil {
 		return qvm.Definition{}, err
 	}
-	if err := applyDevices(def.Devices, sys.MediatedDevices, target); err != nil {
+	if err := applyDevices(def.Devices, sys.MediatedDevices, sys.PassthroughDevices, sys.USBDevices, target); err != nil {
+		return qvm.Definition{}, err
+	}
+	if err := applyHotplug(def.Attributes.Hotplug, target); err != nil {
 		return qvm.Definition{}, err
 	}
 
@@ -45,7 +68,7 @@ func Build(m machina.Machine, sys machina.System) (qvm.Definition, error) {
 }
 
 func applyDefaults(vm *qvm.Definition) error {
-	// Clock settings
+	// Clock settings, which can be overridden by clock attributes
 	vm.Settings.Clock.Base = qguest.ClockBaseUTC
 	vm.Settings.Clock.Isolation = qguest.ClockIsolationHost
 	vm.Settings.Clock.DriftFix = qguest.ClockDriftFixSlew
@@ -64,9 +87,30 @@ func applyAttributes(machine machina.MachineInfo, vars machina.Vars, attrs machi
 		return err
 	}
 
-	// Apply memory attributes.
-	if ram := attrs.Memory.RAM; ram > 0 {
-		target.VM.Settings.Memory.Allocation = qguest.MB(ram)
+	// Apply memory and NUMA attributes.
+	if err := applyMemory(attrs, target); err != nil {
+		return err
+	}
+
+	// Apply memory balloon attributes.
+	if err := applyBalloon(attrs.Balloon, target); err != nil {
+		return err
+	}
+
+	// Apply confidential computing attributes.
+	if err := applySEV(attrs.SEV, target); err != nil {
+		return err
+	}
+
+	// Host CPU pinning is applied to the QEMU process when it starts, but
+	// validate it here so that mistakes are caught early.
+	if err := attrs.Pinning.Validate(); err != nil {
+		return err
+	}
+
+	// Apply clock attributes.
+	if err := applyClock(attrs.Clock, target); err != nil {
+		return err
 	}
 
 	// Apply Trusted Platform Module attributes.
@@ -0,0 +1,30 @@
+package qemugen
+
+import "github.com/gentlemanautomaton/machina"
+
+// applyClock overrides the default clock settings with those specified
+// by the clock attributes.
+func applyClock(clock machina.Clock, target Target) error {
+	if err := clock.Validate(); err != nil {
+		return err
+	}
+
+	settings := &target.VM.Settings.Clock
+	if clock.Base != "" {
+		settings.Base = clock.Base
+	}
+	if clock.Isolation != "" {
+		settings.Isolation = clock.Isolation
+	}
+	if clock.DriftFix != "" {
+		settings.DriftFix = clock.DriftFix
+	}
+	if clock.HPET != nil {
+		settings.DisableHPET = !*clock.HPET
+	}
+	if clock.PITTickPolicy != "" {
+		target.VM.Settings.Globals.Add("kvm-pit", "lost_tick_policy", string(clock.PITTickPolicy))
+	}
+
+	return nil
+}
@@ -4,9 +4,17 @@ import (
 	"fmt"
 
 	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
 	"github.com/gentlemanautomaton/machina/qemu/qhost"
 )
 
+// NetworkDeviceID returns the QEMU device identifier of the virtual network
+// adapter that is generated for conn. It can be used to target the adapter
+// with QMP commands while the machine is running.
+func NetworkDeviceID(conn machina.Connection) qdev.ID {
+	return qdev.ID("nic." + string(conn.Name))
+}
+
 func applyConnections(machine machina.MachineName, conns []machina.Connection, networks machina.NetworkMap, t Target) error {
 	if len(conns) == 0 {
 		return nil
@@ -20,40 +28,106 @@ func applyConnections(machine machina.MachineName, conns []machina.Connection, n
 			return fmt.Errorf("connection %s uses an unspecified machina network: %s", conn.Name, conn.Network)
 		}
 
-		// Determine the link name
-		link := machina.MakeLinkName(machine, conn)
+		// Add the host's netdev resource for this connection
+		var netdev qhost.NetDev
+		switch typ := network.EffectiveType(); typ {
+		case machina.TapNetwork:
+			switch mode := network.EffectiveTapMode(); mode {
+			case machina.ScriptTapMode, machina.PersistentTapMode:
+			default:
+				return fmt.Errorf("connection %s uses machina network %s, which has an unrecognized tap mode: \"%s\"", conn.Name, conn.Network, mode)
+			}
+			if len(conn.Forwards) > 0 {
+				return fmt.Errorf("connection %s specifies port forwards, which are not supported by %s networks", conn.Name, typ)
+			}
 
-		// If up/down scripts were provided, use those
-		up, down := qhost.NoScript, qhost.NoScript
-		if network.Up != "" {
-			up = qhost.Script(network.Up)
-		} else {
-			up = qhost.Script("/usr/bin/machina-ifup")
-		}
-		if network.Down != "" {
-			down = qhost.Script(network.Down)
-		} else {
-			down = qhost.Script("/usr/bin/machina-ifdown")
-		}
+			// Determine the link name
+			link := machina.MakeLinkName(machine, conn)
 
-		// Add the host's netdev resource for this connection
-		tap, err := t.VM.Resources.AddNetworkTap(link, up, down)
-		if err != nil {
-			return err
+			// If up/down scripts were provided, use those. Persistent taps
+			// are prepared by machina ahead of time, so no scripts are run.
+			up, down := qhost.NoScript, qhost.NoScript
+			if !network.PersistentTaps() {
+				if network.Up != "" {
+					up = qhost.Script(network.Up)
+				} else {
+					up = qhost.Script("/usr/bin/machina-ifup")
+				}
+				if network.Down != "" {
+					down = qhost.Script(network.Down)
+				} else {
+					down = qhost.Script("/usr/bin/machina-ifdown")
+				}
+			}
+
+			tap, err := t.VM.Resources.AddNetworkTap(link, up, down)
+			if err != nil {
+				return err
+			}
+			netdev = tap
+		case machina.UserNetwork:
+			forwards, err := makePortForwards(conn)
+			if err != nil {
+				return err
+			}
+			user, err := t.VM.Resources.AddNetworkUser(forwards...)
+			if err != nil {
+				return err
+			}
+			netdev = user
+		case machina.PasstNetwork:
+			forwards, err := makePortForwards(conn)
+			if err != nil {
+				return err
+			}
+			for _, forward := range forwards {
+				if forward.GuestAddr != "" {
+					return fmt.Errorf("connection %s specifies a guest address for a port forward, which is not supported by %s networks", conn.Name, typ)
+				}
+			}
+			passt, err := t.VM.Resources.AddNetworkPasst(forwards...)
+			if err != nil {
+				return err
+			}
+			netdev = passt
+		default:
+			return fmt.Errorf("connection %s uses machina network %s, which has an unrecognized network type: \"%s\"", conn.Name, conn.Network, typ)
 		}
 
 		// Add a PCI Express Root device that we'll connect a Network Controller
 		// to.
-		root, err := t.VM.Topology.AddRoot()
+		root, err := t.VM.Topology.AddRoot(qdev.Key(NetworkDeviceID(conn)))
 		if err != nil {
 			return err
 		}
 
 		// Add a Virtio Network Controller.
-		if _, err := root.AddVirtioNetwork(conn.MAC, tap); err != nil {
+		if _, err := root.AddVirtioNetwork(conn.MAC, netdev, qdev.NetworkID(NetworkDeviceID(conn))); err != nil {
 			return err
 		}
 	}
 
 	return nil
 }
+
+func makePortForwards(conn machina.Connection) ([]qhost.PortForward, error) {
+	if len(conn.Forwards) == 0 {
+		return nil, nil
+	}
+
+	forwards := make([]qhost.PortForward, 0, len(conn.Forwards))
+	for _, forward := range conn.Forwards {
+		if err := forward.Validate(); err != nil {
+			return nil, fmt.Errorf("connection %s: %w", conn.Name, err)
+		}
+		forwards = append(forwards, qhost.PortForward{
+			Protocol:  qhost.PortProtocol(forward.EffectiveProtocol()),
+			HostAddr:  forward.HostAddr,
+			HostPort:  forward.HostPort,
+			GuestAddr: forward.GuestAddr,
+			GuestPort: forward.GuestPort,
+		})
+	}
+
+	return forwards, nil
+}
@@ -2,8 +2,11 @@ package qemugen
 
 import (
 	"fmt"
+	"slices"
+	"strings"
 
 	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
 )
 
 func applyCPU(attrs machina.Attributes, processors machina.ProcessorMap, target Target) error {
@@ -21,13 +24,33 @@ func applyCPU(attrs machina.Attributes, processors machina.ProcessorMap, target
 			if processor.Brand != "" {
 				target.VM.Settings.Processor.Brand = processor.Brand
 			}
+			if processor.CPUModel != "" {
+				target.VM.Settings.Processor.Model = processor.CPUModel
+			}
+			if err := applyCPUFlags(processor.Flags, target); err != nil {
+				return fmt.Errorf("processor \"%s\": %w", name, err)
+			}
 			if processor.ThreadsPerCore != 0 {
 				target.VM.Settings.Processor.ThreadsPerCore = processor.ThreadsPerCore
 			}
 		}
 	}
 
+	// Expose hardware virtualization extensions before the machine's CPU
+	// flags are applied, so that the flags can still override them.
+	if attrs.Nested.Enabled {
+		if err := applyNested(target); err != nil {
+			return err
+		}
+	}
+
 	// Apply machine CPU attributes.
+	if model := attrs.CPU.CPUModel; model != "" {
+		target.VM.Settings.Processor.Model = model
+	}
+	if err := applyCPUFlags(attrs.CPU.Flags, target); err != nil {
+		return err
+	}
 	if sockets := attrs.CPU.Sockets; sockets > 0 {
 		target.VM.Settings.Processor.Sockets = sockets
 	}
@@ -38,8 +61,98 @@ func applyCPU(attrs machina.Attributes, processors machina.ProcessorMap, target
 		target.VM.Settings.Processor.ThreadsPerCore = threads
 	}
 	if attrs.Enlightenments.Enabled {
-		target.VM.Settings.Processor.HyperV = true
+		if err := applyEnlightenments(attrs.Enlightenments, target); err != nil {
+			return err
+		}
+	}
+
+	// Reserve sockets for hotpluggable vCPUs.
+	if maxCPUs := attrs.CPU.MaxCPUs; maxCPUs > 0 {
+		if err := applyMaxCPUs(maxCPUs, target); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// applyMaxCPUs adds sockets to the processor topology so that virtual CPUs
+// can be hotplugged up to a total of maxCPUs. The existing topology
+// determines the number of virtual CPUs that the machine starts with.
+func applyMaxCPUs(maxCPUs int, target Target) error {
+	p := &target.VM.Settings.Processor
+	sockets, cores, threads := max(p.Sockets, 1), max(p.Cores, 1), max(p.ThreadsPerCore, 1)
+	perSocket := cores * threads
+	cpus := sockets * perSocket
+	if maxCPUs < cpus {
+		return fmt.Errorf("the maximum of %d CPUs is less than the %d CPUs that the machine starts with", maxCPUs, cpus)
+	}
+	if maxCPUs%perSocket != 0 {
+		return fmt.Errorf("the maximum of %d CPUs is not a multiple of the %d CPUs in each socket", maxCPUs, perSocket)
+	}
+	p.CPUs = cpus
+	p.MaxCPUs = maxCPUs
+	p.Sockets = maxCPUs / perSocket
+	p.Cores = cores
+	p.ThreadsPerCore = threads
+	return nil
+}
+
+func applyCPUFlags(flags []machina.CPUFlag, target Target) error {
+	for _, flag := range flags {
+		if err := flag.Validate(); err != nil {
+			return err
+		}
+		target.VM.Settings.Processor.Features = append(target.VM.Settings.Processor.Features, qguest.CPUFeature{
+			Name:    flag.Feature(),
+			Enabled: flag.Enabled(),
+		})
+	}
+	return nil
+}
+
+// applyEnlightenments configures the Hyper-V hypervisor that is simulated
+// for the machine.
+func applyEnlightenments(e machina.Enlightenments, target Target) error {
+	if err := e.Validate(); err != nil {
+		return err
+	}
+
+	hyperv := qguest.HyperV{
+		Enabled:     true,
+		Passthrough: e.Passthrough,
+		VendorID:    e.VendorID,
+	}
+
+	switch {
+	case e.Passthrough:
+	case e.Preset == machina.MinimalEnlightenments:
+		hyperv.Enlightenments = qguest.MinimalEnlightenments()
+	default:
+		hyperv.Enlightenments = qguest.StandardEnlightenments(target.VM.Settings.Processor.Brand)
 	}
 
+	// Add or replace individually enabled enlightenments.
+	for _, feature := range e.Enable {
+		name, value, _ := strings.Cut(strings.TrimPrefix(feature, "hv-"), "=")
+		enlightenment := qguest.Enlightenment{Name: name, Value: value}
+		if i := slices.IndexFunc(hyperv.Enlightenments, func(existing qguest.Enlightenment) bool {
+			return existing.Name == name
+		}); i >= 0 {
+			hyperv.Enlightenments[i] = enlightenment
+		} else {
+			hyperv.Enlightenments = append(hyperv.Enlightenments, enlightenment)
+		}
+	}
+
+	// Disabled enlightenments are identified by name, so ignore any value
+	// that was supplied with them.
+	for _, feature := range e.Disable {
+		name, _, _ := strings.Cut(strings.TrimPrefix(feature, "hv-"), "=")
+		hyperv.Disabled = append(hyperv.Disabled, name)
+	}
+
+	target.VM.Settings.Processor.HyperV = hyperv
+
 	return nil
 }
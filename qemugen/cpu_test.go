@@ -0,0 +1,72 @@
+package qemugen
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qvm"
+)
+
+func Example_applyCPU() {
+	processors := machina.ProcessorMap{
+		"epyc": {
+			Brand:    "AMD",
+			CPUModel: "EPYC-v4",
+			Flags:    []machina.CPUFlag{"+avx2", "-xsaves"},
+		},
+	}
+
+	// The machine's flags are applied after the processor's flags, so
+	// that they take precedence.
+	var vm qvm.Definition
+	attrs := machina.Attributes{
+		CPU: machina.CPU{
+			Processor: "epyc",
+			Flags:     []machina.CPUFlag{"+xsaves", "-hle"},
+		},
+	}
+	if err := applyCPU(attrs, processors, Target{VM: &vm}); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(vm.Settings.Processor.CPU())
+
+	// The machine can override the processor's model.
+	vm = qvm.Definition{}
+	attrs.CPU.CPUModel = "EPYC-Milan"
+	attrs.CPU.Flags = nil
+	if err := applyCPU(attrs, processors, Target{VM: &vm}); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(vm.Settings.Processor.CPU())
+
+	// Flags must start with + or -.
+	vm = qvm.Definition{}
+	attrs.CPU.Flags = []machina.CPUFlag{"avx512f"}
+	if err := applyCPU(attrs, processors, Target{VM: &vm}); err != nil {
+		fmt.Println(err)
+	}
+
+	// Output:
+	// EPYC-v4,avx2=on,xsaves=off,xsaves=on,hle=off
+	// EPYC-Milan,avx2=on,xsaves=off
+	// invalid CPU flag "avx512f": flags must start with + or -
+}
+
+func Example_applyEnlightenments() {
+	var vm qvm.Definition
+	e := machina.Enlightenments{
+		Enabled: true,
+		Preset:  machina.MinimalEnlightenments,
+		Enable:  []string{"hv-spinlocks=0x2000", "no-nonarch-coresharing=on"},
+		Disable: []string{"hv-time", "vapic=on"},
+	}
+	if err := applyEnlightenments(e, Target{VM: &vm}); err != nil {
+		fmt.Println(err)
+	}
+	fmt.Println(vm.Settings.Processor.HyperV.Disabled)
+	fmt.Println(vm.Settings.Processor.CPU())
+
+	// Output:
+	// [time vapic]
+	// host,hv-relaxed,hv-spinlocks=0x2000,hv-no-nonarch-coresharing=on
+}
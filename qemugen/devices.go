@@ -5,15 +5,49 @@ import (
 
 	"github.com/gentlemanautomaton/machina"
 	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
 	"github.com/gentlemanautomaton/machina/vmrand"
 )
 
-func applyDevices(devs []machina.Device, mdevs machina.MediatedDeviceMap, t Target) error {
+func applyDevices(devs []machina.Device, mdevs machina.MediatedDeviceMap, pdevs machina.PassthroughDeviceMap, udevs machina.USBDeviceMap, t Target) error {
 	if len(devs) == 0 {
 		return nil
 	}
 
 	for _, dev := range devs {
+		// Look for passthrough devices that supply the device class
+		if pdevs := pdevs.WithClass(dev.Class); len(pdevs) > 0 {
+			for i, pdev := range pdevs {
+				// Locate the PCI device on the host. This only consults the
+				// host's file system when an address hasn't been provided.
+				host, err := t.PCI.Locate(pdev)
+				if err != nil {
+					return fmt.Errorf("device %s: %v", dev.Name, err)
+				}
+
+				// Add a PCI Express Root device that we'll connect the
+				// passthrough device to.
+				root, err := t.VM.Topology.AddRoot(qdev.Key(fmt.Sprintf("dev.%s.%d", dev.Name, i)))
+				if err != nil {
+					return err
+				}
+
+				// Add a VFIO passthrough device to the PCI Express root port.
+				if _, err := root.AddVFIO(host.Path()); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		// Look for USB devices that supply the device class
+		if udevs := udevs.WithClass(dev.Class); len(udevs) > 0 {
+			if err := applyUSBDevices(dev, udevs, t); err != nil {
+				return err
+			}
+			continue
+		}
+
 		// Look for mediated devices that supply the device class
 		if mdevs := mdevs.WithClass(dev.Class); len(mdevs) == 0 {
 			return fmt.Errorf("device %s uses an unspecified machina device class: %s", dev.Name, dev.Class)
@@ -32,7 +66,7 @@ func applyDevices(devs []machina.Device, mdevs machina.MediatedDeviceMap, t Targ
 
 		// Add a PCI Express Root device that we'll connect the mediated
 		// device to.
-		root, err := t.VM.Topology.AddRoot()
+		root, err := t.VM.Topology.AddRoot(qdev.Key("dev." + string(dev.Name)))
 		if err != nil {
 			return err
 		}
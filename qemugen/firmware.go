@@ -1,20 +1,71 @@
 package qemugen
 
 import (
+	"fmt"
+
 	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qfirmware"
+	"github.com/gentlemanautomaton/machina/qemu/qhost/blockdev"
 )
 
+// firmwareCodeNode is the node name of the firmware code when it has been
+// selected from the firmware descriptors installed on the host.
+const firmwareCodeNode = blockdev.NodeName("firmware-code")
+
+// FirmwareRequirements returns the requirements that firmware must satisfy
+// to be used by a machine with the given attributes.
+func FirmwareRequirements(attrs machina.Attributes) qfirmware.Requirements {
+	req := qfirmware.Requirements{
+		Interface:    attrs.Firmware.Interface,
+		Architecture: "x86_64",
+		Machine:      "pc-q35-*",
+	}
+
+	if attrs.Firmware.SecureBoot {
+		req.Features = append(req.Features, qfirmware.FeatureSecureBoot, qfirmware.FeatureEnrolledKeys)
+	} else {
+		req.Exclude = append(req.Exclude, qfirmware.FeatureEnrolledKeys)
+	}
+
+	if attrs.SEV.Enabled {
+		req.Features = append(req.Features, qfirmware.FeatureAMDSEV)
+		if attrs.SEV.ES {
+			req.Features = append(req.Features, qfirmware.FeatureAMDSEVES)
+		}
+	}
+
+	return req
+}
+
+// SelectFirmware selects firmware for a machine with the given attributes
+// from the firmware descriptors installed on the local host.
+func SelectFirmware(attrs machina.Attributes) (qfirmware.Descriptor, error) {
+	catalog, err := qfirmware.Local()
+	if err != nil {
+		return qfirmware.Descriptor{}, err
+	}
+	return selectFirmware(catalog, attrs)
+}
+
+func selectFirmware(catalog qfirmware.Catalog, attrs machina.Attributes) (qfirmware.Descriptor, error) {
+	desc, err := catalog.Select(FirmwareRequirements(attrs))
+	if err != nil {
+		return qfirmware.Descriptor{}, fmt.Errorf("failed to select %s firmware: %w", attrs.Firmware.Interface, err)
+	}
+	return desc, nil
+}
+
 func applyFirmware(machine machina.MachineInfo, def machina.Definition, storage machina.StorageMap, target Target) error {
 	fw := def.Attributes.Firmware
-	// TODO: Consider returning an error if vars are supplied without code
-	if fw.Code.IsEmpty() {
-		return nil
+	if err := fw.Validate(); err != nil {
+		return err
 	}
 
 	var vols []machina.Volume
 
 	handlers := DefaultStorageHandlers()
-	{
+	switch {
+	case !fw.Code.IsEmpty():
 		codeSpec, err := makeVolumeSpec(machine, def.Vars, fw.Code, storage)
 		if err != nil {
 			return err
@@ -25,6 +76,31 @@ func applyFirmware(machine machina.MachineInfo, def machina.Definition, storage
 		}
 		target.VM.Settings.Firmware.Code = codeName
 		vols = append(vols, fw.Code)
+	case fw.IsDiscovered():
+		catalog := target.Firmware
+		if catalog == nil {
+			var err error
+			if catalog, err = qfirmware.Local(); err != nil {
+				return err
+			}
+		}
+		desc, err := selectFirmware(catalog, def.Attributes)
+		if err != nil {
+			return err
+		}
+		_, err = blockdev.File{
+			Name:     firmwareCodeNode,
+			Path:     blockdev.FilePath(desc.Mapping.Executable.Filename),
+			ReadOnly: true,
+		}.Connect(target.VM.Resources.BlockDevs())
+		if err != nil {
+			return err
+		}
+		target.VM.Settings.Firmware.Code = firmwareCodeNode
+		target.VM.Settings.Firmware.SMM = desc.HasFeature(qfirmware.FeatureRequiresSMM)
+	default:
+		// TODO: Consider returning an error if vars are supplied without code
+		return nil
 	}
 
 	if !fw.Vars.IsEmpty() {
@@ -40,7 +116,14 @@ func applyFirmware(machine machina.MachineInfo, def machina.Definition, storage
 		vols = append(vols, fw.Vars)
 	}
 
-	target.VM.Settings.Globals.Add("cfi.pflash01", "secure", "on")
+	// Restrict writes to the firmware's flash devices to System Management
+	// Mode when the selected firmware requires SMM, such as Secure Boot
+	// builds of OVMF. Together with smm=on this is what protects their
+	// variables. Hand-defined firmware code always gets the restriction,
+	// as it always has, because its requirements aren't known.
+	if target.VM.Settings.Firmware.SMM || !fw.Code.IsEmpty() {
+		target.VM.Settings.Globals.Add("cfi.pflash01", "secure", "on")
+	}
 	applyVolumes(machine, def.Vars, vols, storage, target)
 
 	return nil
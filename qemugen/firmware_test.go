@@ -0,0 +1,120 @@
+package qemugen
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+	"github.com/gentlemanautomaton/machina/qemu/qfirmware"
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
+	"github.com/gentlemanautomaton/machina/qemu/qvm"
+)
+
+func TestApplyFirmware(t *testing.T) {
+	descriptor := func(code string, features ...qfirmware.Feature) qfirmware.Descriptor {
+		return qfirmware.Descriptor{
+			InterfaceTypes: []qfirmware.Interface{qfirmware.UEFI},
+			Mapping: qfirmware.Mapping{
+				Device:        "flash",
+				Executable:    qfirmware.File{Filename: code, Format: "raw"},
+				NVRAMTemplate: qfirmware.File{Filename: strings.Replace(code, "CODE", "VARS", 1), Format: "raw"},
+			},
+			Targets:  []qfirmware.Target{{Architecture: "x86_64", Machines: []string{"pc-q35-*"}}},
+			Features: features,
+		}
+	}
+
+	catalog := qfirmware.Catalog{
+		descriptor("/usr/share/OVMF/OVMF_CODE.secboot.fd", qfirmware.FeatureSecureBoot, qfirmware.FeatureEnrolledKeys, qfirmware.FeatureRequiresSMM),
+		descriptor("/usr/share/OVMF/OVMF_CODE.fd"),
+	}
+
+	storage := machina.StorageMap{
+		"firmware-vars": {Path: "/var/lib/machina/nvram", Type: machina.FirmwareStorage},
+		"firmware-code": {Path: "/usr/share/OVMF", Type: machina.FirmwareStorage},
+	}
+
+	tests := []struct {
+		Name       string
+		Interface  qfirmware.Interface
+		Code       machina.Volume
+		SecureBoot bool
+		Want       []string
+		Unwanted   []string
+	}{
+		{
+			Name:       "secure-boot",
+			Interface:  qfirmware.UEFI,
+			SecureBoot: true,
+			Want: []string{
+				"pflash0=firmware-code",
+				"pflash1=test-firmware-vars",
+				"smm=on",
+				"-global driver=cfi.pflash01,property=secure,value=on",
+				"/usr/share/OVMF/OVMF_CODE.secboot.fd",
+			},
+		},
+		{
+			Name:      "standard",
+			Interface: qfirmware.UEFI,
+			Want: []string{
+				"pflash0=firmware-code",
+				"pflash1=test-firmware-vars",
+				"/usr/share/OVMF/OVMF_CODE.fd",
+			},
+			Unwanted: []string{"smm=on", "secboot", "cfi.pflash01"},
+		},
+		{
+			Name: "hand-defined",
+			Code: machina.Volume{Name: "OVMF_CODE.fd", Storage: "firmware-code"},
+			Want: []string{
+				"pflash0=test-OVMF_CODE.fd",
+				"pflash1=test-firmware-vars",
+				"-global driver=cfi.pflash01,property=secure,value=on",
+				"/usr/share/OVMF/OVMF_CODE.fd",
+			},
+			Unwanted: []string{"smm=on", "firmware-code"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			def := machina.Definition{
+				Attributes: machina.Attributes{
+					Firmware: machina.Firmware{
+						Code:       test.Code,
+						Interface:  test.Interface,
+						SecureBoot: test.SecureBoot,
+						Vars:       machina.Volume{Name: "firmware-vars", Storage: "firmware-vars"},
+					},
+				},
+			}
+
+			var vm qvm.Definition
+			vm.Settings.Memory.Allocation = qguest.MB(1024)
+			target := Target{
+				VM:          &vm,
+				Controllers: qdev.NewControllerMap(&vm.Topology),
+				BootOrder:   new(qdev.BootOrder),
+				Firmware:    catalog,
+			}
+
+			if err := applyFirmware(machina.MachineInfo{Name: "test"}, def, storage, target); err != nil {
+				t.Fatal(err)
+			}
+
+			args := strings.Join(vm.Options().Args(), " ")
+			for _, want := range test.Want {
+				if !strings.Contains(args, want) {
+					t.Errorf("missing %q in %s", want, args)
+				}
+			}
+			for _, unwanted := range test.Unwanted {
+				if strings.Contains(args, unwanted) {
+					t.Errorf("unexpected %q in %s", unwanted, args)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,23 @@
+package qemugen
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina"
+)
+
+// applyHotplug reserves spare PCI Express root ports for devices that are
+// hotplugged while the machine is running.
+//
+// It must be called after all other devices have been added to the
+// topology, so that the number of spare root ports does not affect the
+// addresses of the other devices.
+func applyHotplug(hotplug machina.Hotplug, t Target) error {
+	if hotplug.RootPorts <= 0 {
+		return nil
+	}
+	if _, err := t.VM.Topology.AddSpareRoots(hotplug.RootPorts); err != nil {
+		return fmt.Errorf("failed to reserve %d spare hotplug root ports: %w", hotplug.RootPorts, err)
+	}
+	return nil
+}
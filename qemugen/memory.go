@@ -0,0 +1,96 @@
+package qemugen
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
+)
+
+func applyMemory(attrs machina.Attributes, target Target) error {
+	mem := attrs.Memory
+	if err := mem.Validate(); err != nil {
+		return err
+	}
+
+	if ram := mem.RAM; ram > 0 {
+		target.VM.Settings.Memory.Allocation = qguest.MB(ram)
+	}
+	if mem.MaxRAM > 0 {
+		target.VM.Settings.Memory.MaxAllocation = qguest.MB(mem.MaxRAM)
+		target.VM.Settings.Memory.Slots = mem.Slots
+	}
+	target.VM.Settings.Memory.Lock = mem.Lock
+
+	// When NUMA nodes are present each node has its own memory backend.
+	if len(attrs.NUMA.Nodes) > 0 {
+		return applyNUMA(attrs, target)
+	}
+
+	if mem.IsDefault() {
+		return nil
+	}
+	if mem.RAM <= 0 {
+		return errors.New("a memory backend was configured without an amount of ram")
+	}
+
+	backend, err := AddMemoryBackend(&target.VM.Resources, mem, qhost.MemorySettings{Size: mem.RAM})
+	if err != nil {
+		return err
+	}
+	target.VM.Settings.Memory.Backend = backend.ID()
+
+	return nil
+}
+
+// AddMemoryBackend adds a host memory backend of the kind selected by mem
+// with the given settings to res.
+//
+// It is also used to prepare the backends of DIMMs that are hotplugged
+// into running machines, so that they match the machine's memory.
+func AddMemoryBackend(res *qhost.Resources, mem machina.Memory, settings qhost.MemorySettings) (qhost.MemoryBackend, error) {
+	settings.Share = mem.Share
+	settings.Prealloc = mem.Prealloc
+
+	switch {
+	case mem.Backend == machina.MemFDMemoryBackend:
+		// memfd is used to share memory with vhost-user processes, which
+		// requires it to be shared.
+		settings.Share = true
+		return res.AddMemoryFD(string(mem.HugePages), settings)
+	case mem.HugePages != "":
+		return res.AddMemoryFile(mem.EffectiveHugePagesPath(), settings)
+	default:
+		return res.AddMemoryRAM(settings)
+	}
+}
+
+// applyBalloon adds a virtio memory balloon device to the machine when it
+// is enabled.
+func applyBalloon(balloon machina.Balloon, target Target) error {
+	if !balloon.Enabled {
+		return nil
+	}
+
+	root, err := target.VM.Topology.AddRoot(qdev.Key("balloon"))
+	if err != nil {
+		return fmt.Errorf("failed to allocate a PCIe root port for the memory balloon: %w", err)
+	}
+
+	var options []qdev.BalloonOption
+	if balloon.FreePageReporting {
+		options = append(options, qdev.FreePageReporting(true))
+	}
+	if balloon.StatsInterval > 0 {
+		options = append(options, qdev.StatsInterval(balloon.StatsInterval))
+	}
+
+	if _, err := root.AddBalloon(options...); err != nil {
+		return fmt.Errorf("failed to add the memory balloon: %w", err)
+	}
+
+	return nil
+}
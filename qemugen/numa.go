@@ -0,0 +1,73 @@
+package qemugen
+
+import (
+	"fmt"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/filesystem/sysfs"
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
+)
+
+func applyNUMA(attrs machina.Attributes, target Target) error {
+	numa := attrs.NUMA
+	if len(numa.Nodes) == 0 {
+		return nil
+	}
+
+	if err := numa.Validate(); err != nil {
+		return err
+	}
+
+	// Every vCPU, including those that can be hotplugged, must belong to
+	// exactly one node. This relies on the CPU topology having been
+	// applied already.
+	vcpus := target.VM.Settings.Processor.TotalCPUs()
+	var assigned int
+	for i, node := range numa.Nodes {
+		cpus, _ := sysfs.ParseCPUList(node.CPUs)
+		if last := cpus[len(cpus)-1]; last >= vcpus {
+			return fmt.Errorf("numa node %d includes vCPU %d but the machine only has %d vCPUs", i, last, vcpus)
+		}
+		assigned += len(cpus)
+	}
+	if assigned < vcpus {
+		return fmt.Errorf("the numa nodes only include %d of the machine's %d vCPUs", assigned, vcpus)
+	}
+
+	// The memory of the machine is the sum of the memory in its nodes.
+	total := numa.Memory()
+	if ram := attrs.Memory.RAM; ram > 0 && ram != total {
+		return fmt.Errorf("the machine has %s of memory but its numa nodes have %s", qguest.MB(ram).Size(), qguest.MB(total).Size())
+	}
+	target.VM.Settings.Memory.Allocation = qguest.MB(total)
+
+	// Add a memory backend for each node that is bound to the node's host
+	// NUMA nodes.
+	for i, node := range numa.Nodes {
+		cpus, _ := sysfs.ParseCPUList(node.CPUs)
+		hostNodes, _ := sysfs.ParseCPUList(node.HostNodes)
+
+		if err := attrs.Memory.HugePages.CheckSize(node.Memory); err != nil {
+			return fmt.Errorf("numa node %d: %w", i, err)
+		}
+
+		backend, err := AddMemoryBackend(&target.VM.Resources, attrs.Memory, qhost.MemorySettings{
+			Size: node.Memory,
+			Nodes: qhost.HostNodes{
+				Nodes:  hostNodes.String(),
+				Policy: qhost.MemoryPolicy(node.EffectivePolicy()),
+			},
+		})
+		if err != nil {
+			return err
+		}
+
+		target.VM.Settings.NUMA.Nodes = append(target.VM.Settings.NUMA.Nodes, qguest.NUMANode{
+			CPUs:   cpus.String(),
+			MemDev: backend.ID(),
+		})
+	}
+
+	return nil
+}
@@ -0,0 +1,50 @@
+package qemugen
+
+import (
+	"testing"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qvm"
+)
+
+func TestApplyNUMA(t *testing.T) {
+	nodes := func(cpus ...string) machina.NUMA {
+		var numa machina.NUMA
+		for _, list := range cpus {
+			numa.Nodes = append(numa.Nodes, machina.NUMANode{CPUs: list, Memory: 1024})
+		}
+		return numa
+	}
+
+	tests := []struct {
+		Name string
+		CPU  machina.CPU
+		NUMA machina.NUMA
+		OK   bool
+	}{
+		{"covered", machina.CPU{Sockets: 2, Cores: 2}, nodes("0-1", "2-3"), true},
+		{"vCPU out of range", machina.CPU{Sockets: 2, Cores: 2}, nodes("0-1", "2-3,8"), false},
+		{"vCPU left out", machina.CPU{Sockets: 2, Cores: 2}, nodes("0-1", "2"), false},
+		{"default topology", machina.CPU{}, nodes("0"), true},
+		{"default topology out of range", machina.CPU{}, nodes("0", "1"), false},
+		{"hotpluggable vCPUs covered", machina.CPU{Sockets: 1, Cores: 2, MaxCPUs: 4}, nodes("0-1", "2-3"), true},
+		{"hotpluggable vCPUs left out", machina.CPU{Sockets: 1, Cores: 2, MaxCPUs: 4}, nodes("0", "1"), false},
+	}
+
+	for _, test := range tests {
+		t.Run(test.Name, func(t *testing.T) {
+			attrs := machina.Attributes{CPU: test.CPU, NUMA: test.NUMA}
+			var vm qvm.Definition
+			target := Target{VM: &vm}
+			if err := applyCPU(attrs, nil, target); err != nil {
+				t.Fatal(err)
+			}
+			err := applyNUMA(attrs, target)
+			if test.OK && err != nil {
+				t.Errorf("unexpected error: %v", err)
+			} else if !test.OK && err == nil {
+				t.Errorf("expected an error")
+			}
+		})
+	}
+}
@@ -123,7 +123,7 @@ func (h rawDiskHandler) Apply(spec VolumeSpec, t Target) error {
 		}
 
 		// Prepare the SCSI HD device options.
-		var options []qdev.SCSIHDOption
+		options := []qdev.SCSIHDOption{qdev.DiskID(VolumeDeviceID(spec.Volume))}
 		if !spec.Volume.WWN.IsZero() {
 			options = append(options, qdev.WWN(spec.Volume.WWN))
 		}
@@ -141,14 +141,14 @@ func (h rawDiskHandler) Apply(spec VolumeSpec, t Target) error {
 	case "block":
 		// Add a PCI Express Root device that we'll connect a Virtio Block
 		// device to.
-		root, err := t.VM.Topology.AddRoot()
+		root, err := t.VM.Topology.AddRoot(qdev.Key(VolumeDeviceID(spec.Volume)))
 		if err != nil {
 			return err
 		}
 
 		// Prepare the Virtio Block device options. Note that WWN values are
 		// not supported by Virtio Block devices.
-		var options []qdev.BlockOption
+		options := []qdev.BlockOption{qdev.DiskID(VolumeDeviceID(spec.Volume))}
 		if spec.Volume.SerialNumber != "" {
 			options = append(options, qdev.SerialNumber(spec.Volume.SerialNumber))
 		}
@@ -201,14 +201,14 @@ func (h vvfatDiskHandler) Apply(spec VolumeSpec, t Target) error {
 
 	// Add a PCI Express Root device that we'll connect a Virtio Block
 	// device to.
-	root, err := t.VM.Topology.AddRoot()
+	root, err := t.VM.Topology.AddRoot(qdev.Key(VolumeDeviceID(spec.Volume)))
 	if err != nil {
 		return err
 	}
 
 	// Prepare the Virtio Block device options. Note that WWN values are
 	// not supported by Virtio Block devices.
-	var options []qdev.BlockOption
+	options := []qdev.BlockOption{qdev.DiskID(VolumeDeviceID(spec.Volume))}
 	if spec.Volume.SerialNumber != "" {
 		options = append(options, qdev.SerialNumber(spec.Volume.SerialNumber))
 	}
@@ -293,7 +293,7 @@ func (h scsiCDROM) Apply(spec VolumeSpec, t Target) error {
 	}
 
 	// Add a SCSI CD device for this volume to the controller
-	if _, err := scsi.AddCD(file); err != nil {
+	if _, err := scsi.AddCD(file, qdev.DiskID(VolumeDeviceID(spec.Volume))); err != nil {
 		return err
 	}
 
@@ -1,7 +1,9 @@
 package qemugen
 
 import (
+	"github.com/gentlemanautomaton/machina/filesystem/pcifs"
 	"github.com/gentlemanautomaton/machina/qemu/qdev"
+	"github.com/gentlemanautomaton/machina/qemu/qfirmware"
 	"github.com/gentlemanautomaton/machina/qemu/qvm"
 )
 
@@ -11,4 +13,13 @@ type Target struct {
 	VM          *qvm.Definition
 	Controllers *qdev.ControllerMap
 	BootOrder   *qdev.BootOrder
+
+	// PCI is consulted to locate passthrough devices that are identified
+	// by their vendor and device identifier instead of their address.
+	PCI pcifs.System
+
+	// Firmware is the catalog of firmware descriptors that UEFI firmware
+	// is selected from. If it is nil, the descriptors installed on the
+	// local system are used.
+	Firmware qfirmware.Catalog
 }
@@ -0,0 +1,61 @@
+package qemugen
+
+import (
+	"fmt"
+	"strconv"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+)
+
+// USBHostDeviceID returns the QEMU device identifier of the USB host device
+// that is generated for the USB device at the given index of those claimed
+// by dev. It can be used to target the device with QMP commands while the
+// machine is running.
+func USBHostDeviceID(dev machina.Device, index int) qdev.ID {
+	return qdev.ID(fmt.Sprintf("usbhost.%s.%d", dev.Name, index))
+}
+
+// USBHostSelector returns the selector that QEMU uses to find udev on the
+// host system.
+func USBHostSelector(udev machina.USBDevice) (qdev.USBHostSelector, error) {
+	if err := udev.Validate(); err != nil {
+		return qdev.USBHostSelector{}, err
+	}
+
+	if udev.ID == "" {
+		return qdev.USBHostSelector{HostBus: udev.Bus, HostPort: udev.Port}, nil
+	}
+
+	vendor, product, _ := udev.ID.Split()
+	vendorID, err := strconv.ParseUint(vendor, 16, 16)
+	if err != nil {
+		return qdev.USBHostSelector{}, err
+	}
+	productID, err := strconv.ParseUint(product, 16, 16)
+	if err != nil {
+		return qdev.USBHostSelector{}, err
+	}
+	return qdev.USBHostSelector{VendorID: uint16(vendorID), ProductID: uint16(productID)}, nil
+}
+
+func applyUSBDevices(dev machina.Device, udevs machina.USBDeviceList, t Target) error {
+	// Add a USB Controller
+	usb, err := t.Controllers.USB()
+	if err != nil {
+		return err
+	}
+
+	// Add a USB host device for each USB device claimed by the device.
+	for i, udev := range udevs {
+		selector, err := USBHostSelector(udev)
+		if err != nil {
+			return fmt.Errorf("device %s: %v", dev.Name, err)
+		}
+		if _, err := usb.AddHost(selector, qdev.USBHostID(USBHostDeviceID(dev, i))); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
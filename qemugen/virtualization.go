@@ -0,0 +1,57 @@
+package qemugen
+
+import (
+	"errors"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qguest"
+	"github.com/gentlemanautomaton/machina/qemu/qhost"
+)
+
+// applyNested exposes the hardware virtualization extensions of the
+// processor to the guest. The extension depends on the processor brand.
+func applyNested(target Target) error {
+	var feature string
+	switch brand := target.VM.Settings.Processor.Brand; {
+	case strings.EqualFold(brand, "Intel"):
+		feature = "vmx"
+	case strings.EqualFold(brand, "AMD"):
+		feature = "svm"
+	default:
+		return errors.New("nested virtualization requires a processor with an Intel or AMD brand")
+	}
+
+	target.VM.Settings.Processor.Features = append(target.VM.Settings.Processor.Features, qguest.CPUFeature{
+		Name:    feature,
+		Enabled: true,
+	})
+
+	return nil
+}
+
+// applySEV launches the guest with AMD Secure Encrypted Virtualization
+// when it is enabled.
+func applySEV(sev machina.SEV, target Target) error {
+	if !sev.Enabled {
+		return nil
+	}
+	if err := sev.Validate(); err != nil {
+		return err
+	}
+	if !strings.EqualFold(target.VM.Settings.Processor.Brand, "AMD") {
+		return errors.New("secure encrypted virtualization requires a processor with an AMD brand")
+	}
+
+	guest, err := target.VM.Resources.AddSEVGuest(qhost.SEVSettings{
+		Policy:          sev.EffectivePolicy(),
+		CBitPos:         sev.CBitPos,
+		ReducedPhysBits: sev.ReducedPhysBits,
+	})
+	if err != nil {
+		return err
+	}
+	target.VM.Settings.Memory.Encryption = guest.ID()
+
+	return nil
+}
@@ -4,8 +4,29 @@ import (
 	"fmt"
 
 	"github.com/gentlemanautomaton/machina"
+	"github.com/gentlemanautomaton/machina/qemu/qdev"
+	"github.com/gentlemanautomaton/machina/qemu/qhost/blockdev"
 )
 
+// VolumeDeviceID returns the QEMU device identifier of the virtual disk
+// device that is generated for volume. It can be used to target the device
+// with QMP commands while the machine is running.
+//
+// Identifiers are only assigned to disk devices that can be hotplugged.
+func VolumeDeviceID(volume machina.Volume) qdev.ID {
+	return qdev.ID("vol." + string(volume.Name))
+}
+
+// VolumeNodeName returns the name of the block device node that backs the
+// disk device generated for volume.
+func VolumeNodeName(machine machina.MachineInfo, vars machina.Vars, volume machina.Volume, storage machina.StorageMap) (blockdev.NodeName, error) {
+	spec, err := makeVolumeSpec(machine, vars, volume, storage)
+	if err != nil {
+		return "", err
+	}
+	return DefaultStorageHandlers().NodeName(spec)
+}
+
 func applyVolumes(machine machina.MachineInfo, vars machina.Vars, vols []machina.Volume, storage machina.StorageMap, target Target) error {
 	if len(vols) == 0 {
 		return nil
@@ -0,0 +1,59 @@
+package qmpcmd
+
+import "encoding/json"
+
+// Balloon is a QMP command that asks the memory balloon device of a running
+// virtual machine to adjust the amount of memory available to the guest.
+//
+// The guest cooperates with the balloon device to reach the target, so the
+// adjustment is not immediate.
+type Balloon struct {
+	// Value is the target amount of guest memory in bytes.
+	Value int64 `json:"value"`
+}
+
+// Command returns the command name "balloon".
+func (b Balloon) Command() string {
+	return "balloon"
+}
+
+// CommandArgs returns the balloon command arguments marshaled as a JSON
+// byte slice.
+func (b Balloon) CommandArgs() ([]byte, error) {
+	return json.Marshal(b)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a balloon command.
+//
+// No response is expected, so this function does nothing.
+func (b Balloon) CommandResponse([]byte) error {
+	return nil
+}
+
+// QueryBalloon is a QMP command that returns information about the memory
+// balloon device of a virtual machine.
+type QueryBalloon struct {
+	Response BalloonInfo
+}
+
+// Command returns the QMP command name.
+func (action QueryBalloon) Command() string {
+	return "query-balloon"
+}
+
+// CommandArgs returns a nil JSON byte slice.
+func (action QueryBalloon) CommandArgs() ([]byte, error) {
+	return nil, nil
+}
+
+// CommandResponse unmarshals the JSON-encoded response to a QMP command.
+func (action *QueryBalloon) CommandResponse(response []byte) error {
+	return json.Unmarshal(response, &action.Response)
+}
+
+// BalloonInfo describes the memory balloon in a query-balloon response.
+type BalloonInfo struct {
+	// Actual is the amount of memory in bytes that is currently available
+	// to the guest.
+	Actual int64 `json:"actual"`
+}
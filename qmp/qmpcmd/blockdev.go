@@ -0,0 +1,58 @@
+package qmpcmd
+
+import "encoding/json"
+
+// BlockdevAdd is a QMP command that adds a block device node to a running
+// virtual machine.
+type BlockdevAdd struct {
+	// Arguments holds the structured properties of the block device node,
+	// including its driver and node name.
+	Arguments map[string]any
+}
+
+// Command returns the command name "blockdev-add".
+func (add BlockdevAdd) Command() string {
+	return "blockdev-add"
+}
+
+// CommandArgs returns the blockdev add command arguments marshaled as a
+// JSON byte slice.
+func (add BlockdevAdd) CommandArgs() ([]byte, error) {
+	return json.Marshal(add.Arguments)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a blockdev add
+// command.
+//
+// No response is expected, so this function does nothing.
+func (add BlockdevAdd) CommandResponse([]byte) error {
+	return nil
+}
+
+// BlockdevDel is a QMP command that removes a block device node from a
+// running virtual machine.
+//
+// The node must not be in use by a device or another node.
+type BlockdevDel struct {
+	// NodeName is the name of the block device node to remove.
+	NodeName string `json:"node-name"`
+}
+
+// Command returns the command name "blockdev-del".
+func (del BlockdevDel) Command() string {
+	return "blockdev-del"
+}
+
+// CommandArgs returns the blockdev del command arguments marshaled as a
+// JSON byte slice.
+func (del BlockdevDel) CommandArgs() ([]byte, error) {
+	return json.Marshal(del)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a blockdev del
+// command.
+//
+// No response is expected, so this function does nothing.
+func (del BlockdevDel) CommandResponse([]byte) error {
+	return nil
+}
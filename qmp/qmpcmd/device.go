@@ -0,0 +1,74 @@
+package qmpcmd
+
+import "encoding/json"
+
+// DeviceAdd is a QMP command that adds a device to a running virtual
+// machine.
+type DeviceAdd struct {
+	// Driver is the name of the device's driver, such as usb-host.
+	Driver string
+
+	// ID is the device identifier that can be used to remove the device
+	// later.
+	ID string
+
+	// Properties holds the remaining device properties. They are the same
+	// properties that would be provided to QEMU's -device option.
+	Properties map[string]string
+}
+
+// Command returns the command name "device_add".
+func (add DeviceAdd) Command() string {
+	return "device_add"
+}
+
+// CommandArgs returns the device add command arguments marshaled as a JSON
+// byte slice.
+func (add DeviceAdd) CommandArgs() ([]byte, error) {
+	args := make(map[string]string, len(add.Properties)+2)
+	for name, value := range add.Properties {
+		args[name] = value
+	}
+	args["driver"] = add.Driver
+	if add.ID != "" {
+		args["id"] = add.ID
+	}
+	return json.Marshal(args)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a device add
+// command.
+//
+// No response is expected, so this function does nothing.
+func (add DeviceAdd) CommandResponse([]byte) error {
+	return nil
+}
+
+// DeviceDel is a QMP command that asks a running virtual machine to remove
+// a device.
+//
+// Removal is not immediate. Some devices require cooperation from the
+// guest, and QEMU emits a DEVICE_DELETED event when the device is gone.
+type DeviceDel struct {
+	// ID is the device identifier of the device to remove.
+	ID string `json:"id"`
+}
+
+// Command returns the command name "device_del".
+func (del DeviceDel) Command() string {
+	return "device_del"
+}
+
+// CommandArgs returns the device del command arguments marshaled as a JSON
+// byte slice.
+func (del DeviceDel) CommandArgs() ([]byte, error) {
+	return json.Marshal(del)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a device del
+// command.
+//
+// No response is expected, so this function does nothing.
+func (del DeviceDel) CommandResponse([]byte) error {
+	return nil
+}
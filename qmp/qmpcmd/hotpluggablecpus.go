@@ -0,0 +1,110 @@
+package qmpcmd
+
+import (
+	"encoding/json"
+	"strings"
+)
+
+// QueryHotpluggableCPUs is a QMP command that returns information about the
+// virtual CPU slots in a virtual machine, including those that are not
+// currently plugged in.
+type QueryHotpluggableCPUs struct {
+	Response []HotpluggableCPU
+}
+
+// Command returns the QMP command name.
+func (action QueryHotpluggableCPUs) Command() string {
+	return "query-hotpluggable-cpus"
+}
+
+// CommandArgs returns a nil JSON byte slice.
+func (action QueryHotpluggableCPUs) CommandArgs() ([]byte, error) {
+	return nil, nil
+}
+
+// CommandResponse unmarshals the JSON-encoded response to a QMP command.
+func (action *QueryHotpluggableCPUs) CommandResponse(response []byte) error {
+	return json.Unmarshal(response, &action.Response)
+}
+
+// HotpluggableCPU describes a virtual CPU slot in a query-hotpluggable-cpus
+// response.
+type HotpluggableCPU struct {
+	// Type is the driver of the CPU device that can be plugged into the
+	// slot, such as EPYC-v4-x86_64-cpu.
+	Type string `json:"type"`
+
+	// VCPUsCount is the number of virtual CPUs provided by the slot.
+	VCPUsCount int `json:"vcpus-count"`
+
+	// Props holds the location of the slot, such as its socket-id, core-id
+	// and thread-id. The properties are provided to device_add when a CPU
+	// is plugged into the slot.
+	Props map[string]int `json:"props"`
+
+	// QOMPath is the path of the CPU device that occupies the slot. It is
+	// empty when the slot is unoccupied.
+	QOMPath string `json:"qom-path,omitempty"`
+}
+
+// Online returns true if a CPU device occupies the slot.
+func (cpu HotpluggableCPU) Online() bool {
+	return cpu.QOMPath != ""
+}
+
+// Removable returns true if the CPU in the slot was hotplugged with
+// a device identifier, which allows it to be removed with device_del.
+//
+// CPUs that the machine started with cannot be removed.
+func (cpu HotpluggableCPU) Removable() bool {
+	return strings.HasPrefix(cpu.QOMPath, "/machine/peripheral/")
+}
+
+// DeviceID returns the device identifier of the CPU in the slot. It
+// returns an empty string if the CPU is not removable.
+func (cpu HotpluggableCPU) DeviceID() string {
+	if !cpu.Removable() {
+		return ""
+	}
+	return strings.TrimPrefix(cpu.QOMPath, "/machine/peripheral/")
+}
+
+// CPUAdd is a QMP command that plugs a virtual CPU into an unoccupied slot
+// of a running virtual machine. It is a device_add command with the numeric
+// properties that CPU devices require.
+type CPUAdd struct {
+	// Driver is the CPU device type reported for the slot.
+	Driver string
+
+	// ID is the device identifier that can be used to remove the CPU later.
+	ID string
+
+	// Props holds the location of the slot.
+	Props map[string]int
+}
+
+// Command returns the command name "device_add".
+func (add CPUAdd) Command() string {
+	return "device_add"
+}
+
+// CommandArgs returns the CPU add command arguments marshaled as a JSON
+// byte slice.
+func (add CPUAdd) CommandArgs() ([]byte, error) {
+	args := make(map[string]any, len(add.Props)+2)
+	for name, value := range add.Props {
+		args[name] = value
+	}
+	args["driver"] = add.Driver
+	if add.ID != "" {
+		args["id"] = add.ID
+	}
+	return json.Marshal(args)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a CPU add command.
+//
+// No response is expected, so this function does nothing.
+func (add CPUAdd) CommandResponse([]byte) error {
+	return nil
+}
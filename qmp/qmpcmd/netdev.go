@@ -0,0 +1,56 @@
+package qmpcmd
+
+import "encoding/json"
+
+// NetdevAdd is a QMP command that adds a network backend to a running
+// virtual machine.
+type NetdevAdd struct {
+	// Arguments holds the structured properties of the network backend,
+	// including its type and identifier.
+	Arguments map[string]any
+}
+
+// Command returns the command name "netdev_add".
+func (add NetdevAdd) Command() string {
+	return "netdev_add"
+}
+
+// CommandArgs returns the netdev add command arguments marshaled as a JSON
+// byte slice.
+func (add NetdevAdd) CommandArgs() ([]byte, error) {
+	return json.Marshal(add.Arguments)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a netdev add
+// command.
+//
+// No response is expected, so this function does nothing.
+func (add NetdevAdd) CommandResponse([]byte) error {
+	return nil
+}
+
+// NetdevDel is a QMP command that removes a network backend from a running
+// virtual machine.
+type NetdevDel struct {
+	// ID is the identifier of the network backend to remove.
+	ID string `json:"id"`
+}
+
+// Command returns the command name "netdev_del".
+func (del NetdevDel) Command() string {
+	return "netdev_del"
+}
+
+// CommandArgs returns the netdev del command arguments marshaled as a JSON
+// byte slice.
+func (del NetdevDel) CommandArgs() ([]byte, error) {
+	return json.Marshal(del)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a netdev del
+// command.
+//
+// No response is expected, so this function does nothing.
+func (del NetdevDel) CommandResponse([]byte) error {
+	return nil
+}
@@ -0,0 +1,56 @@
+package qmpcmd
+
+import "encoding/json"
+
+// ObjectAdd is a QMP command that adds an object, such as a memory backend,
+// to a running virtual machine.
+type ObjectAdd struct {
+	// Arguments holds the structured properties of the object, including
+	// its qom-type and identifier.
+	Arguments map[string]any
+}
+
+// Command returns the command name "object-add".
+func (add ObjectAdd) Command() string {
+	return "object-add"
+}
+
+// CommandArgs returns the object add command arguments marshaled as a JSON
+// byte slice.
+func (add ObjectAdd) CommandArgs() ([]byte, error) {
+	return json.Marshal(add.Arguments)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to an object add
+// command.
+//
+// No response is expected, so this function does nothing.
+func (add ObjectAdd) CommandResponse([]byte) error {
+	return nil
+}
+
+// ObjectDel is a QMP command that removes an object from a running virtual
+// machine.
+type ObjectDel struct {
+	// ID is the identifier of the object to remove.
+	ID string `json:"id"`
+}
+
+// Command returns the command name "object-del".
+func (del ObjectDel) Command() string {
+	return "object-del"
+}
+
+// CommandArgs returns the object del command arguments marshaled as a JSON
+// byte slice.
+func (del ObjectDel) CommandArgs() ([]byte, error) {
+	return json.Marshal(del)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to an object del
+// command.
+//
+// No response is expected, so this function does nothing.
+func (del ObjectDel) CommandResponse([]byte) error {
+	return nil
+}
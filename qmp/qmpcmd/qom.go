@@ -0,0 +1,41 @@
+package qmpcmd
+
+import "encoding/json"
+
+// QOMGet is a QMP command that reads a property of an object in the QEMU
+// Object Model of a running virtual machine.
+type QOMGet struct {
+	// Path is the path of the object, such as /machine/peripheral/nic.0.
+	Path string `json:"path"`
+
+	// Property is the name of the property to read.
+	Property string `json:"property"`
+
+	// Response holds the JSON-encoded value of the property.
+	Response json.RawMessage `json:"-"`
+}
+
+// Command returns the command name "qom-get".
+func (get QOMGet) Command() string {
+	return "qom-get"
+}
+
+// CommandArgs returns the qom get command arguments marshaled as a JSON
+// byte slice.
+func (get QOMGet) CommandArgs() ([]byte, error) {
+	return json.Marshal(get)
+}
+
+// CommandResponse stores the JSON-encoded property value.
+func (get *QOMGet) CommandResponse(response []byte) error {
+	get.Response = append(get.Response[:0], response...)
+	return nil
+}
+
+// String returns the property value as a string. It returns an error if
+// the value is not a JSON string.
+func (get QOMGet) String() (string, error) {
+	var value string
+	err := json.Unmarshal(get.Response, &value)
+	return value, err
+}
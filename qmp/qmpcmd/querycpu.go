@@ -1,5 +1,7 @@
 package qmpcmd
 
+import "encoding/json"
+
 // QueryCPU is a QMP command that returns information about the CPU in a
 // virtual machine.
 type QueryCPU struct {
@@ -21,3 +23,19 @@ func (action *QueryCPU) CommandResponse(response []byte) error {
 	action.Response = string(response)
 	return nil
 }
+
+// CPUs parses the response and returns the virtual CPUs that it describes.
+func (action QueryCPU) CPUs() ([]CPUInfo, error) {
+	var cpus []CPUInfo
+	if err := json.Unmarshal([]byte(action.Response), &cpus); err != nil {
+		return nil, err
+	}
+	return cpus, nil
+}
+
+// CPUInfo describes a virtual CPU in a query-cpus-fast response.
+type CPUInfo struct {
+	CPUIndex int    `json:"cpu-index"`
+	ThreadID int    `json:"thread-id"`
+	QOMPath  string `json:"qom-path"`
+}
@@ -0,0 +1,30 @@
+package qmpcmd
+
+import "encoding/json"
+
+// QueryIOThreads is a QMP command that returns information about the I/O
+// threads in a virtual machine.
+type QueryIOThreads struct {
+	Response []IOThreadInfo
+}
+
+// Command returns the QMP command name.
+func (action QueryIOThreads) Command() string {
+	return "query-iothreads"
+}
+
+// CommandArgs returns a nil JSON byte slice.
+func (action QueryIOThreads) CommandArgs() ([]byte, error) {
+	return nil, nil
+}
+
+// CommandResponse unmarshals the JSON-encoded response to a QMP command.
+func (action *QueryIOThreads) CommandResponse(response []byte) error {
+	return json.Unmarshal(response, &action.Response)
+}
+
+// IOThreadInfo describes an I/O thread in a query-iothreads response.
+type IOThreadInfo struct {
+	ID       string `json:"id"`
+	ThreadID int    `json:"thread-id"`
+}
@@ -0,0 +1,80 @@
+package qmpcmd
+
+import "encoding/json"
+
+// QueryMemoryDevices is a QMP command that returns information about the
+// memory devices, such as DIMMs, that have been plugged into a virtual
+// machine.
+type QueryMemoryDevices struct {
+	Response []MemoryDeviceInfo
+}
+
+// Command returns the QMP command name.
+func (action QueryMemoryDevices) Command() string {
+	return "query-memory-devices"
+}
+
+// CommandArgs returns a nil JSON byte slice.
+func (action QueryMemoryDevices) CommandArgs() ([]byte, error) {
+	return nil, nil
+}
+
+// CommandResponse unmarshals the JSON-encoded response to a QMP command.
+func (action *QueryMemoryDevices) CommandResponse(response []byte) error {
+	return json.Unmarshal(response, &action.Response)
+}
+
+// MemoryDeviceInfo describes a memory device in a query-memory-devices
+// response.
+type MemoryDeviceInfo struct {
+	Type string           `json:"type"`
+	Data MemoryDeviceData `json:"data"`
+}
+
+// MemoryDeviceData holds the details of a memory device. The fields are
+// common to pc-dimm and nvdimm devices.
+type MemoryDeviceData struct {
+	ID         string `json:"id,omitempty"`
+	Size       int64  `json:"size"`
+	Slot       int    `json:"slot"`
+	Node       int    `json:"node"`
+	MemDev     string `json:"memdev"`
+	Hotplugged bool   `json:"hotplugged"`
+}
+
+// QueryMemorySizeSummary is a QMP command that returns the amount of memory
+// in a virtual machine.
+type QueryMemorySizeSummary struct {
+	Response MemorySizeSummary
+}
+
+// Command returns the QMP command name.
+func (action QueryMemorySizeSummary) Command() string {
+	return "query-memory-size-summary"
+}
+
+// CommandArgs returns a nil JSON byte slice.
+func (action QueryMemorySizeSummary) CommandArgs() ([]byte, error) {
+	return nil, nil
+}
+
+// CommandResponse unmarshals the JSON-encoded response to a QMP command.
+func (action *QueryMemorySizeSummary) CommandResponse(response []byte) error {
+	return json.Unmarshal(response, &action.Response)
+}
+
+// MemorySizeSummary describes the memory in a query-memory-size-summary
+// response. Sizes are in bytes.
+type MemorySizeSummary struct {
+	// BaseMemory is the memory that the virtual machine was started with.
+	BaseMemory int64 `json:"base-memory"`
+
+	// PluggedMemory is the memory that has been hotplugged through memory
+	// devices.
+	PluggedMemory int64 `json:"plugged-memory"`
+}
+
+// Total returns the sum of the base and plugged memory.
+func (s MemorySizeSummary) Total() int64 {
+	return s.BaseMemory + s.PluggedMemory
+}
@@ -1,6 +1,8 @@
 package qmpcmd
 
-// QueryCPU is a QMP command that returns information about the PCI bus in a
+import "encoding/json"
+
+// QueryPCI is a QMP command that returns information about the PCI bus in a
 // virtual machine.
 type QueryPCI struct {
 	Response string
@@ -21,3 +23,50 @@ func (action *QueryPCI) CommandResponse(response []byte) error {
 	action.Response = string(response)
 	return nil
 }
+
+// Buses parses the response and returns the PCI buses that it describes.
+func (action QueryPCI) Buses() ([]PCIBus, error) {
+	var buses []PCIBus
+	if err := json.Unmarshal([]byte(action.Response), &buses); err != nil {
+		return nil, err
+	}
+	return buses, nil
+}
+
+// PCIBus describes a PCI bus in a query-pci response.
+type PCIBus struct {
+	Bus     int         `json:"bus"`
+	Devices []PCIDevice `json:"devices"`
+}
+
+// PCIDevice describes a PCI device in a query-pci response.
+type PCIDevice struct {
+	Bus      int        `json:"bus"`
+	Slot     int        `json:"slot"`
+	Function int        `json:"function"`
+	QdevID   string     `json:"qdev_id"`
+	Bridge   *PCIBridge `json:"pci_bridge,omitempty"`
+}
+
+// PCIBridge describes the devices attached behind a PCI bridge or PCI
+// Express root port in a query-pci response.
+type PCIBridge struct {
+	Devices []PCIDevice `json:"devices"`
+}
+
+// WalkPCIDevices calls fn for each device on buses, including the devices
+// attached behind bridges.
+func WalkPCIDevices(buses []PCIBus, fn func(device PCIDevice)) {
+	var walk func(devices []PCIDevice)
+	walk = func(devices []PCIDevice) {
+		for _, device := range devices {
+			fn(device)
+			if device.Bridge != nil {
+				walk(device.Bridge.Devices)
+			}
+		}
+	}
+	for _, bus := range buses {
+		walk(bus.Devices)
+	}
+}
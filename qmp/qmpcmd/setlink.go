@@ -0,0 +1,32 @@
+package qmpcmd
+
+import "encoding/json"
+
+// SetLink is a QMP command that sets the link state of a virtual network
+// adapter, as seen by the guest.
+type SetLink struct {
+	// Name is the device identifier of the network adapter.
+	Name string `json:"name"`
+
+	// Up is true if the link should be brought up, or false if the link
+	// should be brought down.
+	Up bool `json:"up"`
+}
+
+// Command returns the command name "set_link".
+func (link SetLink) Command() string {
+	return "set_link"
+}
+
+// CommandArgs returns the set link command arguments marshaled as a JSON
+// byte slice.
+func (link SetLink) CommandArgs() ([]byte, error) {
+	return json.Marshal(link)
+}
+
+// CommandResponse unmarshals a JSON-encoded response to a set link command.
+//
+// No response is expected, so this function does nothing.
+func (link SetLink) CommandResponse([]byte) error {
+	return nil
+}
@@ -17,6 +17,14 @@ type System struct {
 	// system.
 	MediatedDevices MediatedDeviceMap `json:"mediated-device,omitempty"`
 
+	// PassthroughDevices is a list of PCI devices available on the host
+	// system that can be passed through to machines in their entirety.
+	PassthroughDevices PassthroughDeviceMap `json:"passthrough-device,omitempty"`
+
+	// USBDevices is a list of USB devices available on the host system that
+	// can be passed through to machines.
+	USBDevices USBDeviceMap `json:"usb-device,omitempty"`
+
 	// Tag defines tags available on the host system.
 	Tag TagMap `json:"tag,omitempty"`
 }
@@ -56,6 +64,30 @@ func (sys System) Summary() string {
 		out.Ascend()
 	}
 
+	if len(sys.PassthroughDevices) > 0 {
+		out.Add("Passthrough Devices:")
+		out.Descend()
+		for name, device := range sys.PassthroughDevices {
+			out.Add("%s:", name)
+			out.Descend()
+			device.Config(&out)
+			out.Ascend()
+		}
+		out.Ascend()
+	}
+
+	if len(sys.USBDevices) > 0 {
+		out.Add("USB Devices:")
+		out.Descend()
+		for name, device := range sys.USBDevices {
+			out.Add("%s:", name)
+			out.Descend()
+			device.Config(&out)
+			out.Ascend()
+		}
+		out.Ascend()
+	}
+
 	if len(sys.Tag) > 0 {
 		out.Add("Tags:")
 		out.Descend()
@@ -70,6 +70,17 @@ func (s UnitStatus) ActiveStateCondition() Condition {
 	}
 }
 
+// Active returns true if the unit is active or is transitioning between
+// states. Resources that were prepared for an active unit may be in use.
+func (s UnitStatus) Active() bool {
+	switch s.ActiveState {
+	case "active", "reloading", "activating", "deactivating":
+		return true
+	default:
+		return false
+	}
+}
+
 // Duration returns the duration of the current status condition.
 func (s UnitStatus) Duration() time.Duration {
 	var durationSince = func(t time.Time) time.Duration {
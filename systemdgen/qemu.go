@@ -17,17 +17,43 @@ func UnitNameForQEMU(name machina.MachineName) string {
 	return fmt.Sprintf("machina-qemu-%s", name)
 }
 
+// Placement describes where the QEMU process of a machine runs on the
+// host.
+type Placement struct {
+	// CPUAffinity lists the host CPUs that the QEMU process may run on,
+	// such as "0-1". It is applied through the CPUAffinity directive.
+	CPUAffinity string
+
+	// PinThreads is true if the vCPU and I/O threads of the machine are
+	// pinned to host CPUs by machina after QEMU starts.
+	PinThreads bool
+}
+
 // BuildQEMU returns a set of systemd unit configuration sections for the
 // given machine and options.
 //
 // If bindToUnits are provided, the resulting qemu system unit will be bound
 // to the provided systemd units, and will start after them.
 func BuildQEMU(machine machina.MachineInfo, opts qemu.Options, bindToUnits ...string) []systemdconf.Section {
+	return BuildQEMUWithPlacement(machine, opts, Placement{}, bindToUnits...)
+}
+
+// BuildQEMUWithPlacement returns a set of systemd unit configuration
+// sections for the given machine and options, with the QEMU process placed
+// on the host according to placement.
+//
+// If bindToUnits are provided, the resulting qemu system unit will be bound
+// to the provided systemd units, and will start after them.
+func BuildQEMUWithPlacement(machine machina.MachineInfo, opts qemu.Options, placement Placement, bindToUnits ...string) []systemdconf.Section {
 	const (
 		serviceTimeout  = time.Second * 90
 		shutdownTimeout = serviceTimeout - (time.Second * 5)
 	)
 	quotedName := QuoteArg(string(machine.Name))
+	var execStartPost []string
+	if placement.PinThreads {
+		execStartPost = append(execStartPost, fmt.Sprintf("machina pin --wait %s", quotedName))
+	}
 	return []systemdconf.Section{
 		systemdconf.Unit{
 			Description:        fmt.Sprintf("machina qemu/kvm %s", machine.Name),
@@ -37,19 +63,37 @@ func BuildQEMU(machine machina.MachineInfo, opts qemu.Options, bindToUnits ...st
 			StartLimitInterval: time.Minute,
 			StartLimitBurst:    2,
 		},
-		systemdconf.Service{
-			Type:               "simple",
-			ExecStartPre:       []string{fmt.Sprintf("machina prepare qemu %s", quotedName)},
-			ExecStart:          []string{fmt.Sprintf("qemu-system-x86_64 \\\n%s", QuoteOptions(opts))},
-			ExecStop:           []string{fmt.Sprintf("machina shutdown --system --timeout %s %s", shutdownTimeout, quotedName)},
-			ExecStopPost:       []string{fmt.Sprintf("machina teardown %s", quotedName)},
-			TimeoutStop:        serviceTimeout,
-			RestartInterval:    time.Second * 10,
-			Restart:            unitvalue.RestartOnFailure,
-			RuntimeDirectories: []string{path.Join("machina", string(machine.Name), "qmp")},
+		qemuService{
+			Service: systemdconf.Service{
+				Type:               "simple",
+				ExecStartPre:       []string{fmt.Sprintf("machina prepare qemu %s", quotedName)},
+				ExecStart:          []string{fmt.Sprintf("qemu-system-x86_64 \\\n%s", QuoteOptions(opts))},
+				ExecStartPost:      execStartPost,
+				ExecStop:           []string{fmt.Sprintf("machina shutdown --system --timeout %s %s", shutdownTimeout, quotedName)},
+				ExecStopPost:       []string{fmt.Sprintf("machina teardown %s", quotedName)},
+				TimeoutStop:        serviceTimeout,
+				RestartInterval:    time.Second * 10,
+				Restart:            unitvalue.RestartOnFailure,
+				RuntimeDirectories: []string{path.Join("machina", string(machine.Name), "qmp")},
+			},
+			CPUAffinity: placement.CPUAffinity,
 		},
 		systemdconf.Install{
 			WantedBy: []string{"multi-user.target"},
 		},
 	}
 }
+
+// qemuService is a [Service] section with directives that aren't supported
+// by systemdconf.Service.
+type qemuService struct {
+	systemdconf.Service
+	CPUAffinity string
+}
+
+// Directives returns the systemd directives for the [Service] section.
+func (s qemuService) Directives() systemdconf.Directives {
+	out := s.Service.Directives()
+	out.AddOptional("CPUAffinity", s.CPUAffinity)
+	return out
+}
@@ -0,0 +1,110 @@
+package machina
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/gentlemanautomaton/machina/summary"
+)
+
+// USBVendorProduct identifies a model of USB device by its vendor and
+// product identifiers. It is expressed in the hexadecimal "vendor:product"
+// form reported by "lsusb", such as "046d:c52b".
+type USBVendorProduct string
+
+// Split returns the vendor and product identifiers.
+func (id USBVendorProduct) Split() (vendor, product string, ok bool) {
+	vendor, product, ok = strings.Cut(strings.ToLower(string(id)), ":")
+	if !ok || !isHex16(vendor) || !isHex16(product) {
+		return "", "", false
+	}
+	return vendor, product, true
+}
+
+// Valid returns true if the identifier is in the "vendor:product" form.
+func (id USBVendorProduct) Valid() bool {
+	_, _, ok := id.Split()
+	return ok
+}
+
+// USBDeviceName is the name of a USB device on the host system.
+type USBDeviceName string
+
+// USBDevice describes a USB device on the host system that can be passed
+// through to a virtual machine via usb-host.
+//
+// The device is identified by its vendor and product identifiers, or by
+// the bus number and port path that it is plugged into, such as bus 3 and
+// port "1.2". When identified by vendor and product, the first matching
+// device is used. When identified by bus and port, whatever device is
+// plugged into the port is used.
+//
+// Machines claim USB devices by class, in the same way that they claim
+// passthrough devices.
+type USBDevice struct {
+	ID    USBVendorProduct `json:"id,omitempty"`
+	Bus   int              `json:"bus,omitempty"`
+	Port  string           `json:"port,omitempty"`
+	Class DeviceClass      `json:"class"`
+}
+
+// Validate returns an error if the USB device is not identified correctly.
+func (dev USBDevice) Validate() error {
+	hasPath := dev.Bus != 0 || dev.Port != ""
+	switch {
+	case dev.ID == "" && !hasPath:
+		return fmt.Errorf("USB device of class %s lacks both a vendor:product identifier and a bus and port", dev.Class)
+	case dev.ID != "" && hasPath:
+		return fmt.Errorf("USB device of class %s specifies both a vendor:product identifier and a bus and port", dev.Class)
+	case dev.ID != "" && !dev.ID.Valid():
+		return fmt.Errorf("USB device of class %s has an invalid vendor:product identifier: \"%s\"", dev.Class, dev.ID)
+	case hasPath && (dev.Bus < 1 || dev.Port == ""):
+		return fmt.Errorf("USB device of class %s must specify both a bus and a port", dev.Class)
+	}
+	return nil
+}
+
+// String returns a string representation of the USB device.
+func (dev USBDevice) String() string {
+	if dev.ID != "" {
+		return string(dev.ID)
+	}
+	return fmt.Sprintf("%d-%s", dev.Bus, dev.Port)
+}
+
+// Config adds the USB device configuration to the summary.
+func (dev USBDevice) Config(out summary.Interface) {
+	if dev.ID != "" {
+		out.Add("Vendor and Product ID: %s", dev.ID)
+	}
+	if dev.Bus != 0 || dev.Port != "" {
+		out.Add("Bus: %d", dev.Bus)
+		out.Add("Port: %s", dev.Port)
+	}
+	out.Add("Supplied Device Class: %s", dev.Class)
+}
+
+// USBDeviceList holds a sortable list of USB devices on the host system.
+type USBDeviceList []USBDevice
+
+func (a USBDeviceList) Len() int      { return len(a) }
+func (a USBDeviceList) Swap(i, j int) { a[i], a[j] = a[j], a[i] }
+func (a USBDeviceList) Less(i, j int) bool {
+	return strings.Compare(a[i].String(), a[j].String()) < 0
+}
+
+// USBDeviceMap describes a set of USB devices on the host system.
+type USBDeviceMap map[USBDeviceName]USBDevice
+
+// WithClass returns zero or more USB devices that supply the given device
+// class.
+func (m USBDeviceMap) WithClass(class DeviceClass) (devices USBDeviceList) {
+	for _, dev := range m {
+		if dev.Class == class {
+			devices = append(devices, dev)
+		}
+	}
+	sort.Stable(devices)
+	return devices
+}